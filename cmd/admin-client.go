@@ -0,0 +1,238 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Region used to sign admin requests, the server accepts any region.
+const adminClientRegion = "us-east-1"
+
+var errInvalidAdminEndpoint = errors.New("Invalid server endpoint, expected http(s)://host[:port]")
+
+// adminClient - client for the admin API of a running server.
+type adminClient struct {
+	endpoint   *url.URL
+	cred       credential
+	httpClient *http.Client
+}
+
+// newAdminClient - returns an admin client for the server listening
+// on endpoint, requests are signed with the given credential.
+func newAdminClient(endpoint string, cred credential) (*adminClient, error) {
+	u, err := checkURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != httpScheme && u.Scheme != httpsScheme) || u.Host == "" {
+		return nil, errInvalidAdminEndpoint
+	}
+	return &adminClient{
+		endpoint: u,
+		cred:     cred,
+		// No timeout, some admin APIs stream their response.
+		httpClient: &http.Client{Transport: newCustomHTTPTransport()},
+	}, nil
+}
+
+// signV4Request - signs the request with AWS Signature Version '4'
+// in the Authorization header.
+func signV4Request(req *http.Request, cred credential, region string, hashedPayload string) {
+	t := UTCNow()
+	req.Header.Set("X-Amz-Date", t.Format(iso8601Format))
+	req.Header.Set("X-Amz-Content-Sha256", hashedPayload)
+
+	signedHeaders := make(http.Header)
+	signedHeaders.Set("host", req.URL.Host)
+	signedHeaders.Set("x-amz-content-sha256", hashedPayload)
+	signedHeaders.Set("x-amz-date", t.Format(iso8601Format))
+
+	canonicalRequest := getCanonicalRequest(signedHeaders, hashedPayload, req.URL.Query().Encode(), req.URL.Path, req.Method)
+	scope := getScope(t, region)
+	stringToSign := getStringToSign(canonicalRequest, t, scope)
+	signature := getSignature(getSigningKey(cred.SecretKey, t, region), stringToSign)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signV4Algorithm, cred.AccessKey, scope, getSignedHeaders(signedHeaders), signature))
+}
+
+// executeMethod - sends a signed admin request, the response body
+// must be closed by the caller. Error responses are returned as errors.
+func (c *adminClient) executeMethod(method, apiPath string, query url.Values, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = adminAPIPathPrefix + apiPath
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	signV4Request(req, c.cred, adminClientRegion, getSHA256Hash(body))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errResp := APIErrorResponse{}
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxRecordedErrBodySize))
+		if xml.Unmarshal(data, &errResp) != nil || errResp.Code == "" {
+			return nil, fmt.Errorf("Server responded with %s", resp.Status)
+		}
+		return nil, fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+	}
+	return resp, nil
+}
+
+// Sends a signed admin request and decodes the JSON response into v.
+func (c *adminClient) getJSON(apiPath string, v interface{}) error {
+	resp, err := c.executeMethod(httpGET, apiPath, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ServerInfo - returns the server version, uptime, mode and endpoints.
+func (c *adminClient) ServerInfo() (info ServerInfo, err error) {
+	err = c.getJSON("/info", &info)
+	return info, err
+}
+
+// StorageInfo - returns the storage usage of the server.
+func (c *adminClient) StorageInfo() (info StorageInfo, err error) {
+	err = c.getJSON("/storageinfo", &info)
+	return info, err
+}
+
+// HTTPStats - returns the HTTP statistics of the server.
+func (c *adminClient) HTTPStats() (stats ServerHTTPStats, err error) {
+	err = c.getJSON("/httpstats", &stats)
+	return stats, err
+}
+
+// ServiceRestart - restarts the server.
+func (c *adminClient) ServiceRestart() error {
+	resp, err := c.executeMethod(httpPOST, "/service/restart", nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ServiceStop - stops the server.
+func (c *adminClient) ServiceStop() error {
+	resp, err := c.executeMethod(httpPOST, "/service/stop", nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// StartProfiling - starts profiling of the given profiler types.
+func (c *adminClient) StartProfiling(types []string) error {
+	query := url.Values{}
+	query.Set("profilerType", strings.Join(types, ","))
+	resp, err := c.executeMethod(httpPOST, "/profiling/start", query, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// StopProfiling - stops profiling.
+func (c *adminClient) StopProfiling() error {
+	resp, err := c.executeMethod(httpPOST, "/profiling/stop", nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// DownloadProfile - returns the zip archive of the profiles recorded
+// in the last profiling session, the caller must close it.
+func (c *adminClient) DownloadProfile() (io.ReadCloser, error) {
+	resp, err := c.executeMethod(httpGET, "/profiling/download", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetBandwidthLimits - returns the bandwidth limits of all the
+// buckets and access keys.
+func (c *adminClient) GetBandwidthLimits() (limits BandwidthLimits, err error) {
+	err = c.getJSON("/bandwidth", &limits)
+	return limits, err
+}
+
+// SetBandwidthLimit - sets the bandwidth limit of a bucket or an
+// access key, a limit of zero for both directions removes it.
+func (c *adminClient) SetBandwidthLimit(bucket, accessKey string, limit bandwidthLimit) error {
+	body, err := json.Marshal(limit)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if bucket != "" {
+		query.Set("bucket", bucket)
+	}
+	if accessKey != "" {
+		query.Set("accessKey", accessKey)
+	}
+	resp, err := c.executeMethod(httpPUT, "/bandwidth", query, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Trace - streams the requests served by the server which pass the
+// filter to traceFn until the connection is closed or traceFn fails.
+func (c *adminClient) Trace(filter traceFilter, traceFn func(requestTrace) error) error {
+	resp, err := c.executeMethod(httpGET, "/trace", filter.values(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var trace requestTrace
+		if err = decoder.Decode(&trace); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err = traceFn(trace); err != nil {
+			return err
+		}
+	}
+}
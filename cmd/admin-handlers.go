@@ -0,0 +1,535 @@
+/*
+ * Minio Cloud Storage, (C) 2016, 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Names of the admin APIs.
+const (
+	adminServerInfoAPIName      = "AdminServerInfo"
+	adminStorageInfoAPIName     = "AdminStorageInfo"
+	adminHTTPStatsAPIName       = "AdminHTTPStats"
+	adminServiceRestartAPIName  = "AdminServiceRestart"
+	adminServiceStopAPIName     = "AdminServiceStop"
+	adminTraceAPIName           = "AdminTrace"
+	adminStartProfilingAPIName  = "AdminStartProfiling"
+	adminStopProfilingAPIName   = "AdminStopProfiling"
+	adminDownloadProfileAPIName = "AdminDownloadProfile"
+	adminGetBandwidthAPIName    = "AdminGetBandwidth"
+	adminSetBandwidthAPIName    = "AdminSetBandwidth"
+	adminGetBucketQuotaAPIName  = "AdminGetBucketQuota"
+	adminSetBucketQuotaAPIName  = "AdminSetBucketQuota"
+	adminDelBucketQuotaAPIName  = "AdminDeleteBucketQuota"
+	adminDataUsageAPIName       = "AdminDataUsage"
+)
+
+// ServerVersion - server version and the commit it was built from.
+type ServerVersion struct {
+	Version  string `json:"version"`
+	CommitID string `json:"commitID"`
+}
+
+// ServerConnStats - network statistics of the server.
+type ServerConnStats struct {
+	TotalInputBytes  uint64 `json:"totalInputBytes"`
+	TotalOutputBytes uint64 `json:"totalOutputBytes"`
+}
+
+// ServerInfo - holds the server information returned by the
+// server info admin API.
+type ServerInfo struct {
+	Version   ServerVersion   `json:"version"`
+	Uptime    time.Duration   `json:"uptime"`
+	Mode      string          `json:"mode"`
+	Region    string          `json:"region"`
+	Endpoints []string        `json:"endpoints"`
+	ConnStats ServerConnStats `json:"network"`
+}
+
+// BucketQuotaInfo - quota of a bucket, if any, and its usage.
+type BucketQuotaInfo struct {
+	Quota *bucketQuota `json:"quota,omitempty"`
+	Usage BucketUsage  `json:"usage"`
+}
+
+// Writes a JSON encoded successful admin API response.
+func writeAdminSuccessResponseJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	writeSuccessResponseJSON(w, data)
+}
+
+// ServerInfoHandler - GET /minio/admin/v1/info
+// -----------
+// Returns the server version, uptime, setup mode, region and endpoints.
+func (adminAPI adminAPIHandlers) ServerInfoHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	var uptime time.Duration
+	if !globalBootTime.IsZero() {
+		uptime = UTCNow().Sub(globalBootTime)
+	}
+	var endpoints []string
+	for _, endpoint := range globalEndpoints {
+		endpoints = append(endpoints, endpoint.String())
+	}
+
+	writeAdminSuccessResponseJSON(w, r, ServerInfo{
+		Version: ServerVersion{
+			Version:  Version,
+			CommitID: CommitID,
+		},
+		Uptime:    uptime,
+		Mode:      globalSetupType.String(),
+		Region:    serverConfig.GetRegion(),
+		Endpoints: endpoints,
+		ConnStats: ServerConnStats{
+			TotalInputBytes:  globalConnStats.getTotalInputBytes(),
+			TotalOutputBytes: globalConnStats.getTotalOutputBytes(),
+		},
+	})
+}
+
+// StorageInfoHandler - GET /minio/admin/v1/storageinfo
+// -----------
+// Returns the disk usage and backend type of the object layer.
+func (adminAPI adminAPIHandlers) StorageInfoHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	objectAPI := newObjectLayerFn()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	writeAdminSuccessResponseJSON(w, r, objectAPI.StorageInfo())
+}
+
+// HTTPStatsHandler - GET /minio/admin/v1/httpstats
+// -----------
+// Returns the number of requests and their average duration per
+// HTTP method since the server started.
+func (adminAPI adminAPIHandlers) HTTPStatsHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	writeAdminSuccessResponseJSON(w, r, globalHTTPStats.toServerHTTPStats())
+}
+
+// sendServiceSignal - sends the service signal asynchronously, the
+// signal handler waits for the in-flight requests to finish which
+// includes the admin request sending the signal.
+func sendServiceSignal(signal serviceSignal) {
+	go func() {
+		globalServiceSignalCh <- signal
+	}()
+}
+
+// ServiceRestartHandler - POST /minio/admin/v1/service/restart
+// -----------
+// Restarts the server once the response is sent.
+func (adminAPI adminAPIHandlers) ServiceRestartHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+	sendServiceSignal(serviceRestart)
+}
+
+// ServiceStopHandler - POST /minio/admin/v1/service/stop
+// -----------
+// Stops the server once the response is sent.
+func (adminAPI adminAPIHandlers) ServiceStopHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+	sendServiceSignal(serviceStop)
+}
+
+// StartProfilingHandler - POST /minio/admin/v1/profiling/start?profilerType=cpu,mem
+// -----------
+// Starts profiling of the given comma separated profiler types, any of
+// cpu, mem, block, mutex and goroutine.
+func (adminAPI adminAPIHandlers) StartProfilingHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	types, err := parseProfilerTypes(r.URL.Query().Get("profilerType"))
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	if err = globalProfiler.Start(types); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// StopProfilingHandler - POST /minio/admin/v1/profiling/stop
+// -----------
+// Stops profiling, the profiles can be downloaded afterwards.
+func (adminAPI adminAPIHandlers) StopProfilingHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	if err := globalProfiler.Stop(); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// DownloadProfileHandler - GET /minio/admin/v1/profiling/download
+// -----------
+// Returns the profiles of the last profiling session as a zip archive
+// of pprof files.
+func (adminAPI adminAPIHandlers) DownloadProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := globalProfiler.WriteArchive(&buf); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	setCommonHeaders(w)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile.zip"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// TraceHandler - GET /minio/admin/v1/trace
+// -----------
+// Streams the HTTP requests served by this server as JSON objects,
+// each along with the object layer calls made while serving it.
+// Requests can be filtered with the query parameters `bucket`, `api`,
+// `errors=true` and `threshold=<duration>`. Whitespace is sent
+// periodically to keep the connection alive.
+func (adminAPI adminAPIHandlers) TraceHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	filter, err := parseTraceFilter(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, ErrInvalidQueryParams, r.URL)
+		return
+	}
+
+	sub := globalTrace.Subscribe(filter)
+	defer globalTrace.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", string(mimeJSON))
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	keepAliveTicker := time.NewTicker(globalSNSConnAlive)
+	defer keepAliveTicker.Stop()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case info := <-sub.ch:
+			if err = encoder.Encode(info); err != nil {
+				return
+			}
+		case <-keepAliveTicker.C:
+			if _, err = w.Write([]byte(" ")); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+// GetBandwidthHandler - GET /minio/admin/v1/bandwidth
+// -----------
+// Returns the download and upload limits of all the buckets and
+// access keys in bytes per second.
+func (adminAPI adminAPIHandlers) GetBandwidthHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	writeAdminSuccessResponseJSON(w, r, globalBandwidthLimiter.GetLimits())
+}
+
+// SetBandwidthHandler - PUT /minio/admin/v1/bandwidth?bucket=mybucket
+// SetBandwidthHandler - PUT /minio/admin/v1/bandwidth?accessKey=mykey
+// -----------
+// Sets the download and upload limits of a bucket or an access key
+// to the ones of the JSON body, e.g. {"download":1048576,"upload":0}.
+// Limits of zero remove the limit. The limits apply to transfers in
+// progress right away and are kept across restarts.
+func (adminAPI adminAPIHandlers) SetBandwidthHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	var limit bandwidthLimit
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxFormFieldSize)).Decode(&limit); err != nil {
+		writeErrorResponse(w, ErrInvalidRequestBody, r.URL)
+		return
+	}
+	query := r.URL.Query()
+	if err := globalBandwidthLimiter.SetLimit(query.Get("bucket"), query.Get("accessKey"), limit); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	if err := globalBandwidthLimiter.Save(); err != nil {
+		println(err, "Unable to save bandwidth limits.")
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// GetBucketQuotaHandler - GET /minio/admin/v1/quota?bucket=mybucket
+// -----------
+// Returns the quota of a bucket and its usage.
+func (adminAPI adminAPIHandlers) GetBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	objectAPI := newObjectLayerFn()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	usage, err := objectAPI.GetBucketUsage(bucket)
+	if err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	info := BucketQuotaInfo{Usage: usage}
+	if quota, ok := globalBucketQuota.Get(bucket); ok {
+		info.Quota = &quota
+	}
+	writeAdminSuccessResponseJSON(w, r, info)
+}
+
+// SetBucketQuotaHandler - PUT /minio/admin/v1/quota?bucket=mybucket
+// -----------
+// Sets the quota of a bucket to the one of the JSON body, e.g.
+// {"maxBytes":1073741824,"maxObjects":0,"type":"hard"}. Limits of zero
+// are not enforced. Writes exceeding a hard quota are rejected, writes
+// exceeding a soft quota are recorded in the audit log.
+func (adminAPI adminAPIHandlers) SetBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	objectAPI := newObjectLayerFn()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	var quota bucketQuota
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxFormFieldSize)).Decode(&quota); err != nil {
+		writeErrorResponse(w, ErrInvalidRequestBody, r.URL)
+		return
+	}
+	if err := quota.Validate(); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// Quotas are enforced against the usage of the bucket.
+	bucket := r.URL.Query().Get("bucket")
+	if _, err := objectAPI.GetBucketUsage(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	data, err := json.Marshal(quota)
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketQuotaConfig, data); err != nil {
+		println(err, "Unable to save quota of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketQuota.Set(bucket, &quota)
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// DeleteBucketQuotaHandler - DELETE /minio/admin/v1/quota?bucket=mybucket
+// -----------
+// Removes the quota of a bucket.
+func (adminAPI adminAPIHandlers) DeleteBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	objectAPI := newObjectLayerFn()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	if err := removeBucketConfig(objectAPI, bucket, bucketQuotaConfig); err != nil {
+		println(err, "Unable to remove quota of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketQuota.Set(bucket, nil)
+
+	writeSuccessNoContent(w)
+}
+
+// DataUsageHandler - GET /minio/admin/v1/datausage?bucket=mybucket&prefixes=10
+// -----------
+// Returns the usage of a bucket, or of all buckets if none is given,
+// with the histogram of the sizes of their objects and the usage of
+// their largest top level prefixes, 10 by default.
+func (adminAPI adminAPIHandlers) DataUsageHandler(w http.ResponseWriter, r *http.Request) {
+	// Validate request signature.
+	adminAPIErr := checkAdminRequestAuthType(r)
+	if adminAPIErr != ErrNone {
+		writeErrorResponse(w, adminAPIErr, r.URL)
+		return
+	}
+
+	objectAPI := newObjectLayerFn()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	query := r.URL.Query()
+	maxPrefixes := dataUsageTopPrefixes
+	if prefixes := query.Get("prefixes"); prefixes != "" {
+		var err error
+		if maxPrefixes, err = strconv.Atoi(prefixes); err != nil || maxPrefixes < 0 {
+			writeErrorResponse(w, ErrInvalidQueryParams, r.URL)
+			return
+		}
+	}
+
+	var buckets []string
+	if bucket := query.Get("bucket"); bucket != "" {
+		buckets = append(buckets, bucket)
+	} else {
+		bucketsInfo, err := objectAPI.ListBuckets()
+		if err != nil {
+			writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+			return
+		}
+		for _, bucketInfo := range bucketsInfo {
+			buckets = append(buckets, bucketInfo.Name)
+		}
+	}
+
+	info := DataUsageInfo{Buckets: []BucketUsageReport{}}
+	for _, bucket := range buckets {
+		// Buckets not crawled yet are crawled right away.
+		if _, err := objectAPI.GetBucketUsage(bucket); err != nil {
+			writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+			return
+		}
+		if report, ok := globalBucketUsage.Report(bucket, maxPrefixes); ok {
+			info.Buckets = append(info.Buckets, report)
+		}
+	}
+	writeAdminSuccessResponseJSON(w, r, info)
+}
@@ -0,0 +1,440 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"shareos/cli"
+)
+
+var adminCmd = cli.Command{
+	Name:  "admin",
+	Usage: "Manage a running server.",
+	Subcommands: []cli.Command{
+		adminInfoCmd,
+		adminStorageCmd,
+		adminStatsCmd,
+		adminRestartCmd,
+		adminStopCmd,
+		adminProfileCmd,
+		adminTraceCmd,
+		adminBandwidthCmd,
+	},
+}
+
+// Help template shared by all the admin sub-commands.
+var adminCmdHelpTemplate = `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} {{if .VisibleFlags}}[FLAGS] {{end}}URL
+{{if .VisibleFlags}}
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}{{end}}
+ENVIRONMENT VARIABLES:
+  ACCESS:
+     MINIO_ACCESS_KEY: Access key of the server.
+     MINIO_SECRET_KEY: Secret key of the server.
+`
+
+var adminJSONFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the result as a JSON object.",
+	},
+}
+
+var adminInfoCmd = cli.Command{
+	Name:               "info",
+	Usage:              "Show the server version, uptime and network traffic.",
+	Flags:              adminJSONFlags,
+	Action:             adminInfoMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminStorageCmd = cli.Command{
+	Name:               "storage",
+	Usage:              "Show the disk usage of the server.",
+	Flags:              adminJSONFlags,
+	Action:             adminStorageMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminStatsCmd = cli.Command{
+	Name:               "stats",
+	Usage:              "Show the HTTP statistics of the server.",
+	Flags:              adminJSONFlags,
+	Action:             adminStatsMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminRestartCmd = cli.Command{
+	Name:               "restart",
+	Usage:              "Restart the server.",
+	Action:             adminRestartMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminStopCmd = cli.Command{
+	Name:               "stop",
+	Usage:              "Stop the server.",
+	Action:             adminStopMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminProfileFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "type",
+		Value: profilerCPU,
+		Usage: "Comma separated list of profiles to record: cpu, mem, block, mutex, goroutine.",
+	},
+	cli.DurationFlag{
+		Name:  "duration",
+		Value: 30 * time.Second,
+		Usage: "Duration of the profiling, profiling stops earlier on interrupt.",
+	},
+	cli.StringFlag{
+		Name:  "output",
+		Value: "profile.zip",
+		Usage: "Zip archive the recorded profiles are saved to.",
+	},
+}
+
+var adminProfileCmd = cli.Command{
+	Name:               "profile",
+	Usage:              "Record runtime profiles of the server.",
+	Flags:              adminProfileFlags,
+	Action:             adminProfileMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+var adminTraceFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "bucket",
+		Usage: "Only show requests on this bucket.",
+	},
+	cli.StringFlag{
+		Name:  "api",
+		Usage: "Only show requests of this API, e.g. GetObject.",
+	},
+	cli.BoolFlag{
+		Name:  "errors",
+		Usage: "Only show requests answered with an error.",
+	},
+	cli.DurationFlag{
+		Name:  "threshold",
+		Usage: "Only show requests taking at least this long, e.g. 100ms.",
+	},
+	cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print each request as a JSON object.",
+	},
+}
+
+var adminTraceCmd = cli.Command{
+	Name:               "trace",
+	Usage:              "Show the requests served by the server in real time.",
+	Flags:              adminTraceFlags,
+	Action:             adminTraceMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+// adminFatalIf - prints the error and exits if err is not nil.
+func adminFatalIf(err error, msg string) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s %s\n", msg, err)
+	os.Exit(1)
+}
+
+var adminBandwidthFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "bucket",
+		Usage: "Set the limit of this bucket.",
+	},
+	cli.StringFlag{
+		Name:  "access-key",
+		Usage: "Set the limit of this access key.",
+	},
+	cli.StringFlag{
+		Name:  "download",
+		Value: "0",
+		Usage: "Download rate per second, e.g. 10MiB, 0 removes the limit.",
+	},
+	cli.StringFlag{
+		Name:  "upload",
+		Value: "0",
+		Usage: "Upload rate per second, e.g. 10MiB, 0 removes the limit.",
+	},
+	cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the limits as a JSON object.",
+	},
+}
+
+var adminBandwidthCmd = cli.Command{
+	Name:               "bandwidth",
+	Usage:              "Show or set the bandwidth limits of buckets and access keys.",
+	Flags:              adminBandwidthFlags,
+	Action:             adminBandwidthMain,
+	CustomHelpTemplate: adminCmdHelpTemplate,
+}
+
+// newAdminClientFromCtx - returns an admin client for the server URL
+// given as the only argument, using the credentials set in the
+// environment.
+func newAdminClientFromCtx(ctx *cli.Context, cmdName string) *adminClient {
+	if ctx.NArg() != 1 {
+		cli.ShowCommandHelpAndExit(ctx, cmdName, 1)
+	}
+	cred, ok, err := getCredentialFromEnv()
+	adminFatalIf(err, "Invalid access/secret key set in environment.")
+	if !ok {
+		adminFatalIf(errors.New("MINIO_ACCESS_KEY and MINIO_SECRET_KEY are not set"), "Missing credentials.")
+	}
+	// Servers using certificates of a private CA are verified
+	// against the CAs of the certs directory.
+	if configDir := ctx.GlobalString("config-dir"); configDir != "" {
+		setConfigDir(configDir)
+	}
+	globalRootCAs, err = loadRootCAs(getCADir())
+	adminFatalIf(err, "Unable to load the CA certificates.")
+	client, err := newAdminClient(ctx.Args().First(), cred)
+	adminFatalIf(err, "Unable to initialize admin client.")
+	return client
+}
+
+// Prints v as an indented JSON object.
+func printAdminJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	adminFatalIf(err, "Unable to marshal JSON.")
+	fmt.Println(string(data))
+}
+
+// adminInfoMain handler called for 'minio admin info' command.
+func adminInfoMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "info")
+	info, err := client.ServerInfo()
+	adminFatalIf(err, "Unable to get server info.")
+	if ctx.Bool("json") {
+		printAdminJSON(info)
+		return
+	}
+
+	fmt.Printf("Version: %s\n", info.Version.Version)
+	fmt.Printf("Commit: %s\n", info.Version.CommitID)
+	fmt.Printf("Uptime: %s\n", info.Uptime.Truncate(time.Second))
+	fmt.Printf("Mode: %s\n", info.Mode)
+	fmt.Printf("Region: %s\n", info.Region)
+	fmt.Printf("Network: received %s, sent %s\n",
+		humanize.IBytes(info.ConnStats.TotalInputBytes), humanize.IBytes(info.ConnStats.TotalOutputBytes))
+	fmt.Println("Endpoints:")
+	for _, endpoint := range info.Endpoints {
+		fmt.Printf("  %s\n", endpoint)
+	}
+}
+
+// adminStorageMain handler called for 'minio admin storage' command.
+func adminStorageMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "storage")
+	info, err := client.StorageInfo()
+	adminFatalIf(err, "Unable to get storage info.")
+	if ctx.Bool("json") {
+		printAdminJSON(info)
+		return
+	}
+
+	fmt.Printf("Total: %s\n", humanize.IBytes(uint64(info.Total)))
+	fmt.Printf("Free: %s\n", humanize.IBytes(uint64(info.Free)))
+	fmt.Printf("Used: %s\n", humanize.IBytes(uint64(info.Total-info.Free)))
+}
+
+// adminStatsMain handler called for 'minio admin stats' command.
+func adminStatsMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "stats")
+	stats, err := client.HTTPStats()
+	adminFatalIf(err, "Unable to get HTTP statistics.")
+	if ctx.Bool("json") {
+		printAdminJSON(stats)
+		return
+	}
+
+	fmt.Printf("%-8s %10s %12s %10s %12s\n", "METHOD", "TOTAL", "AVG", "SUCCESS", "AVG")
+	for _, m := range []struct {
+		method         string
+		total, success ServerHTTPMethodStats
+	}{
+		{httpHEAD, stats.TotalHEADStats, stats.SuccessHEADStats},
+		{httpGET, stats.TotalGETStats, stats.SuccessGETStats},
+		{httpPUT, stats.TotalPUTStats, stats.SuccessPUTStats},
+		{httpPOST, stats.TotalPOSTStats, stats.SuccessPOSTStats},
+		{httpDELETE, stats.TotalDELETEStats, stats.SuccessDELETEStats},
+	} {
+		fmt.Printf("%-8s %10d %12s %10d %12s\n", m.method,
+			m.total.Count, m.total.AvgDuration, m.success.Count, m.success.AvgDuration)
+	}
+}
+
+// adminRestartMain handler called for 'minio admin restart' command.
+func adminRestartMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "restart")
+	adminFatalIf(client.ServiceRestart(), "Unable to restart the server.")
+	fmt.Println("Restart signal sent to the server.")
+}
+
+// adminStopMain handler called for 'minio admin stop' command.
+func adminStopMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "stop")
+	adminFatalIf(client.ServiceStop(), "Unable to stop the server.")
+	fmt.Println("Stop signal sent to the server.")
+}
+
+// adminProfileMain handler called for 'minio admin profile' command.
+func adminProfileMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "profile")
+	types, err := parseProfilerTypes(ctx.String("type"))
+	adminFatalIf(err, "Invalid profile type.")
+
+	adminFatalIf(client.StartProfiling(types), "Unable to start profiling.")
+	fmt.Printf("Profiling %s for %s...\n", strings.Join(types, ","), ctx.Duration("duration"))
+
+	// Stop profiling after the duration or on interrupt.
+	trapCh := make(chan os.Signal, 1)
+	signal.Notify(trapCh, os.Interrupt)
+	select {
+	case <-time.After(ctx.Duration("duration")):
+	case <-trapCh:
+	}
+	signal.Stop(trapCh)
+	adminFatalIf(client.StopProfiling(), "Unable to stop profiling.")
+
+	archive, err := client.DownloadProfile()
+	adminFatalIf(err, "Unable to download the profiles.")
+	defer archive.Close()
+
+	output := ctx.String("output")
+	f, err := os.Create(output)
+	adminFatalIf(err, "Unable to create the output file.")
+	_, err = io.Copy(f, archive)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	adminFatalIf(err, "Unable to save the profiles.")
+	fmt.Printf("Profiles saved to %s\n", output)
+}
+
+// Prints a traced request in a human readable form.
+func printRequestTrace(trace requestTrace) {
+	status := fmt.Sprintf("%d", trace.StatusCode)
+	if trace.ErrorCode != "" {
+		status += " " + trace.ErrorCode
+	}
+	path := trace.Path
+	if trace.Query != "" {
+		path += "?" + trace.Query
+	}
+	fmt.Printf("%s %s %s %s %s %s in=%s out=%s %s\n",
+		trace.Time.Format(time.RFC3339Nano), trace.RemoteAddr, trace.API,
+		trace.Method, path, status,
+		humanize.IBytes(uint64(trace.InputBytes)), humanize.IBytes(uint64(trace.OutputBytes)),
+		trace.Duration)
+	for _, call := range trace.Calls {
+		if call.Error != "" {
+			fmt.Printf("    %s %s error=%q\n", call.Name, call.Duration, call.Error)
+		} else {
+			fmt.Printf("    %s %s\n", call.Name, call.Duration)
+		}
+	}
+}
+
+// adminTraceMain handler called for 'minio admin trace' command.
+func adminTraceMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "trace")
+	filter := traceFilter{
+		Bucket:     ctx.String("bucket"),
+		API:        ctx.String("api"),
+		ErrorsOnly: ctx.Bool("errors"),
+		Threshold:  ctx.Duration("threshold"),
+	}
+
+	jsonFlag := ctx.Bool("json")
+	encoder := json.NewEncoder(os.Stdout)
+	err := client.Trace(filter, func(trace requestTrace) error {
+		if jsonFlag {
+			return encoder.Encode(trace)
+		}
+		printRequestTrace(trace)
+		return nil
+	})
+	adminFatalIf(err, "Unable to trace the server.")
+}
+
+// Prints the bandwidth limits of kind in a human readable form.
+func printBandwidthLimits(kind string, limits map[string]bandwidthLimit) {
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	formatRate := func(rate int64) string {
+		if rate == 0 {
+			return "unlimited"
+		}
+		return humanize.IBytes(uint64(rate)) + "/s"
+	}
+	for _, name := range names {
+		fmt.Printf("%-10s %-32s download=%s upload=%s\n", kind, name,
+			formatRate(limits[name].Download), formatRate(limits[name].Upload))
+	}
+}
+
+// adminBandwidthMain handler called for 'minio admin bandwidth' command.
+func adminBandwidthMain(ctx *cli.Context) {
+	client := newAdminClientFromCtx(ctx, "bandwidth")
+	bucket, accessKey := ctx.String("bucket"), ctx.String("access-key")
+	if bucket == "" && accessKey == "" {
+		limits, err := client.GetBandwidthLimits()
+		adminFatalIf(err, "Unable to get bandwidth limits.")
+		if ctx.Bool("json") {
+			printAdminJSON(limits)
+			return
+		}
+		printBandwidthLimits("bucket", limits.Buckets)
+		printBandwidthLimits("access-key", limits.AccessKeys)
+		return
+	}
+
+	download, err := humanize.ParseBytes(ctx.String("download"))
+	adminFatalIf(err, "Invalid download rate.")
+	upload, err := humanize.ParseBytes(ctx.String("upload"))
+	adminFatalIf(err, "Invalid upload rate.")
+	limit := bandwidthLimit{Download: int64(download), Upload: int64(upload)}
+	adminFatalIf(client.SetBandwidthLimit(bucket, accessKey, limit), "Unable to set bandwidth limit.")
+	fmt.Println("Bandwidth limit set.")
+}
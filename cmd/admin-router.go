@@ -0,0 +1,84 @@
+/*
+ * Minio Cloud Storage, (C) 2016, 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import router "github.com/gorilla/mux"
+
+// Admin API path prefix.
+const adminAPIPathPrefix = minioReservedBucketPath + "/admin/v1"
+
+// adminAPIHandlers provides HTTP handlers for Minio admin API.
+type adminAPIHandlers struct {
+}
+
+// registerAdminRouter - Add handler functions for each service REST API routes.
+func registerAdminRouter(mux *router.Router) {
+	adminAPI := adminAPIHandlers{}
+	// Admin router
+	adminRouter := mux.NewRoute().PathPrefix(adminAPIPathPrefix).Subrouter()
+
+	/// Server operations
+
+	// Server info
+	adminRouter.Methods(httpGET).Path("/info").HandlerFunc(adminAPI.ServerInfoHandler)
+	// Storage info
+	adminRouter.Methods(httpGET).Path("/storageinfo").HandlerFunc(adminAPI.StorageInfoHandler)
+	// HTTP stats
+	adminRouter.Methods(httpGET).Path("/httpstats").HandlerFunc(adminAPI.HTTPStatsHandler)
+
+	/// Service operations
+
+	// Service restart
+	adminRouter.Methods(httpPOST).Path("/service/restart").HandlerFunc(adminAPI.ServiceRestartHandler)
+	// Service stop
+	adminRouter.Methods(httpPOST).Path("/service/stop").HandlerFunc(adminAPI.ServiceStopHandler)
+
+	/// Profiling operations
+
+	// Start profiling
+	adminRouter.Methods(httpPOST).Path("/profiling/start").HandlerFunc(adminAPI.StartProfilingHandler)
+	// Stop profiling
+	adminRouter.Methods(httpPOST).Path("/profiling/stop").HandlerFunc(adminAPI.StopProfilingHandler)
+	// Download profiles
+	adminRouter.Methods(httpGET).Path("/profiling/download").HandlerFunc(adminAPI.DownloadProfileHandler)
+
+	/// Bandwidth operations
+
+	// Get bandwidth limits
+	adminRouter.Methods(httpGET).Path("/bandwidth").HandlerFunc(adminAPI.GetBandwidthHandler)
+	// Set the bandwidth limit of a bucket or an access key
+	adminRouter.Methods(httpPUT).Path("/bandwidth").HandlerFunc(adminAPI.SetBandwidthHandler)
+
+	/// Quota operations
+
+	// Get the quota and the usage of a bucket
+	adminRouter.Methods(httpGET).Path("/quota").HandlerFunc(adminAPI.GetBucketQuotaHandler)
+	// Set the quota of a bucket
+	adminRouter.Methods(httpPUT).Path("/quota").HandlerFunc(adminAPI.SetBucketQuotaHandler)
+	// Remove the quota of a bucket
+	adminRouter.Methods(httpDELETE).Path("/quota").HandlerFunc(adminAPI.DeleteBucketQuotaHandler)
+
+	/// Data usage operations
+
+	// Get the data usage of buckets and their prefixes
+	adminRouter.Methods(httpGET).Path("/datausage").HandlerFunc(adminAPI.DataUsageHandler)
+
+	/// Trace operations
+
+	// Trace
+	adminRouter.Methods(httpGET).Path("/trace").HandlerFunc(adminAPI.TraceHandler)
+}
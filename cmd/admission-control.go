@@ -0,0 +1,230 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Classes of API calls with separate budgets.
+const (
+	admissionRead  = "read"
+	admissionWrite = "write"
+	admissionList  = "list"
+)
+
+const (
+	// Requests queued per class, as a multiple of its budget.
+	admissionQueueFactor = 4
+
+	// Seconds clients are asked to wait before retrying.
+	admissionRetryAfter = 1
+)
+
+var errSlowDown = errors.New("Too many requests in progress, please reduce your request rate")
+
+// admissionWaiter - a request waiting for a free slot.
+type admissionWaiter struct {
+	accessKey string
+	elem      *list.Element
+	admitted  bool
+	ch        chan struct{}
+}
+
+// admissionQueue - limits the number of requests of a class in
+// progress. Requests exceeding the limit wait in a FIFO queue per
+// access key, freed slots are handed to the queues in turn so every
+// access key gets its share regardless of how many requests it sends.
+type admissionQueue struct {
+	mu        sync.Mutex
+	capacity  int
+	maxQueued int
+	inFlight  int
+	queued    int
+
+	// Waiting requests by access key.
+	waiters map[string]*list.List
+	// Access keys with waiting requests, served round robin.
+	keys []string
+	next int
+}
+
+// newAdmissionQueue - returns a queue admitting capacity requests at
+// a time, a capacity of zero admits all requests.
+func newAdmissionQueue(capacity int) *admissionQueue {
+	return &admissionQueue{
+		capacity:  capacity,
+		maxQueued: capacity * admissionQueueFactor,
+		waiters:   make(map[string]*list.List),
+	}
+}
+
+// acquire - waits up to timeout for a free slot, returns errSlowDown
+// if the queue is full or no slot was freed in time.
+func (q *admissionQueue) acquire(accessKey string, timeout time.Duration, doneCh <-chan struct{}) error {
+	if q.capacity == 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	if q.inFlight < q.capacity && q.queued == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return nil
+	}
+	if q.queued >= q.maxQueued {
+		q.mu.Unlock()
+		return errSlowDown
+	}
+	w := &admissionWaiter{accessKey: accessKey, ch: make(chan struct{})}
+	waiters, ok := q.waiters[accessKey]
+	if !ok {
+		waiters = list.New()
+		q.waiters[accessKey] = waiters
+		q.keys = append(q.keys, accessKey)
+	}
+	w.elem = waiters.PushBack(w)
+	q.queued++
+	q.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-w.ch:
+		return nil
+	case <-timer.C:
+	case <-doneCh:
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if w.admitted {
+		// Admitted while timing out.
+		return nil
+	}
+	q.remove(w)
+	return errSlowDown
+}
+
+// release - frees the slot of a finished request.
+func (q *admissionQueue) release() {
+	if q.capacity == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight--
+	for q.inFlight < q.capacity && q.queued > 0 {
+		if q.next >= len(q.keys) {
+			q.next = 0
+		}
+		waiters := q.waiters[q.keys[q.next]]
+		w := waiters.Front().Value.(*admissionWaiter)
+		// Serve the next access key unless this one is removed,
+		// which moves the next key to the current position.
+		if waiters.Len() > 1 {
+			q.next++
+		}
+		q.remove(w)
+		w.admitted = true
+		q.inFlight++
+		close(w.ch)
+	}
+}
+
+// Removes w from the waiting requests, must be called with the lock held.
+func (q *admissionQueue) remove(w *admissionWaiter) {
+	waiters := q.waiters[w.accessKey]
+	waiters.Remove(w.elem)
+	q.queued--
+	if waiters.Len() > 0 {
+		return
+	}
+	delete(q.waiters, w.accessKey)
+	for i, key := range q.keys {
+		if key == w.accessKey {
+			q.keys = append(q.keys[:i], q.keys[i+1:]...)
+			if i < q.next {
+				q.next--
+			}
+			break
+		}
+	}
+}
+
+// getAdmissionClass - returns the budget the API call is accounted to.
+func getAdmissionClass(r *http.Request) string {
+	if strings.HasPrefix(getAPIName(r), "List") {
+		return admissionList
+	}
+	switch r.Method {
+	case httpGET, httpHEAD:
+		return admissionRead
+	}
+	return admissionWrite
+}
+
+// admissionControlHandler definition: limits the number of API calls
+// in progress.
+type admissionControlHandler struct {
+	handler http.Handler
+	queues  map[string]*admissionQueue
+	timeout time.Duration
+}
+
+// setAdmissionControlHandler sets an admission control handler with
+// the budgets configured for read, write and list calls.
+func setAdmissionControlHandler(h http.Handler) http.Handler {
+	return admissionControlHandler{
+		handler: h,
+		queues: map[string]*admissionQueue{
+			admissionRead:  newAdmissionQueue(globalAPIReadRequests),
+			admissionWrite: newAdmissionQueue(globalAPIWriteRequests),
+			admissionList:  newAdmissionQueue(globalAPIListRequests),
+		},
+		timeout: globalAPIRequestsDeadline,
+	}
+}
+
+func (h admissionControlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Health checks, metrics and admin calls are never queued.
+	bucket, _ := urlPath2BucketObjectName(r.URL)
+	if isMinioReservedBucket(bucket) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	// The access key is not validated yet, a client claiming the
+	// access key of another one only competes with its requests.
+	q := h.queues[getAdmissionClass(r)]
+	if err := q.acquire(getRequestAccessKey(r), h.timeout, r.Context().Done()); err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(admissionRetryAfter))
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	defer q.release()
+
+	h.handler.ServeHTTP(w, r)
+}
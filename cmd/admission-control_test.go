@@ -0,0 +1,257 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Queues a request of accessKey on q, the returned channel receives
+// the result of acquire.
+func queueAdmission(t *testing.T, q *admissionQueue, accessKey string, timeout time.Duration, doneCh <-chan struct{}) <-chan error {
+	queued := q.queued
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- q.acquire(accessKey, timeout, doneCh)
+	}()
+	// Wait for the request to be queued.
+	for {
+		q.mu.Lock()
+		n := q.queued
+		q.mu.Unlock()
+		if n > queued {
+			return errCh
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Tests that freed slots are handed to the access keys in turn,
+// whatever the order and number of their waiting requests.
+func TestAdmissionQueueFairness(t *testing.T) {
+	testCases := []struct {
+		capacity int
+		queued   []string
+		expected []string
+	}{
+		{1, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{1, []string{"a", "a", "a", "b"}, []string{"a", "b", "a", "a"}},
+		{2, []string{"a", "a", "b", "b", "c"}, []string{"a", "b", "c", "a", "b"}},
+		{2, []string{"a", "a", "a", "b", "b", "c"}, []string{"a", "b", "c", "a", "b", "a"}},
+	}
+	for i, testCase := range testCases {
+		q := newAdmissionQueue(testCase.capacity)
+		for j := 0; j < testCase.capacity; j++ {
+			if err := q.acquire("busy", time.Minute, nil); err != nil {
+				t.Fatalf("Test %d: expected a free slot, got %v", i+1, err)
+			}
+		}
+
+		var errChs []<-chan error
+		for _, accessKey := range testCase.queued {
+			errChs = append(errChs, queueAdmission(t, q, accessKey, time.Minute, nil))
+		}
+		var waiters []*admissionWaiter
+		q.mu.Lock()
+		for _, key := range q.keys {
+			for e := q.waiters[key].Front(); e != nil; e = e.Next() {
+				waiters = append(waiters, e.Value.(*admissionWaiter))
+			}
+		}
+		q.mu.Unlock()
+
+		// Each freed slot admits exactly one waiting request.
+		var admitted []string
+		for range testCase.queued {
+			q.release()
+			for j, w := range waiters {
+				select {
+				case <-w.ch:
+					admitted = append(admitted, w.accessKey)
+					waiters = append(waiters[:j], waiters[j+1:]...)
+				default:
+					continue
+				}
+				break
+			}
+		}
+		if strings.Join(admitted, ",") != strings.Join(testCase.expected, ",") {
+			t.Fatalf("Test %d: expected %v to be admitted, got %v", i+1, testCase.expected, admitted)
+		}
+		for _, errCh := range errChs {
+			if err := <-errCh; err != nil {
+				t.Fatalf("Test %d: expected the request to be admitted, got %v", i+1, err)
+			}
+		}
+		if q.inFlight != testCase.capacity || q.queued != 0 || len(q.keys) != 0 || len(q.waiters) != 0 {
+			t.Fatalf("Test %d: expected no waiting requests and %d in flight, got %d queued, keys %v and %d in flight",
+				i+1, testCase.capacity, q.queued, q.keys, q.inFlight)
+		}
+	}
+}
+
+// Tests that requests give up their place once timed out or canceled,
+// without disturbing the turn of the other access keys.
+func TestAdmissionQueueTimeout(t *testing.T) {
+	q := newAdmissionQueue(1)
+	if err := q.acquire("busy", time.Minute, nil); err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+
+	aCh := queueAdmission(t, q, "a", time.Minute, nil)
+	bCh := queueAdmission(t, q, "b", 200*time.Millisecond, nil)
+	doneCh := make(chan struct{})
+	cCh := queueAdmission(t, q, "c", time.Minute, doneCh)
+
+	if err := <-bCh; err != errSlowDown {
+		t.Fatalf("Expected a timed out request to slow down, got %v", err)
+	}
+	close(doneCh)
+	if err := <-cCh; err != errSlowDown {
+		t.Fatalf("Expected a canceled request to slow down, got %v", err)
+	}
+	if q.queued != 1 || len(q.keys) != 1 || q.keys[0] != "a" {
+		t.Fatalf("Expected only a to be waiting, got %d queued, keys %v", q.queued, q.keys)
+	}
+
+	q.release()
+	if err := <-aCh; err != nil {
+		t.Fatalf("Expected the request to be admitted, got %v", err)
+	}
+	if q.inFlight != 1 || q.queued != 0 {
+		t.Fatalf("Expected 1 request in flight and none queued, got %d and %d", q.inFlight, q.queued)
+	}
+}
+
+// Tests that a request admitted while timing out keeps its slot.
+func TestAdmissionQueueAdmittedWhileTimingOut(t *testing.T) {
+	q := newAdmissionQueue(1)
+	if err := q.acquire("busy", time.Minute, nil); err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+	doneCh := make(chan struct{})
+	errCh := queueAdmission(t, q, "a", time.Minute, doneCh)
+
+	// Cancel the request while holding the lock, then hand it the
+	// slot of the busy request the way release does.
+	q.mu.Lock()
+	close(doneCh)
+	time.Sleep(10 * time.Millisecond)
+	w := q.waiters["a"].Front().Value.(*admissionWaiter)
+	q.remove(w)
+	w.admitted = true
+	close(w.ch)
+	q.mu.Unlock()
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Expected the admitted request to succeed, got %v", err)
+	}
+	if q.inFlight != 1 || q.queued != 0 || len(q.keys) != 0 {
+		t.Fatalf("Expected the admitted request to keep its slot, got %d in flight, %d queued, keys %v",
+			q.inFlight, q.queued, q.keys)
+	}
+}
+
+// Tests that requests slow down right away once the queue is full.
+func TestAdmissionQueueFull(t *testing.T) {
+	for _, capacity := range []int{1, 3} {
+		q := newAdmissionQueue(capacity)
+		for j := 0; j < capacity; j++ {
+			if err := q.acquire("a", time.Minute, nil); err != nil {
+				t.Fatalf("Capacity %d: expected a free slot, got %v", capacity, err)
+			}
+		}
+		for j := 0; j < capacity*admissionQueueFactor; j++ {
+			queueAdmission(t, q, "a", time.Minute, nil)
+		}
+		if err := q.acquire("b", time.Minute, nil); err != errSlowDown {
+			t.Fatalf("Capacity %d: expected a full queue to slow down, got %v", capacity, err)
+		}
+		for j := 0; j < capacity*admissionQueueFactor; j++ {
+			q.release()
+		}
+	}
+
+	// A capacity of zero admits all requests.
+	q := newAdmissionQueue(0)
+	for j := 0; j < 100; j++ {
+		if err := q.acquire("a", time.Millisecond, nil); err != nil {
+			t.Fatalf("Expected all requests to be admitted, got %v", err)
+		}
+	}
+}
+
+// Tests that requests exceeding the budget of their class get a
+// SlowDown error with Retry-After, while reserved paths are served.
+func TestAdmissionControlHandler(t *testing.T) {
+	defer func(config *serverConfigV2) { serverConfig = config }(serverConfig)
+	serverConfig = newServerConfig()
+
+	release := make(chan struct{})
+	served := make(chan struct{}, 1)
+	h := admissionControlHandler{
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == httpPUT {
+				served <- struct{}{}
+				<-release
+			}
+		}),
+		queues: map[string]*admissionQueue{
+			admissionRead:  newAdmissionQueue(0),
+			admissionWrite: newAdmissionQueue(1),
+			admissionList:  newAdmissionQueue(0),
+		},
+		timeout: time.Millisecond,
+	}
+	h.queues[admissionWrite].maxQueued = 0
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(httpPUT, "/bucket/object", nil))
+	<-served
+	defer close(release)
+
+	testCases := []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{httpPUT, "/bucket/other", http.StatusServiceUnavailable},
+		{httpGET, "/bucket/object", http.StatusOK},
+		{httpGET, "/bucket", http.StatusOK},
+		{httpGET, minioReservedBucketPath + "/health/live", http.StatusOK},
+	}
+	for i, testCase := range testCases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(testCase.method, testCase.path, nil))
+		if w.Code != testCase.expectedCode {
+			t.Fatalf("Test %d: expected status %d, got %d", i+1, testCase.expectedCode, w.Code)
+		}
+		if testCase.expectedCode != http.StatusServiceUnavailable {
+			continue
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != strconv.Itoa(admissionRetryAfter) {
+			t.Errorf("Test %d: expected Retry-After %d, got %q", i+1, admissionRetryAfter, retryAfter)
+		}
+		if apiErr := getAPIError(ErrSlowDown); !strings.Contains(w.Body.String(), apiErr.Code) {
+			t.Errorf("Test %d: expected %s error, got %s", i+1, apiErr.Code, w.Body.String())
+		}
+	}
+}
@@ -115,6 +115,7 @@ const (
 	ErrBucketAlreadyOwnedByYou
 	ErrInvalidDuration
 	ErrNotSupported
+	ErrInvalidTargetBucketForLogging
 	// Add new error codes here.
 
 	// Bucket notification related errors.
@@ -149,6 +150,30 @@ const (
 	ErrAdminInvalidAccessKey
 	ErrAdminInvalidSecretKey
 	ErrAdminConfigNoQuorum
+	ErrAdminInvalidProfilerType
+	ErrAdminProfilerRunning
+	ErrAdminProfilerNotRunning
+	ErrAdminProfilerNoProfiles
+	ErrSlowDown
+	ErrAdminInvalidBandwidthLimit
+	ErrNoSuchVersion
+	ErrIllegalVersioningConfiguration
+	ErrObjectLocked
+	ErrObjectLockConfigurationNotFound
+	ErrInvalidBucketObjectLockConfiguration
+	ErrNoSuchObjectLockConfiguration
+	ErrObjectLockInvalidHeaders
+	ErrUnknownWORMModeDirective
+	ErrInvalidRetentionDate
+	ErrPastObjectLockRetainDate
+	ErrInvalidLegalHoldStatus
+	ErrNoSuchLifecycleConfiguration
+	ErrInvalidTag
+	ErrInvalidTaggingDirective
+	ErrNoSuchCORSConfiguration
+	ErrCORSForbidden
+	ErrBucketQuotaExceeded
+	ErrAdminInvalidBucketQuota
 )
 
 // error code to APIError structure, these fields carry respective
@@ -497,6 +522,11 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Duration provided in the request is invalid.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrInvalidTargetBucketForLogging: {
+		Code:           "InvalidTargetBucketForLogging",
+		Description:    "The target bucket for logging does not exist or is not a valid bucket name.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 
 	/// Bucket notification related errors.
 	ErrEventNotification: {
@@ -618,6 +648,126 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Configuration update failed because server quorum was not met",
 		HTTPStatusCode: http.StatusServiceUnavailable,
 	},
+	ErrAdminInvalidProfilerType: {
+		Code:           "XMinioAdminInvalidProfilerType",
+		Description:    "The profiler type is invalid, supported types are cpu, mem, block, mutex and goroutine.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrAdminProfilerRunning: {
+		Code:           "XMinioAdminProfilerRunning",
+		Description:    "Profiling is already running, stop it first.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrAdminProfilerNotRunning: {
+		Code:           "XMinioAdminProfilerNotRunning",
+		Description:    "Profiling is not running.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrAdminProfilerNoProfiles: {
+		Code:           "XMinioAdminProfilerNoProfiles",
+		Description:    "No profiles have been recorded yet.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrSlowDown: {
+		Code:           "SlowDown",
+		Description:    "Please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrAdminInvalidBandwidthLimit: {
+		Code:           "XMinioAdminInvalidBandwidthLimit",
+		Description:    "Bandwidth limits apply to either a bucket or an access key and must not be negative.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchVersion: {
+		Code:           "NoSuchVersion",
+		Description:    "The specified version does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrIllegalVersioningConfiguration: {
+		Code:           "IllegalVersioningConfigurationException",
+		Description:    "The versioning configuration specified in the request is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLocked: {
+		Code:           "InvalidRequest",
+		Description:    "Object is WORM protected and cannot be overwritten",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLockConfigurationNotFound: {
+		Code:           "ObjectLockConfigurationNotFoundError",
+		Description:    "Object Lock configuration does not exist for this bucket",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBucketObjectLockConfiguration: {
+		Code:           "InvalidRequest",
+		Description:    "Bucket is missing ObjectLockConfiguration",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		Code:           "NoSuchObjectLockConfiguration",
+		Description:    "The specified object does not have a ObjectLock configuration",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrObjectLockInvalidHeaders: {
+		Code:           "InvalidRequest",
+		Description:    "x-amz-object-lock-retain-until-date and x-amz-object-lock-mode must both be supplied",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrUnknownWORMModeDirective: {
+		Code:           "InvalidRequest",
+		Description:    "unknown wormMode directive",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidRetentionDate: {
+		Code:           "InvalidRequest",
+		Description:    "Date must be provided in ISO 8601 format",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrPastObjectLockRetainDate: {
+		Code:           "InvalidRequest",
+		Description:    "the retain until date must be in the future",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidLegalHoldStatus: {
+		Code:           "InvalidArgument",
+		Description:    "Legal Hold must be either of 'ON' or 'OFF'",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchLifecycleConfiguration: {
+		Code:           "NoSuchLifecycleConfiguration",
+		Description:    "The lifecycle configuration does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidTag: {
+		Code:           "InvalidTag",
+		Description:    "The tag provided was not a valid tag",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTaggingDirective: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown tagging directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchCORSConfiguration: {
+		Code:           "NoSuchCORSConfiguration",
+		Description:    "The CORS configuration does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrCORSForbidden: {
+		Code:           "AccessForbidden",
+		Description:    "CORSResponse: This CORS request is not allowed. This is usually because the evaluation of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrBucketQuotaExceeded: {
+		Code:           "XMinioBucketQuotaExceeded",
+		Description:    "Bucket quota exceeded.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrAdminInvalidBucketQuota: {
+		Code:           "XMinioAdminInvalidBucketQuota",
+		Description:    "Bucket quota must limit bytes or objects, must not be negative and must be of type hard or soft.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 
 	// Add your error structure here.
 }
@@ -641,6 +791,20 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrEntityTooLarge
 	case errDataTooSmall:
 		apiErr = ErrEntityTooSmall
+	case errInvalidProfilerType:
+		apiErr = ErrAdminInvalidProfilerType
+	case errProfilerRunning:
+		apiErr = ErrAdminProfilerRunning
+	case errProfilerNotRunning:
+		apiErr = ErrAdminProfilerNotRunning
+	case errProfilerNoProfiles:
+		apiErr = ErrAdminProfilerNoProfiles
+	case errSlowDown:
+		apiErr = ErrSlowDown
+	case errInvalidBandwidthLimit:
+		apiErr = ErrAdminInvalidBandwidthLimit
+	case errInvalidBucketQuota:
+		apiErr = ErrAdminInvalidBucketQuota
 	//case errInvalidAccessKeyLength:
 	//	apiErr = ErrAdminInvalidAccessKey
 	//case errInvalidSecretKeyLength:
@@ -675,6 +839,16 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrBucketAlreadyOwnedByYou
 	case ObjectNotFound:
 		apiErr = ErrNoSuchKey
+	case VersionNotFound:
+		apiErr = ErrNoSuchVersion
+	case VersionIsDeleteMarker:
+		apiErr = ErrMethodNotAllowed
+	case ObjectLocked:
+		apiErr = ErrObjectLocked
+	case InvalidObjectState:
+		apiErr = ErrInvalidObjectState
+	case BucketQuotaExceeded:
+		apiErr = ErrBucketQuotaExceeded
 	case ObjectNameInvalid:
 		apiErr = ErrInvalidObjectName
 	case InvalidUploadID:
@@ -36,7 +36,9 @@ func setCommonHeaders(w http.ResponseWriter) {
 	// Set unique request ID for each reply.
 	w.Header().Set(responseRequestIDKey, mustGetRequestID(UTCNow()))
 	w.Header().Set("Server", globalServerUserAgent)
-	//w.Header().Set("X-Amz-Bucket-Region", serverConfig.GetRegion())
+	if region := serverConfig.GetRegion(); region != "" {
+		w.Header().Set("X-Amz-Bucket-Region", region)
+	}
 	w.Header().Set("Accept-Ranges", "bytes")
 }
 
@@ -71,6 +73,19 @@ func setObjectHeaders(w http.ResponseWriter, objInfo ObjectInfo, contentRange *h
 		w.Header().Set(k, v)
 	}
 
+	// Set version ID in versioned buckets.
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
+
+	// Set storage class and restore status of transitioned objects.
+	if objInfo.StorageClass != "" {
+		w.Header().Set(amzStorageClass, objInfo.StorageClass)
+	}
+	if !objInfo.RestoreExpiry.IsZero() {
+		w.Header().Set(amzRestore, `ongoing-request="false", expiry-date="`+objInfo.RestoreExpiry.UTC().Format(http.TimeFormat)+`"`)
+	}
+
 	// for providing ranged content
 	//if contentRange != nil && contentRange.offsetBegin > -1 {
 	//	// Override content-length
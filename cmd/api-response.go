@@ -68,6 +68,34 @@ type ListObjectsResponse struct {
 	EncodingType string `xml:"EncodingType,omitempty"`
 }
 
+// ListVersionsResponse - format for list object versions response.
+type ListVersionsResponse struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult" json:"-"`
+
+	Name            string
+	Prefix          string
+	KeyMarker       string
+	VersionIDMarker string `xml:"VersionIdMarker"`
+
+	// When response is truncated, the key and version ID to use as
+	// key-marker and version-id-marker in the subsequent request.
+	NextKeyMarker       string `xml:"NextKeyMarker,omitempty"`
+	NextVersionIDMarker string `xml:"NextVersionIdMarker,omitempty"`
+
+	MaxKeys   int
+	Delimiter string
+	// A flag that indicates whether or not ListObjectVersions returned
+	// all of the results that satisfied the search criteria.
+	IsTruncated bool
+
+	// Versions and delete markers in listing order.
+	Versions       []ObjectVersion `xml:"Version"`
+	CommonPrefixes []CommonPrefix
+
+	// Encoding type used to encode object keys in the response.
+	EncodingType string `xml:"EncodingType,omitempty"`
+}
+
 // ListObjectsV2Response - format for list objects response.
 type ListObjectsV2Response struct {
 	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult" json:"-"`
@@ -202,6 +230,41 @@ type Object struct {
 	HealObjectInfo *HealObjectInfo `xml:"HealObjectInfo,omitempty"`
 }
 
+// ObjectVersion container for a version or a delete marker of an object
+type ObjectVersion struct {
+	Key          string
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool
+	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
+	ETag         string
+	Size         int64
+
+	// Owner of the object.
+	Owner Owner
+
+	// The class of storage used to store the object.
+	StorageClass string
+
+	isDeleteMarker bool
+}
+
+// MarshalXML - encodes delete markers as DeleteMarker elements without
+// the fields describing object data.
+func (v ObjectVersion) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if !v.isDeleteMarker {
+		type objectVersion ObjectVersion // Has no MarshalXML.
+		return e.EncodeElement(objectVersion(v), start)
+	}
+	start.Name.Local = "DeleteMarker"
+	return e.EncodeElement(struct {
+		Key          string
+		VersionID    string `xml:"VersionId"`
+		IsLatest     bool
+		LastModified string
+		Owner        Owner
+	}{v.Key, v.VersionID, v.IsLatest, v.LastModified, v.Owner}, start)
+}
+
 // CopyObjectResponse container returns ETag and LastModified of the successfully copied object
 type CopyObjectResponse struct {
 	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyObjectResult" json:"-"`
@@ -399,6 +462,52 @@ func generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter
 	return data
 }
 
+// generates a ListVersionsResponse for the given list object versions result.
+func generateListVersionsResponse(bucket, prefix, keyMarker, versionIDMarker, delimiter string, maxKeys int, resp ListObjectVersionsInfo) ListVersionsResponse {
+	var versions []ObjectVersion
+	var prefixes []CommonPrefix
+	var owner = Owner{}
+	var data = ListVersionsResponse{}
+
+	owner.ID = globalMinioDefaultOwnerID
+	owner.DisplayName = globalMinioDefaultOwnerID
+
+	for _, object := range resp.Objects {
+		var version = ObjectVersion{}
+		version.Key = object.Name
+		version.VersionID = object.VersionID
+		version.IsLatest = object.IsLatest
+		version.LastModified = object.ModTime.UTC().Format(timeFormatAMZLong)
+		if object.ETag != "" {
+			version.ETag = "\"" + object.ETag + "\""
+		}
+		version.Size = object.Size
+		version.StorageClass = globalMinioDefaultStorageClass
+		version.Owner = owner
+		version.isDeleteMarker = object.DeleteMarker
+		versions = append(versions, version)
+	}
+	data.Name = bucket
+	data.Versions = versions
+
+	data.Prefix = prefix
+	data.KeyMarker = keyMarker
+	data.VersionIDMarker = versionIDMarker
+	data.Delimiter = delimiter
+	data.MaxKeys = maxKeys
+
+	data.NextKeyMarker = resp.NextKeyMarker
+	data.NextVersionIDMarker = resp.NextVersionIDMarker
+	data.IsTruncated = resp.IsTruncated
+	for _, prefix := range resp.Prefixes {
+		var prefixItem = CommonPrefix{}
+		prefixItem.Prefix = prefix
+		prefixes = append(prefixes, prefixItem)
+	}
+	data.CommonPrefixes = prefixes
+	return data
+}
+
 // generates CopyObjectResponse from etag and lastModified time.
 func generateCopyObjectResponse(etag string, lastModified time.Time) CopyObjectResponse {
 	return CopyObjectResponse{
@@ -39,27 +39,37 @@ func registerAPIRouter(mux *router.Router) {
 	/// Object operations
 
 	// HeadObject
-	bucket.Methods("HEAD").Path("/{object:.+}").HandlerFunc(api.HeadObjectHandler)
+	bucket.Methods("HEAD").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.HeadObjectHandler))
 	//// CopyObjectPart
 	//bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/|%2F).*?").HandlerFunc(api.CopyObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}")
 	//// PutObjectPart
 	//bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}")
 	// ListObjectPxarts
-	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.ListObjectPartsHandler).Queries("uploadId", "{uploadId:.*}")
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.ListObjectPartsHandler)).Queries("uploadId", "{uploadId:.*}")
 	//// CompleteMultipartUpload
 	//bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.CompleteMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}")
 	//// NewMultipartUpload
 	//bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.NewMultipartUploadHandler).Queries("uploads", "")
 	//// AbortMultipartUpload
 	//bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.AbortMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}")
+	// GetObjectRetention
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.GetObjectRetentionHandler)).Queries("retention", "")
+	// GetObjectLegalHold
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.GetObjectLegalHoldHandler)).Queries("legal-hold", "")
 	//// GetObject
-	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectHandler)
-	//// CopyObject
-	//bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/|%2F).*?").HandlerFunc(api.CopyObjectHandler)
+	bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.GetObjectHandler))
+	// PutObjectRetention
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.PutObjectRetentionHandler)).Queries("retention", "")
+	// PutObjectLegalHold
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.PutObjectLegalHoldHandler)).Queries("legal-hold", "")
+	// RestoreObject
+	bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.RestoreObjectHandler)).Queries("restore", "")
+	// CopyObject
+	bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/|%2F).*?").HandlerFunc(traceAPI(api, objectAPIHandlers.CopyObjectHandler))
 	//// PutObject
-	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectHandler)
-	//// DeleteObject
-	//bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectHandler)
+	bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.PutObjectHandler))
+	// DeleteObject
+	bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(traceAPI(api, objectAPIHandlers.DeleteObjectHandler))
 
 	/// Bucket operations
 
@@ -75,20 +85,46 @@ func registerAPIRouter(mux *router.Router) {
 	//bucket.Methods("GET").HandlerFunc(api.ListMultipartUploadsHandler).Queries("uploads", "")
 	//// ListObjectsV2
 	//bucket.Methods("GET").HandlerFunc(api.ListObjectsV2Handler).Queries("list-type", "2")
+	// GetBucketLogging
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.GetBucketLoggingHandler)).Queries("logging", "")
+	// GetBucketVersioning
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.GetBucketVersioningHandler)).Queries("versioning", "")
+	// GetBucketObjectLockConfig
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.GetBucketObjectLockConfigHandler)).Queries("object-lock", "")
+	// GetBucketCors
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.GetBucketCorsHandler)).Queries("cors", "")
+	// GetBucketLifecycle
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.GetBucketLifecycleHandler)).Queries("lifecycle", "")
+	// ListObjectVersions
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.ListObjectVersionsHandler)).Queries("versions", "")
 	//// ListObjectsV1 (Legacy)
-	bucket.Methods("GET").HandlerFunc(api.ListObjectsV1Handler)
+	bucket.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.ListObjectsV1Handler))
 	//// PutBucketPolicy
 	//bucket.Methods("PUT").HandlerFunc(api.PutBucketPolicyHandler).Queries("policy", "")
 	//// PutBucketNotification
 	//bucket.Methods("PUT").HandlerFunc(api.PutBucketNotificationHandler).Queries("notification", "")
+	// PutBucketLogging
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketLoggingHandler)).Queries("logging", "")
+	// PutBucketVersioning
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketVersioningHandler)).Queries("versioning", "")
+	// PutBucketObjectLockConfig
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketObjectLockConfigHandler)).Queries("object-lock", "")
+	// PutBucketCors
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketCorsHandler)).Queries("cors", "")
+	// PutBucketLifecycle
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketLifecycleHandler)).Queries("lifecycle", "")
 	//// PutBucket
-	bucket.Methods("PUT").HandlerFunc(api.PutBucketHandler)
+	bucket.Methods("PUT").HandlerFunc(traceAPI(api, objectAPIHandlers.PutBucketHandler))
 	//// HeadBucket
 	//bucket.Methods("HEAD").HandlerFunc(api.HeadBucketHandler)
 	//// PostPolicy
 	//bucket.Methods("POST").HeadersRegexp("Content-Type", "multipart/form-data*").HandlerFunc(api.PostPolicyBucketHandler)
 	//// DeleteMultipleObjects
 	//bucket.Methods("POST").HandlerFunc(api.DeleteMultipleObjectsHandler)
+	// DeleteBucketCors
+	bucket.Methods("DELETE").HandlerFunc(traceAPI(api, objectAPIHandlers.DeleteBucketCorsHandler)).Queries("cors", "")
+	// DeleteBucketLifecycle
+	bucket.Methods("DELETE").HandlerFunc(traceAPI(api, objectAPIHandlers.DeleteBucketLifecycleHandler)).Queries("lifecycle", "")
 	//// DeleteBucketPolicy
 	//bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketPolicyHandler).Queries("policy", "")
 	//// DeleteBucket
@@ -97,5 +133,5 @@ func registerAPIRouter(mux *router.Router) {
 	/// Root operation
 
 	// ListBuckets
-	apiRouter.Methods("GET").HandlerFunc(api.ListBucketsHandler)
+	apiRouter.Methods("GET").HandlerFunc(traceAPI(api, objectAPIHandlers.ListBucketsHandler))
 }
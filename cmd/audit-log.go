@@ -0,0 +1,502 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	// Audit entry format version.
+	auditEntryVersion = "1"
+
+	// Default number of audit entries buffered by the webhook sink.
+	auditWebhookDefaultQueueSize = 10000
+
+	// Maximum number of audit entries posted in a single webhook request.
+	auditWebhookBatchSize = 100
+
+	// Interval at which partially filled batches are posted.
+	auditWebhookFlushInterval = time.Second
+
+	// Maximum time an API call waits on a full webhook queue before
+	// its audit entry is dropped.
+	auditWebhookSendTimeout = 5 * time.Second
+
+	// Number of delivery attempts for each batch.
+	auditWebhookMaxRetry = 5
+
+	// Timeout for each webhook request.
+	auditWebhookRequestTimeout = 10 * time.Second
+)
+
+// Request headers recorded by default in each audit entry.
+var defaultAuditRequestHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Range",
+	"User-Agent",
+	"X-Amz-Copy-Source",
+	"X-Forwarded-For",
+}
+
+// Response headers recorded by default in each audit entry.
+var defaultAuditResponseHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+}
+
+var (
+	errAuditQueueFull     = errors.New("Audit queue is full, dropping audit entry")
+	errAuditSinkClosed    = errors.New("Audit sink is closed")
+	errAuditWebhookScheme = errors.New("Audit webhook endpoint must be a http or https URL")
+)
+
+// auditIdentity - identity which performed the API call.
+type auditIdentity struct {
+	AccessKey string `json:"accessKey,omitempty"`
+	AuthType  string `json:"authType"`
+}
+
+// auditEntry - a single audit record, one for every API call.
+type auditEntry struct {
+	Version     string            `json:"version"`
+	Time        time.Time         `json:"time"`
+	RequestID   string            `json:"requestID"`
+	API         string            `json:"api"`
+	Bucket      string            `json:"bucket,omitempty"`
+	Object      string            `json:"object,omitempty"`
+	Identity    auditIdentity     `json:"identity"`
+	RemoteAddr  string            `json:"remoteAddr"`
+	StatusCode  int               `json:"statusCode"`
+	InputBytes  int64             `json:"inputBytes"`
+	OutputBytes int64             `json:"outputBytes"`
+	Duration    time.Duration     `json:"durationNs"`
+	ReqHeader   map[string]string `json:"requestHeader,omitempty"`
+	RespHeader  map[string]string `json:"responseHeader,omitempty"`
+}
+
+// auditSink - destination of audit entries.
+type auditSink interface {
+	// Send delivers an audit entry, an error is returned
+	// if the entry could not be accepted.
+	Send(entry auditEntry) error
+	// Close flushes all pending entries and releases resources.
+	Close() error
+}
+
+// auditLogger - fans out audit entries to all configured sinks.
+type auditLogger struct {
+	sinks       []auditSink
+	reqHeaders  []string
+	respHeaders []string
+}
+
+// newAuditLogger - returns a new audit logger, with default
+// header selection if none are provided.
+func newAuditLogger(sinks []auditSink, reqHeaders, respHeaders []string) *auditLogger {
+	if len(reqHeaders) == 0 {
+		reqHeaders = defaultAuditRequestHeaders
+	}
+	if len(respHeaders) == 0 {
+		respHeaders = defaultAuditResponseHeaders
+	}
+	return &auditLogger{
+		sinks:       sinks,
+		reqHeaders:  reqHeaders,
+		respHeaders: respHeaders,
+	}
+}
+
+// selectHeaders - returns the values of only the given header names.
+func selectHeaders(h http.Header, names []string) map[string]string {
+	selected := make(map[string]string)
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			selected[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	return selected
+}
+
+// Log - sends the audit entry to all the sinks.
+func (l *auditLogger) Log(entry auditEntry) {
+	for _, sink := range l.sinks {
+		if err := sink.Send(entry); err != nil {
+			println(err, "Unable to send audit entry", entry.RequestID)
+		}
+	}
+}
+
+// Close - closes all the sinks.
+func (l *auditLogger) Close() (err error) {
+	for _, sink := range l.sinks {
+		if serr := sink.Close(); serr != nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// fileAuditSink - writes audit entries as JSON lines to a local file.
+type fileAuditSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+	closed bool
+}
+
+// newFileAuditSink - opens the audit file for appending, creating
+// the file and its parent directories if they don't exist.
+func newFileAuditSink(path string) (*fileAuditSink, error) {
+	if err := mkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(preparePath(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuditSink{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+// Send - appends the audit entry as a single JSON line.
+func (s *fileAuditSink) Send(entry auditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errAuditSinkClosed
+	}
+	return s.enc.Encode(entry)
+}
+
+// Close - syncs and closes the audit file.
+func (s *fileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// webhookAuditSink - posts batches of audit entries as JSON lines to a
+// HTTP endpoint. Entries are buffered in a bounded queue, when the
+// queue is full API calls block for a bounded time to apply back
+// pressure before the entry is dropped.
+type webhookAuditSink struct {
+	endpoint string
+	client   *http.Client
+	queueCh  chan auditEntry
+	doneCh   chan struct{}
+
+	mu     sync.RWMutex // guards closing queueCh.
+	closed bool
+
+	// Total number of entries which could not be delivered.
+	dropped atomic.Uint64
+}
+
+// newWebhookAuditSink - validates the endpoint and starts the
+// background delivery routine.
+func newWebhookAuditSink(endpoint string, queueSize int) (*webhookAuditSink, error) {
+	u, err := checkURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != httpScheme && u.Scheme != httpsScheme {
+		return nil, errAuditWebhookScheme
+	}
+	if queueSize <= 0 {
+		queueSize = auditWebhookDefaultQueueSize
+	}
+	s := &webhookAuditSink{
+		endpoint: endpoint,
+		client: &http.Client{
+			Transport: newCustomHTTPTransport(),
+			Timeout:   auditWebhookRequestTimeout,
+		},
+		queueCh: make(chan auditEntry, queueSize),
+		doneCh:  make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Send - queues the audit entry for delivery.
+func (s *webhookAuditSink) Send(entry auditEntry) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errAuditSinkClosed
+	}
+
+	select {
+	case s.queueCh <- entry:
+		return nil
+	default:
+	}
+
+	// Queue is full, wait for the delivery routine to catch up.
+	timer := time.NewTimer(auditWebhookSendTimeout)
+	defer timer.Stop()
+	select {
+	case s.queueCh <- entry:
+		return nil
+	case <-timer.C:
+		s.dropped.Inc()
+		return errAuditQueueFull
+	}
+}
+
+// Close - delivers all the queued entries and stops the sink.
+func (s *webhookAuditSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queueCh)
+	s.mu.Unlock()
+
+	<-s.doneCh
+	if dropped := s.dropped.Load(); dropped > 0 {
+		return fmt.Errorf("%d audit entries could not be delivered to %s", dropped, s.endpoint)
+	}
+	return nil
+}
+
+// run - batches queued entries and posts them to the endpoint.
+func (s *webhookAuditSink) run() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(auditWebhookFlushInterval)
+	defer ticker.Stop()
+
+	var batch []auditEntry
+	for {
+		select {
+		case entry, ok := <-s.queueCh:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= auditWebhookBatchSize {
+				s.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+// flush - posts a batch, retrying with exponential backoff. The
+// queue is not drained while a batch is being retried which is
+// what eventually applies back pressure on API calls.
+func (s *webhookAuditSink) flush(batch []auditEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range batch {
+		if err := enc.Encode(entry); err != nil {
+			println(err, "Unable to encode audit entry", entry.RequestID)
+		}
+	}
+
+	var err error
+	backoff := time.Second
+	for i := 0; i < auditWebhookMaxRetry; i++ {
+		if err = s.post(buf.Bytes()); err == nil {
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	s.dropped.Add(uint64(len(batch)))
+	println(err, "Unable to deliver audit entries to", s.endpoint)
+}
+
+// post - sends a single request to the endpoint.
+func (s *webhookAuditSink) post(body []byte) error {
+	req, err := http.NewRequest(httpPOST, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("User-Agent", globalServerUserAgent)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", s.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Environment variables to configure audit logging.
+const (
+	auditFileEnv             = "MINIO_AUDIT_FILE"
+	auditWebhookEndpointEnv  = "MINIO_AUDIT_WEBHOOK_ENDPOINT"
+	auditWebhookQueueSizeEnv = "MINIO_AUDIT_WEBHOOK_QUEUE_SIZE"
+	auditRequestHeadersEnv   = "MINIO_AUDIT_REQUEST_HEADERS"
+	auditResponseHeadersEnv  = "MINIO_AUDIT_RESPONSE_HEADERS"
+)
+
+// splitHeaderList - splits a comma separated list of header names.
+func splitHeaderList(list string) (headers []string) {
+	for _, header := range strings.Split(list, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// auditConfig - audit logging settings, audit logging is enabled
+// if a file or a webhook endpoint is set.
+type auditConfig struct {
+	File             string   `json:"file"`
+	WebhookEndpoint  string   `json:"webhookEndpoint"`
+	WebhookQueueSize int      `json:"webhookQueueSize"`
+	RequestHeaders   []string `json:"requestHeaders"`
+	ResponseHeaders  []string `json:"responseHeaders"`
+}
+
+// Validate - checks the webhook endpoint and queue size.
+func (c auditConfig) Validate() error {
+	if c.WebhookEndpoint != "" {
+		u, err := url.Parse(c.WebhookEndpoint)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != httpScheme && u.Scheme != httpsScheme {
+			return errAuditWebhookScheme
+		}
+	}
+	if c.WebhookQueueSize < 0 {
+		return fmt.Errorf("Invalid audit webhook queue size %d", c.WebhookQueueSize)
+	}
+	return nil
+}
+
+// auditConfigFromEnv - returns the audit settings set through
+// environment variables, ok is false if no audit sink is set.
+func auditConfigFromEnv() (cfg auditConfig, ok bool, err error) {
+	cfg.File = os.Getenv(auditFileEnv)
+	cfg.WebhookEndpoint = os.Getenv(auditWebhookEndpointEnv)
+	if cfg.File == "" && cfg.WebhookEndpoint == "" {
+		return auditConfig{}, false, nil
+	}
+	if sizeStr := os.Getenv(auditWebhookQueueSizeEnv); sizeStr != "" {
+		if cfg.WebhookQueueSize, err = strconv.Atoi(sizeStr); err != nil {
+			return auditConfig{}, true, fmt.Errorf("Invalid %s value `%s`: %s", auditWebhookQueueSizeEnv, sizeStr, err)
+		}
+	}
+	cfg.RequestHeaders = splitHeaderList(os.Getenv(auditRequestHeadersEnv))
+	cfg.ResponseHeaders = splitHeaderList(os.Getenv(auditResponseHeadersEnv))
+	return cfg, true, cfg.Validate()
+}
+
+// newAuditLoggerFromConfig - initializes audit logging, returns nil
+// if no audit sink is configured.
+func newAuditLoggerFromConfig(cfg auditConfig) (*auditLogger, error) {
+	var sinks []auditSink
+	if cfg.File != "" {
+		sink, err := newFileAuditSink(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.WebhookEndpoint != "" {
+		sink, err := newWebhookAuditSink(cfg.WebhookEndpoint, cfg.WebhookQueueSize)
+		if err != nil {
+			for _, sink := range sinks {
+				sink.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return newAuditLogger(sinks, cfg.RequestHeaders, cfg.ResponseHeaders), nil
+}
+
+var (
+	// Audit logger, nil when audit logging is disabled.
+	globalAuditLogger   *auditLogger
+	globalAuditLoggerMu sync.RWMutex
+)
+
+// getAuditLogger - returns the active audit logger, nil when audit
+// logging is disabled.
+func getAuditLogger() *auditLogger {
+	globalAuditLoggerMu.RLock()
+	defer globalAuditLoggerMu.RUnlock()
+	return globalAuditLogger
+}
+
+// setAuditLogger - replaces the active audit logger, the previous
+// one is closed once its pending entries are flushed.
+func setAuditLogger(l *auditLogger) {
+	globalAuditLoggerMu.Lock()
+	old := globalAuditLogger
+	globalAuditLogger = l
+	globalAuditLoggerMu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			println(err, "Unable to close the previous audit logger.")
+		}
+	}
+}
@@ -17,8 +17,9 @@
 package cmd
 
 import (
-	//"bytes"
-	//"io/ioutil"
+	"bytes"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
 )
@@ -104,6 +105,53 @@ func getRequestAuthType(r *http.Request) authType {
 	}
 	return authTypeUnknown
 }
+
+// Human readable names of all the auth types.
+var authTypeNames = map[authType]string{
+	authTypeUnknown:         "unknown",
+	authTypeAnonymous:       "anonymous",
+	authTypePresigned:       "presigned",
+	authTypePresignedV2:     "presignedV2",
+	authTypePostPolicy:      "postPolicy",
+	authTypeStreamingSigned: "streamingSigned",
+	authTypeSigned:          "signed",
+	authTypeSignedV2:        "signedV2",
+	authTypeJWT:             "jwt",
+}
+
+// String - returns the name of the auth type.
+func (a authType) String() string {
+	return authTypeNames[a]
+}
+
+// getRequestAccessKey - returns the access key the request claims to
+// be signed with, this is extracted purely from the request and is not
+// validated. Returns empty string for anonymous and JWT requests.
+func getRequestAccessKey(r *http.Request) string {
+	switch getRequestAuthType(r) {
+	case authTypeSigned, authTypeStreamingSigned:
+		// Authorization: AWS4-HMAC-SHA256 Credential=<access-key>/<scope>, ...
+		authFields := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), signV4Algorithm))
+		for _, field := range strings.Split(authFields, ",") {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "Credential=") {
+				credential := strings.TrimPrefix(field, "Credential=")
+				return strings.SplitN(credential, slashSeparator, 2)[0]
+			}
+		}
+	case authTypePresigned:
+		// X-Amz-Credential=<access-key>/<scope>
+		credential := r.URL.Query().Get("X-Amz-Credential")
+		return strings.SplitN(credential, slashSeparator, 2)[0]
+	case authTypeSignedV2:
+		// Authorization: AWS <access-key>:<signature>
+		authFields := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), signV2Algorithm))
+		return strings.SplitN(authFields, ":", 2)[0]
+	case authTypePresignedV2:
+		return r.URL.Query().Get("AWSAccessKeyId")
+	}
+	return ""
+}
 //
 //func checkRequestAuthType(r *http.Request, bucket, policyAction, region string) APIErrorCode {
 //	reqAuthType := getRequestAuthType(r)
@@ -194,6 +242,43 @@ func getRequestAuthType(r *http.Request) authType {
 //	return ErrNone
 //}
 
+// Maximum size of an admin request body.
+const maxAdminRequestBodySize = 1 * 1024 * 1024
+
+// checkAdminRequestAuthType - validates that the request is signed
+// with AWS Signature Version '4' using the server credentials, the
+// admin APIs accept no other authentication type. The request body
+// is verified against X-Amz-Content-Sha256 and populated back.
+func checkAdminRequestAuthType(r *http.Request) APIErrorCode {
+	if getRequestAuthType(r) != authTypeSigned {
+		return ErrAccessDenied
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxAdminRequestBodySize+1))
+	if err != nil {
+		println(err, "Unable to read request body for signature verification")
+		return ErrInternalError
+	}
+	if len(payload) > maxAdminRequestBodySize {
+		return ErrEntityTooLarge
+	}
+
+	// Populate back the payload.
+	r.Body = ioutil.NopCloser(bytes.NewReader(payload))
+
+	// Verify that X-Amz-Content-Sha256 Header == sha256(payload)
+	hashedPayload := getSHA256Hash(payload)
+	if sum := r.Header.Get("X-Amz-Content-Sha256"); sum != "" && sum != hashedPayload {
+		return ErrContentSHA256Mismatch
+	}
+
+	if s3Error := doesSignatureMatch(hashedPayload, r, serverConfig.GetCredential()); s3Error != ErrNone {
+		println(errSignatureMismatch, dumpRequest(r))
+		return s3Error
+	}
+	return ErrNone
+}
+
 // authHandler - handles all the incoming authorization headers and validates them if possible.
 type authHandler struct {
 	handler http.Handler
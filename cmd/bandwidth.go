@@ -0,0 +1,311 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+const (
+	// Bandwidth limits are kept in the config directory.
+	bandwidthConfigFile = "bandwidth.json"
+
+	// Largest amount of data transferred at once by a throttled
+	// transfer, keeps the transfer rate smooth.
+	bandwidthChunkSize = 32 * humanize.KiByte
+)
+
+var errInvalidBandwidthLimit = errors.New("Bandwidth limits apply to either a bucket or an access key and must not be negative")
+
+// bandwidthLimit - download and upload rates in bytes per second,
+// zero means no limit.
+type bandwidthLimit struct {
+	Download int64 `json:"download"`
+	Upload   int64 `json:"upload"`
+}
+
+// BandwidthLimits - bandwidth limits by bucket and by access key,
+// a transfer is limited by both the limit of its bucket and the one
+// of its access key. The limits are shared by all the transfers of a
+// bucket or access key.
+type BandwidthLimits struct {
+	Buckets    map[string]bandwidthLimit `json:"buckets"`
+	AccessKeys map[string]bandwidthLimit `json:"accessKeys"`
+}
+
+// tokenBucket - limits the rate of a transfer, tokens are bytes.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+// setRate - changes the rate, transfers in progress adapt right away.
+func (b *tokenBucket) setRate(rate int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rate = rate
+	if b.tokens > float64(rate) {
+		b.tokens = float64(rate)
+	}
+}
+
+// reserve - takes n tokens, returns the time to wait until they are
+// refilled if the bucket runs short. At most one second worth of
+// tokens is accumulated.
+func (b *tokenBucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+	if b.tokens > float64(b.rate) {
+		b.tokens = float64(b.rate)
+	}
+	b.last = now
+	// Waiters take tokens in advance, later ones wait longer.
+	b.tokens -= float64(n)
+	return time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+}
+
+// waitTokens - takes n tokens from all the token buckets, waits as
+// long as the slowest of them requires.
+func waitTokens(tbs []*tokenBucket, n int) {
+	var delay time.Duration
+	for _, tb := range tbs {
+		if d := tb.reserve(n); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// bandwidthLimiter - the token buckets of all the limited buckets and
+// access keys.
+type bandwidthLimiter struct {
+	mu     sync.RWMutex
+	limits BandwidthLimits
+
+	// Token buckets for downloads and uploads by bucket and by
+	// access key, index 0 downloads and 1 uploads.
+	buckets    map[string]*[2]tokenBucket
+	accessKeys map[string]*[2]tokenBucket
+}
+
+var globalBandwidthLimiter = newBandwidthLimiter()
+
+func newBandwidthLimiter() *bandwidthLimiter {
+	return &bandwidthLimiter{
+		limits: BandwidthLimits{
+			Buckets:    make(map[string]bandwidthLimit),
+			AccessKeys: make(map[string]bandwidthLimit),
+		},
+		buckets:    make(map[string]*[2]tokenBucket),
+		accessKeys: make(map[string]*[2]tokenBucket),
+	}
+}
+
+// Updates the token buckets of name in tbs to limit, a token bucket
+// is removed along with its limit.
+func setTokenBuckets(tbs map[string]*[2]tokenBucket, name string, limit bandwidthLimit) {
+	if limit.Download == 0 && limit.Upload == 0 {
+		if tb, ok := tbs[name]; ok {
+			// Release transfers in progress.
+			tb[0].setRate(0)
+			tb[1].setRate(0)
+			delete(tbs, name)
+		}
+		return
+	}
+	tb, ok := tbs[name]
+	if !ok {
+		tb = &[2]tokenBucket{}
+		tbs[name] = tb
+	}
+	tb[0].setRate(limit.Download)
+	tb[1].setRate(limit.Upload)
+}
+
+// GetLimits - returns a copy of all the limits.
+func (l *bandwidthLimiter) GetLimits() BandwidthLimits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	limits := BandwidthLimits{
+		Buckets:    make(map[string]bandwidthLimit),
+		AccessKeys: make(map[string]bandwidthLimit),
+	}
+	for bucket, limit := range l.limits.Buckets {
+		limits.Buckets[bucket] = limit
+	}
+	for accessKey, limit := range l.limits.AccessKeys {
+		limits.AccessKeys[accessKey] = limit
+	}
+	return limits
+}
+
+// SetLimit - sets the limit of a bucket or an access key, a limit of
+// zero for both directions removes it.
+func (l *bandwidthLimiter) SetLimit(bucket, accessKey string, limit bandwidthLimit) error {
+	if (bucket == "") == (accessKey == "") || limit.Download < 0 || limit.Upload < 0 {
+		return errInvalidBandwidthLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits, tbs, name := l.limits.Buckets, l.buckets, bucket
+	if accessKey != "" {
+		limits, tbs, name = l.limits.AccessKeys, l.accessKeys, accessKey
+	}
+	if limit.Download == 0 && limit.Upload == 0 {
+		delete(limits, name)
+	} else {
+		limits[name] = limit
+	}
+	setTokenBuckets(tbs, name, limit)
+	return nil
+}
+
+// Returns the token buckets limiting a transfer of the given
+// direction for bucket and accessKey.
+func (l *bandwidthLimiter) getTokenBuckets(bucket, accessKey string, direction int) (tbs []*tokenBucket) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if tb, ok := l.buckets[bucket]; ok {
+		tbs = append(tbs, &tb[direction])
+	}
+	if tb, ok := l.accessKeys[accessKey]; ok {
+		tbs = append(tbs, &tb[direction])
+	}
+	return tbs
+}
+
+// throttledWriter - writes at the rate allowed by the limits of its
+// bucket and access key, the limits are looked up for each chunk so
+// the ones set during the transfer apply right away.
+type throttledWriter struct {
+	io.Writer
+	l         *bandwidthLimiter
+	bucket    string
+	accessKey string
+}
+
+func (t *throttledWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		tbs := t.l.getTokenBuckets(t.bucket, t.accessKey, 0)
+		// Unlimited transfers are not split into chunks.
+		if len(tbs) > 0 && len(chunk) > bandwidthChunkSize {
+			chunk = chunk[:bandwidthChunkSize]
+		}
+		waitTokens(tbs, len(chunk))
+		var m int
+		m, err = t.Writer.Write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// throttledReader - reads at the rate allowed by the limits of its
+// bucket and access key, the limits are looked up for each chunk so
+// the ones set during the transfer apply right away.
+type throttledReader struct {
+	io.ReadCloser
+	l         *bandwidthLimiter
+	bucket    string
+	accessKey string
+}
+
+func (t *throttledReader) Read(p []byte) (n int, err error) {
+	tbs := t.l.getTokenBuckets(t.bucket, t.accessKey, 1)
+	if len(tbs) > 0 && len(p) > bandwidthChunkSize {
+		p = p[:bandwidthChunkSize]
+	}
+	n, err = t.ReadCloser.Read(p)
+	waitTokens(tbs, n)
+	return n, err
+}
+
+// DownloadWriter - returns w limited to the download rates of bucket
+// and accessKey.
+func (l *bandwidthLimiter) DownloadWriter(bucket, accessKey string, w io.Writer) io.Writer {
+	return &throttledWriter{Writer: w, l: l, bucket: bucket, accessKey: accessKey}
+}
+
+// UploadReader - returns r limited to the upload rates of bucket and
+// accessKey.
+func (l *bandwidthLimiter) UploadReader(bucket, accessKey string, r io.ReadCloser) io.ReadCloser {
+	return &throttledReader{ReadCloser: r, l: l, bucket: bucket, accessKey: accessKey}
+}
+
+func getBandwidthConfigFile() string {
+	return filepath.Join(getConfigDir(), bandwidthConfigFile)
+}
+
+// Save - writes all the limits to the config directory.
+func (l *bandwidthLimiter) Save() error {
+	return saveConfigFile(getBandwidthConfigFile(), l.GetLimits())
+}
+
+// initBandwidthLimits - loads the bandwidth limits saved in the
+// config directory.
+func initBandwidthLimits() error {
+	data, err := ioutil.ReadFile(getBandwidthConfigFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var limits BandwidthLimits
+	if err = json.Unmarshal(data, &limits); err != nil {
+		return err
+	}
+	for bucket, limit := range limits.Buckets {
+		if err = globalBandwidthLimiter.SetLimit(bucket, "", limit); err != nil {
+			return err
+		}
+	}
+	for accessKey, limit := range limits.AccessKeys {
+		if err = globalBandwidthLimiter.SetLimit("", accessKey, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
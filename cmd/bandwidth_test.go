@@ -0,0 +1,190 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	testCases := []struct {
+		rate          int64
+		tokens        float64
+		elapsed       time.Duration
+		n             int
+		expectedDelay time.Duration
+	}{
+		// No limit.
+		{0, 0, 0, 1000, 0},
+		// Enough tokens refilled.
+		{1000, 0, time.Second, 500, 0},
+		{1000, 500, 0, 500, 0},
+		// Tokens short.
+		{1000, 0, 0, 500, 500 * time.Millisecond},
+		{1000, 250, 0, 500, 250 * time.Millisecond},
+		// At most one second worth of tokens is accumulated.
+		{1000, 0, time.Minute, 1500, 500 * time.Millisecond},
+		// Tokens taken in advance by earlier waiters.
+		{1000, -1000, 0, 1000, 2 * time.Second},
+	}
+	for i, testCase := range testCases {
+		tb := &tokenBucket{rate: testCase.rate, tokens: testCase.tokens, last: time.Now().Add(-testCase.elapsed)}
+		delay := tb.reserve(testCase.n)
+		if delay < 0 {
+			delay = 0
+		}
+		// Allow for the time passed since the bucket was set up.
+		if diff := delay - testCase.expectedDelay; diff > 10*time.Millisecond || diff < -10*time.Millisecond {
+			t.Errorf("Test %d: expected delay %s, got %s", i+1, testCase.expectedDelay, delay)
+		}
+	}
+}
+
+func TestTokenBucketSetRate(t *testing.T) {
+	tb := &tokenBucket{rate: 1000, tokens: 1000, last: time.Now()}
+	tb.setRate(100)
+	if tb.tokens != 100 {
+		t.Fatalf("Expected the tokens to be capped to the new rate, got %f", tb.tokens)
+	}
+	// Removing the limit releases transfers right away.
+	tb.setRate(0)
+	if delay := tb.reserve(humanize.MiByte); delay != 0 {
+		t.Fatalf("Expected no delay without a limit, got %s", delay)
+	}
+}
+
+// chunkWriter - records the size of each write.
+type chunkWriter struct {
+	bytes.Buffer
+	chunks []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, len(p))
+	return w.Buffer.Write(p)
+}
+
+// Tests that throttled transfers move data in chunks at the rate of
+// their slowest limit, after a burst of one second worth of data.
+func TestThrottledTransfers(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1536*humanize.KiByte)
+	l := newBandwidthLimiter()
+	l.SetLimit("bucket", "", bandwidthLimit{Download: 4 * humanize.MiByte, Upload: 4 * humanize.MiByte})
+	l.SetLimit("", "access", bandwidthLimit{Download: humanize.MiByte, Upload: humanize.MiByte})
+	checkDuration := func(name string, start time.Time) {
+		if d := time.Since(start); d < 400*time.Millisecond || d > 2*time.Second {
+			t.Errorf("%s: expected 1.5MiB at 1MiB/s to take about 500ms, took %s", name, d)
+		}
+	}
+
+	w := &chunkWriter{}
+	start := time.Now()
+	n, err := l.DownloadWriter("bucket", "access", w).Write(data)
+	if err != nil || n != len(data) {
+		t.Fatalf("Expected %d bytes written, got %d, %v", len(data), n, err)
+	}
+	checkDuration("Write", start)
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Fatal("Expected the data written to be unchanged")
+	}
+	for _, chunk := range w.chunks {
+		if chunk > bandwidthChunkSize {
+			t.Fatalf("Expected chunks of at most %d bytes, got %d", bandwidthChunkSize, chunk)
+		}
+	}
+
+	start = time.Now()
+	r := l.UploadReader("bucket", "access", ioutil.NopCloser(bytes.NewReader(data)))
+	read, err := ioutil.ReadAll(r)
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("Expected the data read to be unchanged, got %d bytes, %v", len(read), err)
+	}
+	checkDuration("Read", start)
+
+	// Unlimited transfers are written as is.
+	w = &chunkWriter{}
+	if _, err = l.DownloadWriter("other", "", w).Write(data); err != nil || len(w.chunks) != 1 {
+		t.Fatalf("Expected the data to be written at once, got %d chunks, %v", len(w.chunks), err)
+	}
+}
+
+// Tests that limits set while a transfer is in progress apply to it.
+func TestThrottledTransferLimitChanged(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1536*humanize.KiByte)
+	l := newBandwidthLimiter()
+	r := l.UploadReader("bucket", "", ioutil.NopCloser(bytes.NewReader(bytes.Repeat(data, 3))))
+	readData := func(name string, minDuration, maxDuration time.Duration) {
+		start := time.Now()
+		if _, err := io.ReadFull(r, make([]byte, len(data))); err != nil {
+			t.Fatalf("%s: unable to read: %v", name, err)
+		}
+		if d := time.Since(start); d < minDuration || d > maxDuration {
+			t.Errorf("%s: expected 1.5MiB to take between %s and %s, took %s", name, minDuration, maxDuration, d)
+		}
+	}
+
+	readData("No limit", 0, 100*time.Millisecond)
+	l.SetLimit("bucket", "", bandwidthLimit{Upload: humanize.MiByte})
+	readData("Limit set", 400*time.Millisecond, 2*time.Second)
+	// A limit removed and set again applies too.
+	l.SetLimit("bucket", "", bandwidthLimit{})
+	l.SetLimit("bucket", "", bandwidthLimit{Upload: humanize.MiByte})
+	readData("Limit set again", 400*time.Millisecond, 2*time.Second)
+}
+
+func TestBandwidthLimiterSetLimit(t *testing.T) {
+	testCases := []struct {
+		bucket      string
+		accessKey   string
+		limit       bandwidthLimit
+		expectedErr error
+	}{
+		{"bucket", "", bandwidthLimit{Download: 100}, nil},
+		{"", "access", bandwidthLimit{Upload: 100}, nil},
+		{"", "", bandwidthLimit{Download: 100}, errInvalidBandwidthLimit},
+		{"bucket", "access", bandwidthLimit{Download: 100}, errInvalidBandwidthLimit},
+		{"bucket", "", bandwidthLimit{Download: -1}, errInvalidBandwidthLimit},
+	}
+	for i, testCase := range testCases {
+		l := newBandwidthLimiter()
+		if err := l.SetLimit(testCase.bucket, testCase.accessKey, testCase.limit); err != testCase.expectedErr {
+			t.Errorf("Test %d: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+	}
+
+	l := newBandwidthLimiter()
+	l.SetLimit("bucket", "", bandwidthLimit{Download: 100})
+	l.SetLimit("", "access", bandwidthLimit{Download: 200, Upload: 300})
+	if tbs := l.getTokenBuckets("bucket", "access", 0); len(tbs) != 2 || tbs[0].rate != 100 || tbs[1].rate != 200 {
+		t.Fatalf("Expected the download buckets of the bucket and the access key, got %d", len(tbs))
+	}
+	if tbs := l.getTokenBuckets("other", "", 0); len(tbs) != 0 {
+		t.Fatalf("Expected no token buckets without a limit, got %d", len(tbs))
+	}
+
+	// Removing a limit forgets it.
+	l.SetLimit("bucket", "", bandwidthLimit{})
+	if limits := l.GetLimits(); len(limits.Buckets) != 0 || len(limits.AccessKeys) != 1 {
+		t.Fatalf("Expected only the limit of the access key, got %+v", limits)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path"
+)
+
+// errConfigNotFound - bucket has no such configuration.
+var errConfigNotFound = errors.New("Bucket configuration not found")
+
+// Returns the path of a bucket configuration file inside the
+// meta bucket i.e `.minio.sys/buckets/<bucket>/<configFile>`.
+func getBucketConfigPath(bucket, configFile string) string {
+	return path.Join(bucketConfigPrefix, bucket, configFile)
+}
+
+// readBucketConfig - reads a bucket configuration file, returns
+// errConfigNotFound if the bucket has no such configuration.
+func readBucketConfig(objAPI ObjectLayer, bucket, configFile string) ([]byte, error) {
+	configPath := getBucketConfigPath(bucket, configFile)
+	var buffer bytes.Buffer
+	if err := objAPI.GetObject(minioMetaBucket, configPath, 0, -1, &buffer); err != nil {
+		if _, ok := errorCause(err).(ObjectNotFound); ok {
+			return nil, errConfigNotFound
+		}
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// saveBucketConfig - saves a bucket configuration file.
+func saveBucketConfig(objAPI ObjectLayer, bucket, configFile string, data []byte) error {
+	configPath := getBucketConfigPath(bucket, configFile)
+	_, err := objAPI.PutObject(minioMetaBucket, configPath, int64(len(data)), bytes.NewReader(data), nil, "")
+	return err
+}
+
+// removeBucketConfig - removes a bucket configuration file, removing
+// a configuration which does not exist is not an error.
+func removeBucketConfig(objAPI ObjectLayer, bucket, configFile string) error {
+	configPath := getBucketConfigPath(bucket, configFile)
+	if err := objAPI.DeleteObject(minioMetaBucket, configPath); err != nil {
+		if _, ok := errorCause(err).(ObjectNotFound); ok {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetBucketCorsHandler - GET Bucket cors
+// -----------------
+// Returns the CORS configuration of a bucket.
+func (api objectAPIHandlers) GetBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	config, err := readBucketCorsConfig(objectAPI, bucket)
+	if err != nil {
+		if err == errConfigNotFound {
+			writeErrorResponse(w, ErrNoSuchCORSConfiguration, r.URL)
+			return
+		}
+		println(err, "Unable to read CORS configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	config.XMLNS = s3XMLNamespace
+
+	writeSuccessResponseXML(w, encodeResponse(config))
+}
+
+// PutBucketCorsHandler - PUT Bucket cors
+// -----------------
+// Replaces the CORS configuration of a bucket, the rules are evaluated
+// for all cross origin requests to the bucket including preflight
+// requests.
+func (api objectAPIHandlers) PutBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// PutBucketCors always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+	if r.ContentLength > maxCorsConfigLen {
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	config := corsConfiguration{}
+	if err := xmlDecoder(r.Body, &config, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse CORS configuration.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+	if err := config.Validate(); err != nil {
+		println(err, "Invalid CORS configuration of bucket", bucket)
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	config.XMLNS = ""
+	data, err := xml.Marshal(config)
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketCorsConfig, data); err != nil {
+		println(err, "Unable to save CORS configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketCors.Set(bucket, &config)
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// DeleteBucketCorsHandler - DELETE Bucket cors
+// -----------------
+// Removes the CORS configuration of a bucket.
+func (api objectAPIHandlers) DeleteBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	if err := removeBucketConfig(objectAPI, bucket, bucketCorsConfig); err != nil {
+		println(err, "Unable to remove CORS configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketCors.Set(bucket, nil)
+
+	writeSuccessNoContent(w)
+}
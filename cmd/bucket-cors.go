@@ -0,0 +1,263 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Bucket CORS config name.
+	bucketCorsConfig = "cors.xml"
+
+	// Limits of a CORS configuration as per S3 spec.
+	maxCorsRules     = 100
+	maxCorsConfigLen = 64 * 1024
+)
+
+// CORS configuration errors.
+var (
+	errCorsNoRules       = errors.New("CORS configuration must have at least one rule")
+	errCorsTooManyRules  = errors.New("CORS configuration allows a maximum of 100 rules")
+	errCorsNoOrigin      = errors.New("CORS rule must specify at least one allowed origin")
+	errCorsInvalidOrigin = errors.New("CORS rule allowed origin can contain at most one wildcard")
+	errCorsNoMethod      = errors.New("CORS rule must specify at least one allowed method")
+	errCorsInvalidMethod = errors.New("CORS rule allowed method must be one of GET, PUT, HEAD, POST or DELETE")
+	errCorsInvalidHeader = errors.New("CORS rule allowed header can contain at most one wildcard")
+	errCorsInvalidMaxAge = errors.New("CORS rule max age must not be negative")
+)
+
+// Methods a CORS rule can allow.
+var corsAllowedMethods = map[string]bool{
+	httpGET:    true,
+	httpPUT:    true,
+	httpHEAD:   true,
+	httpPOST:   true,
+	httpDELETE: true,
+}
+
+// corsRule - origins allowed to send requests with some methods and
+// headers to a bucket, and the response headers they may read.
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// corsConfiguration - bucket CORS configuration.
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	XMLNS   string     `xml:"xmlns,attr,omitempty"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+// Validate - validates a CORS configuration as per S3 spec.
+func (config corsConfiguration) Validate() error {
+	if len(config.Rules) == 0 {
+		return errCorsNoRules
+	}
+	if len(config.Rules) > maxCorsRules {
+		return errCorsTooManyRules
+	}
+	for _, rule := range config.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate - validates a CORS rule as per S3 spec.
+func (rule corsRule) Validate() error {
+	if len(rule.AllowedOrigins) == 0 {
+		return errCorsNoOrigin
+	}
+	for _, origin := range rule.AllowedOrigins {
+		if origin == "" || strings.Count(origin, "*") > 1 {
+			return errCorsInvalidOrigin
+		}
+	}
+	if len(rule.AllowedMethods) == 0 {
+		return errCorsNoMethod
+	}
+	for _, method := range rule.AllowedMethods {
+		if !corsAllowedMethods[method] {
+			return errCorsInvalidMethod
+		}
+	}
+	for _, header := range rule.AllowedHeaders {
+		if strings.Count(header, "*") > 1 {
+			return errCorsInvalidHeader
+		}
+	}
+	if rule.MaxAgeSeconds < 0 {
+		return errCorsInvalidMaxAge
+	}
+	return nil
+}
+
+// matchCorsPattern - returns true if value matches pattern, which may
+// contain one wildcard matching any sequence of characters.
+func matchCorsPattern(pattern, value string) bool {
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return pattern == value
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(value) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// allowsOrigin - returns true if the rule allows requests from origin.
+func (rule corsRule) allowsOrigin(origin string) bool {
+	for _, allowed := range rule.AllowedOrigins {
+		if matchCorsPattern(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod - returns true if the rule allows requests with method.
+func (rule corsRule) allowsMethod(method string) bool {
+	for _, allowed := range rule.AllowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders - returns true if the rule allows requests with all of
+// headers, header names are case insensitive.
+func (rule corsRule) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		allowed := false
+		for _, pattern := range rule.AllowedHeaders {
+			if matchCorsPattern(strings.ToLower(pattern), strings.ToLower(header)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// match - returns the first rule which allows a request from origin
+// with method and headers, nil if none does.
+func (config corsConfiguration) match(origin, method string, headers []string) *corsRule {
+	for i, rule := range config.Rules {
+		if rule.allowsOrigin(origin) && rule.allowsMethod(method) && rule.allowsHeaders(headers) {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+// setCorsHeaders - sets the CORS response headers allowing a request
+// from origin by rule.
+func setCorsHeaders(w http.ResponseWriter, rule *corsRule, origin string) {
+	if len(rule.AllowedOrigins) == 1 && rule.AllowedOrigins[0] == "*" {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+	if len(rule.ExposeHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+	if rule.MaxAgeSeconds > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+	}
+}
+
+// bucketCors - CORS configurations of all buckets which have one.
+type bucketCors struct {
+	mu      sync.RWMutex
+	configs map[string]corsConfiguration
+}
+
+// Global bucket CORS configurations.
+var globalBucketCors = &bucketCors{
+	configs: make(map[string]corsConfiguration),
+}
+
+// Get - returns the CORS configuration of a bucket, ok is false if the
+// bucket has none.
+func (c *bucketCors) Get(bucket string) (config corsConfiguration, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	config, ok = c.configs[bucket]
+	return config, ok
+}
+
+// Set - sets the CORS configuration of a bucket, nil removes the bucket.
+func (c *bucketCors) Set(bucket string, config *corsConfiguration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if config == nil {
+		delete(c.configs, bucket)
+		return
+	}
+	c.configs[bucket] = *config
+}
+
+// Initialize CORS configurations of all buckets.
+func initBucketCors(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		config, err := readBucketCorsConfig(objAPI, bucket.Name)
+		if err != nil {
+			if err == errConfigNotFound {
+				continue
+			}
+			return err
+		}
+		globalBucketCors.Set(bucket.Name, &config)
+	}
+	return nil
+}
+
+// readBucketCorsConfig - reads the CORS configuration of a bucket,
+// returns errConfigNotFound if the bucket has none.
+func readBucketCorsConfig(objAPI ObjectLayer, bucket string) (config corsConfiguration, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketCorsConfig)
+	if err != nil {
+		return config, err
+	}
+	if err = xml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
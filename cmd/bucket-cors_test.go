@@ -0,0 +1,158 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatchCorsPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"http://example.com", "http://example.com", true},
+		{"http://example.com", "http://example.org", false},
+		{"*", "", true},
+		{"*", "http://example.com", true},
+		{"http://*.example.com", "http://www.example.com", true},
+		{"http://*.example.com", "http://a.b.example.com", true},
+		{"http://*.example.com", "http://example.com", false},
+		{"http://*.example.com", "https://www.example.com", false},
+		// Prefix and suffix must not overlap.
+		{"ab*ba", "aba", false},
+		{"ab*ba", "abba", true},
+	}
+	for i, testCase := range testCases {
+		if matched := matchCorsPattern(testCase.pattern, testCase.value); matched != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, matched)
+		}
+	}
+}
+
+func TestCorsConfigurationMatch(t *testing.T) {
+	config := corsConfiguration{
+		Rules: []corsRule{
+			{
+				ID:             "write",
+				AllowedOrigins: []string{"https://*.example.com"},
+				AllowedMethods: []string{httpPUT, httpPOST, httpDELETE},
+				AllowedHeaders: []string{"Content-*", "x-amz-meta-*"},
+			},
+			{
+				ID:             "read",
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{httpGET, httpHEAD},
+			},
+		},
+	}
+
+	testCases := []struct {
+		origin     string
+		method     string
+		headers    []string
+		expectedID string
+	}{
+		{"https://www.example.com", httpPUT, nil, "write"},
+		{"https://www.example.com", httpPUT, []string{"content-type", "X-Amz-Meta-Name"}, "write"},
+		{"https://www.example.com", httpPUT, []string{"content-type", "authorization"}, ""},
+		{"http://www.example.com", httpPUT, nil, ""},
+		{"https://www.example.com", httpGET, nil, "read"},
+		{"https://other.org", httpHEAD, nil, "read"},
+		// Rules without allowed headers allow none.
+		{"https://other.org", httpGET, []string{"range"}, ""},
+		{"https://other.org", httpPOST, nil, ""},
+	}
+	for i, testCase := range testCases {
+		rule := config.match(testCase.origin, testCase.method, testCase.headers)
+		id := ""
+		if rule != nil {
+			id = rule.ID
+		}
+		if id != testCase.expectedID {
+			t.Errorf("Test %d: expected rule %q, got %q", i+1, testCase.expectedID, id)
+		}
+	}
+}
+
+func TestCorsConfigurationValidate(t *testing.T) {
+	validRule := corsRule{AllowedOrigins: []string{"*"}, AllowedMethods: []string{httpGET}}
+	tooManyRules := make([]corsRule, maxCorsRules+1)
+	for i := range tooManyRules {
+		tooManyRules[i] = validRule
+	}
+
+	testCases := []struct {
+		rules       []corsRule
+		expectedErr error
+	}{
+		{[]corsRule{validRule}, nil},
+		{nil, errCorsNoRules},
+		{tooManyRules, errCorsTooManyRules},
+		{[]corsRule{{AllowedMethods: []string{httpGET}}}, errCorsNoOrigin},
+		{[]corsRule{{AllowedOrigins: []string{"http://*.*.com"}, AllowedMethods: []string{httpGET}}}, errCorsInvalidOrigin},
+		{[]corsRule{{AllowedOrigins: []string{""}, AllowedMethods: []string{httpGET}}}, errCorsInvalidOrigin},
+		{[]corsRule{{AllowedOrigins: []string{"*"}}}, errCorsNoMethod},
+		{[]corsRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PATCH"}}}, errCorsInvalidMethod},
+		{[]corsRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{httpGET}, AllowedHeaders: []string{"**"}}}, errCorsInvalidHeader},
+		{[]corsRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{httpGET}, MaxAgeSeconds: -1}}, errCorsInvalidMaxAge},
+	}
+	for i, testCase := range testCases {
+		if err := (corsConfiguration{Rules: testCase.rules}).Validate(); err != testCase.expectedErr {
+			t.Errorf("Test %d: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestSetCorsHeaders(t *testing.T) {
+	rule := &corsRule{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{httpGET, httpPUT},
+		ExposeHeaders:  []string{"ETag"},
+		MaxAgeSeconds:  600,
+	}
+	w := httptest.NewRecorder()
+	setCorsHeaders(w, rule, "https://www.example.com")
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://www.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Expose-Headers":    "ETag",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range expected {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("Expected %s to be %q, got %q", name, value, got)
+		}
+	}
+
+	// Requests of any origin are allowed without credentials.
+	w = httptest.NewRecorder()
+	setCorsHeaders(w, &corsRule{AllowedOrigins: []string{"*"}, AllowedMethods: []string{httpGET}}, "https://other.org")
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+		t.Errorf("Expected any origin to be allowed, got %q", origin)
+	}
+	if credentials := w.Header().Get("Access-Control-Allow-Credentials"); credentials != "" {
+		t.Errorf("Expected no credentials to be allowed, got %q", credentials)
+	}
+	if strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), httpPUT) {
+		t.Error("Expected only GET to be allowed")
+	}
+}
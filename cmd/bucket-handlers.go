@@ -60,18 +60,18 @@ func (api objectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 	bucket := vars["bucket"]
 
 	// Parse incoming location constraint.
-	//location, s3Error := parseLocationConstraint(r)
-	//if s3Error != ErrNone {
-	//	writeErrorResponse(w, s3Error, r.URL)
-	//	return
-	//}
+	location, s3Error := parseLocationConstraint(r)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, s3Error, r.URL)
+		return
+	}
 
 	// Validate if location sent by the client is valid, reject
 	// requests which do not follow valid region requirements.
-	//if !isValidLocation(location) {
-	//	writeErrorResponse(w, ErrInvalidRegion, r.URL)
-	//	return
-	//}
+	if !isValidLocation(location) {
+		writeErrorResponse(w, ErrInvalidRegion, r.URL)
+		return
+	}
 
 	//bucketLock := globalNSMutex.NewNSLock(bucket, "")
 	//bucketLock.Lock()
@@ -0,0 +1,186 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetBucketLifecycleHandler - GET Bucket lifecycle
+// -----------------
+// Returns the lifecycle configuration of a bucket.
+func (api objectAPIHandlers) GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	config, err := readBucketLifecycleConfig(objectAPI, bucket)
+	if err != nil {
+		if err == errConfigNotFound {
+			writeErrorResponse(w, ErrNoSuchLifecycleConfiguration, r.URL)
+			return
+		}
+		println(err, "Unable to read lifecycle configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	config.XMLNS = s3XMLNamespace
+
+	writeSuccessResponseXML(w, encodeResponse(config))
+}
+
+// PutBucketLifecycleHandler - PUT Bucket lifecycle
+// -----------------
+// Replaces the lifecycle configuration of a bucket, the rules are
+// applied by the lifecycle scanner in the background.
+func (api objectAPIHandlers) PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// PutBucketLifecycle always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	config := lifecycleConfiguration{}
+	if err := xmlDecoder(r.Body, &config, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse lifecycle configuration.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+	if err := config.Validate(); err != nil {
+		println(err, "Invalid lifecycle configuration of bucket", bucket)
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	config.XMLNS = ""
+	data, err := xml.Marshal(config)
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketLifecycleConfig, data); err != nil {
+		println(err, "Unable to save lifecycle configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketLifecycle.Set(bucket, &config)
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// DeleteBucketLifecycleHandler - DELETE Bucket lifecycle
+// -----------------
+// Removes the lifecycle configuration of a bucket.
+func (api objectAPIHandlers) DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	if err := removeBucketConfig(objectAPI, bucket, bucketLifecycleConfig); err != nil {
+		println(err, "Unable to remove lifecycle configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketLifecycle.Set(bucket, nil)
+
+	writeSuccessNoContent(w)
+}
+
+// RestoreObjectHandler - POST Object restore
+// -----------------
+// Copies the data of an object transitioned to the lifecycle tier back
+// for a number of days, restoring an object already restored updates
+// the expiry of the restore.
+func (api objectAPIHandlers) RestoreObjectHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	// RestoreObject always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	request := restoreRequest{}
+	if err := xmlDecoder(r.Body, &request, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse restore request.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+	if request.Days <= 0 {
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	alreadyRestored, err := objectAPI.RestoreObject(bucket, object, request.Days)
+	if err != nil {
+		println(err, "Unable to restore object. %s", r.URL.Path)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	if alreadyRestored {
+		writeSuccessResponseHeadersOnly(w)
+		return
+	}
+	writeResponse(w, http.StatusAccepted, nil, mimeNone)
+}
@@ -0,0 +1,567 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Bucket lifecycle config name.
+	bucketLifecycleConfig = "lifecycle.xml"
+
+	// Lifecycle rule states.
+	lifecycleRuleEnabled  = "Enabled"
+	lifecycleRuleDisabled = "Disabled"
+
+	// Limits of a lifecycle configuration as per S3 spec.
+	maxLifecycleRules     = 1000
+	maxLifecycleRuleIDLen = 255
+
+	// Storage class of objects which were not transitioned.
+	standardStorageClass = "STANDARD"
+)
+
+// Lifecycle related headers.
+const (
+	amzStorageClass = "X-Amz-Storage-Class"
+	amzRestore      = "X-Amz-Restore"
+)
+
+// Lifecycle configuration errors.
+var (
+	errLifecycleNoRules         = errors.New("Lifecycle configuration must have at least one rule")
+	errLifecycleTooManyRules    = errors.New("Lifecycle configuration allows a maximum of 1000 rules")
+	errLifecycleInvalidRuleID   = errors.New("Lifecycle rule ID must be unique and at most 255 characters long")
+	errLifecycleInvalidStatus   = errors.New("Lifecycle rule status must be either Enabled or Disabled")
+	errLifecycleNoAction        = errors.New("Lifecycle rule must specify at least one action")
+	errLifecycleInvalidFilter   = errors.New("Lifecycle rule filter must specify exactly one of Prefix, Tag or And")
+	errLifecycleInvalidTag      = errors.New("Lifecycle rule has an invalid or duplicate tag")
+	errLifecycleInvalidDays     = errors.New("Lifecycle rule days must be a positive integer")
+	errLifecycleInvalidDate     = errors.New("Lifecycle rule date must be at midnight UTC in ISO 8601 format")
+	errLifecycleInvalidExpiry   = errors.New("Lifecycle rule expiration must specify exactly one of Days or Date")
+	errLifecycleAbortWithTags   = errors.New("Lifecycle rule with tag filters can not abort incomplete multipart uploads")
+	errLifecycleDuplicatePrefix = errors.New("Lifecycle rule must specify either a Prefix or a Filter, not both")
+	errLifecycleInvalidTransit  = errors.New("Lifecycle rule transition must specify exactly one of Days or Date")
+	errLifecycleInvalidClass    = errors.New("Lifecycle rule transition must specify a storage class other than STANDARD")
+	errLifecycleNoTier          = errors.New("Lifecycle rule transition requires a lifecycle tier to be configured")
+	errLifecycleTransitAfterExp = errors.New("Lifecycle rule must expire objects after transitioning them")
+)
+
+// lifecycleTag - tag a version must have to match a rule.
+type lifecycleTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// lifecycleAnd - prefix and tags a version must all have to match a rule.
+type lifecycleAnd struct {
+	Prefix string         `xml:"Prefix,omitempty"`
+	Tags   []lifecycleTag `xml:"Tag"`
+}
+
+// lifecycleFilter - selects the versions a rule applies to, by
+// exactly one of a prefix, a tag or a combination of both.
+type lifecycleFilter struct {
+	Prefix *string       `xml:"Prefix"`
+	Tag    *lifecycleTag `xml:"Tag"`
+	And    *lifecycleAnd `xml:"And"`
+}
+
+// lifecycleExpiration - expires versions a number of days after they
+// were written or from a date on.
+type lifecycleExpiration struct {
+	Days int    `xml:"Days,omitempty"`
+	Date string `xml:"Date,omitempty"`
+}
+
+// lifecycleTransition - moves versions to the lifecycle tier a number of
+// days after they were written or from a date on.
+type lifecycleTransition struct {
+	Days         int    `xml:"Days,omitempty"`
+	Date         string `xml:"Date,omitempty"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// restoreRequest - restores a transitioned object for a number of days.
+type restoreRequest struct {
+	XMLName xml.Name `xml:"RestoreRequest"`
+	Days    int      `xml:"Days"`
+}
+
+// abortIncompleteMultipartUpload - aborts multipart uploads a number
+// of days after they were initiated.
+type abortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// lifecycleRule - lifecycle rule, Prefix is the deprecated form of a
+// prefix filter.
+type lifecycleRule struct {
+	ID                             string                          `xml:"ID,omitempty"`
+	Status                         string                          `xml:"Status"`
+	Prefix                         *string                         `xml:"Prefix"`
+	Filter                         *lifecycleFilter                `xml:"Filter"`
+	Transition                     *lifecycleTransition            `xml:"Transition"`
+	Expiration                     *lifecycleExpiration            `xml:"Expiration"`
+	AbortIncompleteMultipartUpload *abortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload"`
+}
+
+// lifecycleConfiguration - bucket lifecycle configuration.
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	XMLNS   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+// Validate - validates a lifecycle configuration as per S3 spec.
+func (config lifecycleConfiguration) Validate() error {
+	if len(config.Rules) == 0 {
+		return errLifecycleNoRules
+	}
+	if len(config.Rules) > maxLifecycleRules {
+		return errLifecycleTooManyRules
+	}
+	ruleIDs := make(map[string]struct{})
+	for _, rule := range config.Rules {
+		if rule.ID != "" {
+			if _, ok := ruleIDs[rule.ID]; ok || len(rule.ID) > maxLifecycleRuleIDLen {
+				return errLifecycleInvalidRuleID
+			}
+			ruleIDs[rule.ID] = struct{}{}
+		}
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate - validates a lifecycle rule as per S3 spec.
+func (rule lifecycleRule) Validate() error {
+	if rule.Status != lifecycleRuleEnabled && rule.Status != lifecycleRuleDisabled {
+		return errLifecycleInvalidStatus
+	}
+	if rule.Expiration == nil && rule.Transition == nil && rule.AbortIncompleteMultipartUpload == nil {
+		return errLifecycleNoAction
+	}
+
+	if rule.Prefix != nil && rule.Filter != nil {
+		return errLifecycleDuplicatePrefix
+	}
+	if filter := rule.Filter; filter != nil {
+		filters := 0
+		for _, set := range []bool{filter.Prefix != nil, filter.Tag != nil, filter.And != nil} {
+			if set {
+				filters++
+			}
+		}
+		if filters != 1 {
+			return errLifecycleInvalidFilter
+		}
+		tagKeys := make(map[string]struct{})
+		for _, tag := range rule.tags() {
+			if _, ok := tagKeys[tag.Key]; ok || tag.Key == "" || len(tag.Key) > maxObjectTagKeyLen || len(tag.Value) > maxObjectTagValueLen {
+				return errLifecycleInvalidTag
+			}
+			tagKeys[tag.Key] = struct{}{}
+		}
+	}
+
+	if expiration := rule.Expiration; expiration != nil {
+		if (expiration.Days == 0) == (expiration.Date == "") {
+			return errLifecycleInvalidExpiry
+		}
+		if expiration.Days < 0 {
+			return errLifecycleInvalidDays
+		}
+		if expiration.Date != "" {
+			if _, err := parseLifecycleDate(expiration.Date); err != nil {
+				return err
+			}
+		}
+	}
+	if transition := rule.Transition; transition != nil {
+		if (transition.Days == 0) == (transition.Date == "") {
+			return errLifecycleInvalidTransit
+		}
+		if transition.Days < 0 {
+			return errLifecycleInvalidDays
+		}
+		if transition.Date != "" {
+			if _, err := parseLifecycleDate(transition.Date); err != nil {
+				return err
+			}
+		}
+		if transition.StorageClass == "" || transition.StorageClass == standardStorageClass {
+			return errLifecycleInvalidClass
+		}
+		if globalLifecycleTier == nil {
+			return errLifecycleNoTier
+		}
+		if expiration := rule.Expiration; expiration != nil && expiration.Days > 0 && transition.Days >= expiration.Days {
+			return errLifecycleTransitAfterExp
+		}
+	}
+	if abort := rule.AbortIncompleteMultipartUpload; abort != nil {
+		if abort.DaysAfterInitiation <= 0 {
+			return errLifecycleInvalidDays
+		}
+		if len(rule.tags()) > 0 {
+			return errLifecycleAbortWithTags
+		}
+	}
+	return nil
+}
+
+// prefix - returns the prefix of the objects a rule applies to.
+func (rule lifecycleRule) prefix() string {
+	if rule.Prefix != nil {
+		return *rule.Prefix
+	}
+	if filter := rule.Filter; filter != nil {
+		if filter.Prefix != nil {
+			return *filter.Prefix
+		}
+		if filter.And != nil {
+			return filter.And.Prefix
+		}
+	}
+	return ""
+}
+
+// tags - returns the tags of the objects a rule applies to.
+func (rule lifecycleRule) tags() []lifecycleTag {
+	if filter := rule.Filter; filter != nil {
+		if filter.Tag != nil {
+			return []lifecycleTag{*filter.Tag}
+		}
+		if filter.And != nil {
+			return filter.And.Tags
+		}
+	}
+	return nil
+}
+
+// matchesTags - returns true if tags has all the tags of a rule.
+func (rule lifecycleRule) matchesTags(tags url.Values) bool {
+	for _, tag := range rule.tags() {
+		if values, ok := tags[tag.Key]; !ok || len(values) == 0 || values[0] != tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLifecycleDate - parses a lifecycle rule date, which must be at
+// midnight UTC in ISO 8601 format.
+func parseLifecycleDate(date string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, errLifecycleInvalidDate
+	}
+	t = t.UTC()
+	if !t.Equal(t.Truncate(24 * time.Hour)) {
+		return time.Time{}, errLifecycleInvalidDate
+	}
+	return t, nil
+}
+
+// lifecycleDueTime - returns the time an action applies to a version
+// or an upload created at modTime after days, rounded up to the next
+// midnight UTC like S3 does.
+func lifecycleDueTime(modTime time.Time, days int) time.Time {
+	t := modTime.UTC().Add(time.Duration(days) * 24 * time.Hour)
+	return t.Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// isLifecycleActionDue - returns true if an action applying days after
+// modTime or from date on applies at now.
+func isLifecycleActionDue(days int, date string, modTime, now time.Time) bool {
+	if date != "" {
+		t, err := parseLifecycleDate(date)
+		return err == nil && !now.Before(t)
+	}
+	return !now.Before(lifecycleDueTime(modTime, days))
+}
+
+// isExpired - returns true if a version written at modTime is expired
+// by a rule at now.
+func (expiration lifecycleExpiration) isExpired(modTime, now time.Time) bool {
+	return isLifecycleActionDue(expiration.Days, expiration.Date, modTime, now)
+}
+
+// isDue - returns true if a version written at modTime is to be
+// transitioned by a rule at now.
+func (transition lifecycleTransition) isDue(modTime, now time.Time) bool {
+	return isLifecycleActionDue(transition.Days, transition.Date, modTime, now)
+}
+
+// bucketLifecycle - lifecycle configurations of all buckets which
+// have one.
+type bucketLifecycle struct {
+	mu      sync.RWMutex
+	configs map[string]lifecycleConfiguration
+}
+
+// Global bucket lifecycle configurations.
+var globalBucketLifecycle = &bucketLifecycle{
+	configs: make(map[string]lifecycleConfiguration),
+}
+
+// Get - returns the lifecycle configuration of a bucket, ok is false
+// if the bucket has none.
+func (l *bucketLifecycle) Get(bucket string) (config lifecycleConfiguration, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	config, ok = l.configs[bucket]
+	return config, ok
+}
+
+// Set - sets the lifecycle configuration of a bucket, nil removes the
+// bucket.
+func (l *bucketLifecycle) Set(bucket string, config *lifecycleConfiguration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if config == nil {
+		delete(l.configs, bucket)
+		return
+	}
+	l.configs[bucket] = *config
+}
+
+// Initialize lifecycle configurations of all buckets and start the
+// lifecycle scanner.
+func initBucketLifecycle(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		config, err := readBucketLifecycleConfig(objAPI, bucket.Name)
+		if err != nil {
+			if err == errConfigNotFound {
+				continue
+			}
+			return err
+		}
+		globalBucketLifecycle.Set(bucket.Name, &config)
+	}
+	globalLifecycleScanner.Start()
+	return nil
+}
+
+// readBucketLifecycleConfig - reads the lifecycle configuration of a
+// bucket, returns errConfigNotFound if the bucket has none.
+func readBucketLifecycleConfig(objAPI ObjectLayer, bucket string) (config lifecycleConfiguration, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketLifecycleConfig)
+	if err != nil {
+		return config, err
+	}
+	if err = xml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// lifecycleScanner - background routine applying the lifecycle rules
+// of all buckets, the scan pauses after each object or upload to
+// limit its load on the disk.
+type lifecycleScanner struct {
+	startOnce sync.Once
+}
+
+// Global lifecycle scanner.
+var globalLifecycleScanner = &lifecycleScanner{}
+
+// Start - starts scanning all buckets every scan interval, only the
+// first call has any effect.
+func (s *lifecycleScanner) Start() {
+	s.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(globalLifecycleScanInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				s.Scan()
+			}
+		}()
+	})
+}
+
+// Scan - applies the lifecycle rules of all buckets once.
+func (s *lifecycleScanner) Scan() {
+	objAPI := newObjectLayerFn()
+	if objAPI == nil {
+		// Server not initialized yet, retry on the next scan.
+		return
+	}
+
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		println(err, "Unable to list buckets for lifecycle scan.")
+		return
+	}
+	for _, bucket := range buckets {
+		config, ok := globalBucketLifecycle.Get(bucket.Name)
+		if !ok {
+			continue
+		}
+		var expirationRules, transitionRules, abortRules []lifecycleRule
+		for _, rule := range config.Rules {
+			if rule.Status != lifecycleRuleEnabled {
+				continue
+			}
+			if rule.Expiration != nil {
+				expirationRules = append(expirationRules, rule)
+			}
+			if rule.Transition != nil && globalLifecycleTier != nil {
+				transitionRules = append(transitionRules, rule)
+			}
+			if rule.AbortIncompleteMultipartUpload != nil {
+				abortRules = append(abortRules, rule)
+			}
+		}
+		if len(expirationRules) > 0 || len(transitionRules) > 0 {
+			s.scanObjects(objAPI, bucket.Name, expirationRules, transitionRules)
+		}
+		if len(abortRules) > 0 {
+			s.abortUploads(objAPI, bucket.Name, abortRules)
+		}
+	}
+}
+
+// Pauses the scan after an object or upload was scanned.
+func (s *lifecycleScanner) throttle() {
+	if globalLifecycleScanDelay > 0 {
+		time.Sleep(globalLifecycleScanDelay)
+	}
+}
+
+// scanObjects - deletes the objects of a bucket expired by rules, in
+// versioned buckets their latest version is replaced by a delete
+// marker, and moves the objects due for transition to the lifecycle
+// tier. Objects under retention or legal hold are not expired.
+func (s *lifecycleScanner) scanObjects(objAPI ObjectLayer, bucket string, expirationRules, transitionRules []lifecycleRule) {
+	marker := ""
+	for {
+		result, err := objAPI.ListObjects(bucket, "", marker, "", maxObjectList)
+		if err != nil {
+			println(err, "Unable to list objects for lifecycle scan of bucket", bucket)
+			return
+		}
+		now := UTCNow()
+		for _, obj := range result.Objects {
+			if obj.IsDir {
+				continue
+			}
+			// Tags are only read for rules with tag filters.
+			var tags url.Values
+			getTags := func() url.Values {
+				if tags == nil {
+					tags = url.Values{}
+					if objInfo, gerr := objAPI.GetObjectInfo(bucket, obj.Name); gerr == nil {
+						tags = getObjectTags(objInfo.UserDefined)
+					}
+				}
+				return tags
+			}
+
+			expired := matchLifecycleRule(obj.Name, expirationRules, getTags, func(rule lifecycleRule) bool {
+				return rule.Expiration.isExpired(obj.ModTime, now)
+			})
+			if expired != nil {
+				err = objAPI.ExpireObject(bucket, obj.Name, obj.ModTime)
+				switch errorCause(err).(type) {
+				case nil, ObjectLocked, ObjectNotFound:
+				default:
+					println(err, "Unable to expire object", bucket, obj.Name)
+				}
+				s.throttle()
+				continue
+			}
+
+			transition := matchLifecycleRule(obj.Name, transitionRules, getTags, func(rule lifecycleRule) bool {
+				return rule.Transition.isDue(obj.ModTime, now)
+			})
+			if transition != nil {
+				err = objAPI.TransitionObject(bucket, obj.Name, transition.Transition.StorageClass, obj.ModTime)
+				if _, ok := errorCause(err).(ObjectNotFound); err != nil && !ok {
+					println(err, "Unable to transition object", bucket, obj.Name)
+				}
+			}
+			s.throttle()
+		}
+		if !result.IsTruncated {
+			return
+		}
+		marker = result.NextMarker
+	}
+}
+
+// matchLifecycleRule - returns the first of rules which applies to an
+// object and is due, nil if none.
+func matchLifecycleRule(object string, rules []lifecycleRule, getTags func() url.Values, isDue func(lifecycleRule) bool) *lifecycleRule {
+	for i, rule := range rules {
+		if !strings.HasPrefix(object, rule.prefix()) || !isDue(rule) {
+			continue
+		}
+		if len(rule.tags()) == 0 || rule.matchesTags(getTags()) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// abortUploads - aborts the multipart uploads of a bucket which were
+// initiated longer ago than allowed by rules.
+func (s *lifecycleScanner) abortUploads(objAPI ObjectLayer, bucket string, rules []lifecycleRule) {
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		result, err := objAPI.ListMultipartUploads(bucket, "", keyMarker, uploadIDMarker, "", maxUploadsList)
+		if err != nil {
+			println(err, "Unable to list multipart uploads for lifecycle scan of bucket", bucket)
+			return
+		}
+		now := UTCNow()
+		for _, upload := range result.Uploads {
+			for _, rule := range rules {
+				if !strings.HasPrefix(upload.Object, rule.prefix()) {
+					continue
+				}
+				if now.Before(lifecycleDueTime(upload.Initiated, rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)) {
+					continue
+				}
+				if err = objAPI.AbortMultipartUpload(bucket, upload.Object, upload.UploadID); err != nil {
+					println(err, "Unable to abort multipart upload", bucket, upload.Object, upload.UploadID)
+				}
+				break
+			}
+			s.throttle()
+		}
+		if !result.IsTruncated {
+			return
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+}
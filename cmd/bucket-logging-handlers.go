@@ -0,0 +1,132 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// S3 XML namespace.
+const s3XMLNamespace = "http://doc.s3.amazonaws.com/2006-03-01"
+
+// GetBucketLoggingHandler - GET Bucket logging
+// -----------------
+// Returns the logging status of a bucket, an empty
+// BucketLoggingStatus is returned if logging is disabled.
+func (api objectAPIHandlers) GetBucketLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	status, err := readBucketLoggingConfig(objectAPI, bucket)
+	if err != nil {
+		println(err, "Unable to read logging configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	status.XMLNS = s3XMLNamespace
+
+	writeSuccessResponseXML(w, encodeResponse(status))
+}
+
+// PutBucketLoggingHandler - PUT Bucket logging
+// -----------------
+// Enables logging of access records of a bucket into a target
+// bucket and prefix, an empty BucketLoggingStatus disables logging.
+func (api objectAPIHandlers) PutBucketLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// PutBucketLogging always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	status := bucketLoggingStatus{}
+	if err := xmlDecoder(r.Body, &status, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse logging configuration.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	if status.LoggingEnabled == nil {
+		// Disable logging.
+		if err := removeBucketConfig(objectAPI, bucket, bucketLoggingConfig); err != nil {
+			println(err, "Unable to remove logging configuration of bucket", bucket)
+			writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+			return
+		}
+		globalBucketAccessLogger.SetConfig(bucket, nil)
+		writeSuccessResponseHeadersOnly(w)
+		return
+	}
+
+	// Validate the target bucket and prefix.
+	target := status.LoggingEnabled
+	if !IsValidBucketName(target.TargetBucket) || isMinioMetaBucketName(target.TargetBucket) {
+		writeErrorResponse(w, ErrInvalidTargetBucketForLogging, r.URL)
+		return
+	}
+	if _, err := objectAPI.GetBucketInfo(target.TargetBucket); err != nil {
+		writeErrorResponse(w, ErrInvalidTargetBucketForLogging, r.URL)
+		return
+	}
+	if !IsValidObjectPrefix(target.TargetPrefix) {
+		writeErrorResponse(w, ErrInvalidObjectName, r.URL)
+		return
+	}
+
+	data, err := xml.Marshal(bucketLoggingStatus{LoggingEnabled: target})
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketLoggingConfig, data); err != nil {
+		println(err, "Unable to save logging configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketAccessLogger.SetConfig(bucket, target)
+
+	writeSuccessResponseHeadersOnly(w)
+}
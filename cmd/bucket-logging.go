@@ -0,0 +1,407 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+const (
+	// Bucket logging config name.
+	bucketLoggingConfig = "logging.xml"
+
+	// Interval at which buffered access records are written
+	// as log objects into the target buckets.
+	bucketLoggingFlushInterval = 5 * time.Minute
+
+	// Buffered access records for a target are written out
+	// early once they grow beyond this size.
+	bucketLoggingFlushSize = 5 * humanize.MiByte
+
+	// Maximum access records retained per target while the
+	// target bucket cannot be written to.
+	bucketLoggingMaxBufferSize = 64 * humanize.MiByte
+
+	// Time format used in access log records.
+	bucketLoggingTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+	// Time format used in log object names.
+	bucketLoggingObjectTimeFormat = "2006-01-02-15-04-05"
+)
+
+// bucketLoggingStatus - bucket logging configuration, an empty
+// status without LoggingEnabled disables logging.
+type bucketLoggingStatus struct {
+	XMLName        xml.Name        `xml:"BucketLoggingStatus"`
+	XMLNS          string          `xml:"xmlns,attr,omitempty"`
+	LoggingEnabled *loggingEnabled `xml:"LoggingEnabled,omitempty"`
+}
+
+// loggingEnabled - target bucket and prefix of the log objects.
+type loggingEnabled struct {
+	TargetBucket string `xml:"TargetBucket"`
+	TargetPrefix string `xml:"TargetPrefix"`
+}
+
+// bucketAccessLogger - batches access records of buckets with logging
+// enabled and periodically writes them as objects into the target
+// buckets through the object layer.
+type bucketAccessLogger struct {
+	mu sync.Mutex
+	// Logging target for each bucket with logging enabled.
+	configs map[string]loggingEnabled
+	// Pending access records for each target.
+	buffers map[loggingEnabled]*bytes.Buffer
+
+	flushCh   chan struct{}
+	startOnce sync.Once
+}
+
+// newBucketAccessLogger - returns an initialized bucket access logger.
+func newBucketAccessLogger() *bucketAccessLogger {
+	return &bucketAccessLogger{
+		configs: make(map[string]loggingEnabled),
+		buffers: make(map[loggingEnabled]*bytes.Buffer),
+		flushCh: make(chan struct{}, 1),
+	}
+}
+
+// Global bucket access logger.
+var globalBucketAccessLogger = newBucketAccessLogger()
+
+// GetConfig - returns the logging target of a bucket.
+func (l *bucketAccessLogger) GetConfig(bucket string) (target loggingEnabled, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	target, ok = l.configs[bucket]
+	return target, ok
+}
+
+// SetConfig - sets the logging target of a bucket, a nil
+// target disables logging for the bucket.
+func (l *bucketAccessLogger) SetConfig(bucket string, target *loggingEnabled) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if target == nil {
+		delete(l.configs, bucket)
+		return
+	}
+	l.configs[bucket] = *target
+}
+
+// Log - buffers an access record for the target.
+func (l *bucketAccessLogger) Log(target loggingEnabled, record string) {
+	l.mu.Lock()
+	buf, ok := l.buffers[target]
+	if !ok {
+		buf = &bytes.Buffer{}
+		l.buffers[target] = buf
+	}
+	if buf.Len() >= bucketLoggingMaxBufferSize {
+		l.mu.Unlock()
+		println(fmt.Errorf("access log buffer for %s/%s is full", target.TargetBucket, target.TargetPrefix), "Dropping access record.")
+		return
+	}
+	buf.WriteString(record)
+	buf.WriteByte('\n')
+	full := buf.Len() >= bucketLoggingFlushSize
+	l.mu.Unlock()
+
+	if full {
+		// Request an early flush, if one is already pending
+		// there is nothing to do.
+		select {
+		case l.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start - starts the background routine which writes out the
+// buffered access records, only the first call has any effect.
+func (l *bucketAccessLogger) Start() {
+	l.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(bucketLoggingFlushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-l.flushCh:
+				}
+				l.Flush()
+			}
+		}()
+	})
+}
+
+// Flush - writes all the buffered access records as log objects.
+// Records which could not be written are retained for the next flush.
+func (l *bucketAccessLogger) Flush() {
+	objAPI := newObjectLayerFn()
+	if objAPI == nil {
+		// Server not initialized yet, retry on the next flush.
+		return
+	}
+
+	l.mu.Lock()
+	pending := l.buffers
+	l.buffers = make(map[loggingEnabled]*bytes.Buffer)
+	l.mu.Unlock()
+
+	for target, buf := range pending {
+		if buf.Len() == 0 {
+			continue
+		}
+		objectName := getBucketLogObjectName(target.TargetPrefix, UTCNow())
+		metadata := map[string]string{"content-type": "text/plain"}
+		_, err := objAPI.PutObject(target.TargetBucket, objectName, int64(buf.Len()),
+			bytes.NewReader(buf.Bytes()), metadata, "")
+		if err != nil {
+			println(err, "Unable to write access log object", target.TargetBucket, objectName)
+			l.requeue(target, buf)
+		}
+	}
+}
+
+// requeue - puts back records which could not be written, ahead
+// of the records logged since the flush started.
+func (l *bucketAccessLogger) requeue(target loggingEnabled, buf *bytes.Buffer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if newer, ok := l.buffers[target]; ok {
+		if buf.Len()+newer.Len() > bucketLoggingMaxBufferSize {
+			println(fmt.Errorf("access log buffer for %s/%s is full", target.TargetBucket, target.TargetPrefix),
+				"Dropping unwritten access records.")
+			return
+		}
+		buf.Write(newer.Bytes())
+	}
+	l.buffers[target] = buf
+}
+
+// getBucketLogObjectName - returns a unique log object name in the
+// format `TargetPrefixYYYY-mm-DD-HH-MM-SS-UniqueString`.
+func getBucketLogObjectName(prefix string, t time.Time) string {
+	uniqueID := strings.ToUpper(strings.Replace(mustGetUUID(), "-", "", -1))[:16]
+	return prefix + t.UTC().Format(bucketLoggingObjectTimeFormat) + "-" + uniqueID
+}
+
+// initBucketLogging - loads logging configuration of all the buckets
+// and starts writing out access records.
+func initBucketLogging(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		status, err := readBucketLoggingConfig(objAPI, bucket.Name)
+		if err != nil {
+			return err
+		}
+		globalBucketAccessLogger.SetConfig(bucket.Name, status.LoggingEnabled)
+	}
+	globalBucketAccessLogger.Start()
+	return nil
+}
+
+// readBucketLoggingConfig - reads the logging configuration of a bucket,
+// returns an empty status if the bucket has no logging configuration.
+func readBucketLoggingConfig(objAPI ObjectLayer, bucket string) (status bucketLoggingStatus, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketLoggingConfig)
+	if err != nil {
+		if err == errConfigNotFound {
+			return status, nil
+		}
+		return status, err
+	}
+	if err = xml.Unmarshal(data, &status); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// Returns the value or "-" for empty values as used in access records.
+func accessLogField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// Returns the quoted value or "-" for empty values.
+func accessLogQuotedField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strconv.Quote(value)
+}
+
+// Access log operation types of APIs which do not
+// operate on the bucket or the object directly.
+var accessLogOperationTypes = map[string]string{
+	"GetBucketLocation":         "LOCATION",
+	"GetBucketPolicy":           "BUCKETPOLICY",
+	"PutBucketPolicy":           "BUCKETPOLICY",
+	"DeleteBucketPolicy":        "BUCKETPOLICY",
+	"GetBucketNotification":     "NOTIFICATION",
+	"PutBucketNotification":     "NOTIFICATION",
+	"ListenBucketNotification":  "NOTIFICATION",
+	"GetBucketLogging":          "LOGGING_STATUS",
+	"PutBucketLogging":          "LOGGING_STATUS",
+	"GetBucketVersioning":       "VERSIONING",
+	"PutBucketVersioning":       "VERSIONING",
+	"ListObjectVersions":        "BUCKETVERSIONS",
+	"GetBucketObjectLockConfig": "OBJECT_LOCK_CONFIGURATION",
+	"PutBucketObjectLockConfig": "OBJECT_LOCK_CONFIGURATION",
+	"GetObjectRetention":        "RETENTION",
+	"PutObjectRetention":        "RETENTION",
+	"GetObjectLegalHold":        "LEGAL_HOLD",
+	"PutObjectLegalHold":        "LEGAL_HOLD",
+	"GetBucketLifecycle":        "LIFECYCLE",
+	"PutBucketLifecycle":        "LIFECYCLE",
+	"DeleteBucketLifecycle":     "LIFECYCLE",
+	"RestoreObject":             "RESTORE",
+	"GetBucketCors":             "CORS",
+	"PutBucketCors":             "CORS",
+	"DeleteBucketCors":          "CORS",
+	"ListMultipartUploads":      "UPLOADS",
+	"NewMultipartUpload":        "UPLOADS",
+	"PutObjectPart":             "PART",
+	"CopyObjectPart":            "PART",
+	"ListObjectParts":           "UPLOAD",
+	"CompleteMultipartUpload":   "UPLOAD",
+	"AbortMultipartUpload":      "UPLOAD",
+	"DeleteMultipleObjects":     "MULTI_OBJECT_DELETE",
+}
+
+// getAccessLogOperation - returns the operation of an access record
+// in the format `REST.<HTTP method>.<resource type>`.
+func getAccessLogOperation(r *http.Request, object string) string {
+	api := getAPIName(r)
+	opType, ok := accessLogOperationTypes[api]
+	if !ok {
+		opType = "BUCKET"
+		if object != "" {
+			opType = "OBJECT"
+		}
+	}
+	method := r.Method
+	if api == "CopyObject" {
+		method = "COPY"
+	}
+	return "REST." + method + "." + opType
+}
+
+// Returns the signature version and authentication type of
+// the request as used in access records.
+func getAccessLogAuthInfo(r *http.Request) (sigVersion, authType string) {
+	switch getRequestAuthType(r) {
+	case authTypeSigned, authTypeStreamingSigned:
+		return "SigV4", "AuthHeader"
+	case authTypePresigned:
+		return "SigV4", "QueryString"
+	case authTypeSignedV2:
+		return "SigV2", "AuthHeader"
+	case authTypePresignedV2:
+		return "SigV2", "QueryString"
+	case authTypePostPolicy:
+		return "SigV4", "AuthHeader"
+	}
+	return "", ""
+}
+
+// Names of TLS versions as used in access records.
+var accessLogTLSVersions = map[uint16]string{
+	0x0301: "TLSv1",
+	0x0302: "TLSv1.1",
+	0x0303: "TLSv1.2",
+	0x0304: "TLSv1.3",
+}
+
+// formatAccessLogRecord - formats a single access record in the
+// Amazon S3 server access log format, see
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/LogFormat.html
+func formatAccessLogRecord(r *http.Request, w *httpResponseRecorder, reqTime time.Time, totalTime time.Duration) string {
+	bucket, object := urlPath2BucketObjectName(r.URL)
+
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	var bytesSent string
+	if w.bytesWritten > 0 {
+		bytesSent = strconv.FormatInt(w.bytesWritten, 10)
+	}
+
+	// Object size is known from the response for reads
+	// and from the request for writes.
+	objectSize := w.Header().Get("Content-Length")
+	if r.Method == httpPUT && object != "" && r.ContentLength >= 0 {
+		objectSize = strconv.FormatInt(r.ContentLength, 10)
+	}
+
+	var cipherSuite, tlsVersion string
+	if r.TLS != nil {
+		cipherSuite = tls.CipherSuiteName(r.TLS.CipherSuite)
+		tlsVersion = accessLogTLSVersions[r.TLS.Version]
+	}
+
+	sigVersion, authType := getAccessLogAuthInfo(r)
+	fields := []string{
+		globalMinioDefaultOwnerID,
+		bucket,
+		"[" + reqTime.Format(bucketLoggingTimeFormat) + "]",
+		accessLogField(remoteIP),
+		accessLogField(getRequestAccessKey(r)),
+		accessLogField(w.Header().Get(responseRequestIDKey)),
+		getAccessLogOperation(r, object),
+		accessLogField(getURLEncodedName(object)),
+		strconv.Quote(r.Method + " " + r.URL.RequestURI() + " " + r.Proto),
+		strconv.Itoa(w.statusCode()),
+		accessLogField(w.errorCode()),
+		accessLogField(bytesSent),
+		accessLogField(objectSize),
+		strconv.FormatInt(int64(totalTime/time.Millisecond), 10),
+		"-", // Turn-around time is not tracked.
+		accessLogQuotedField(r.Referer()),
+		accessLogQuotedField(r.UserAgent()),
+		accessLogField(r.URL.Query().Get("versionId")),
+		"-", // Host ID is not used.
+		accessLogField(sigVersion),
+		accessLogField(cipherSuite),
+		accessLogField(authType),
+		accessLogField(r.Host),
+		accessLogField(tlsVersion),
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,305 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"time"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetBucketObjectLockConfigHandler - GET Bucket object-lock
+// -----------------
+// Returns the object lock configuration of a bucket.
+func (api objectAPIHandlers) GetBucketObjectLockConfigHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	config, err := readBucketObjectLockConfig(objectAPI, bucket)
+	if err != nil {
+		if err == errConfigNotFound {
+			writeErrorResponse(w, ErrObjectLockConfigurationNotFound, r.URL)
+			return
+		}
+		println(err, "Unable to read object lock configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	config.XMLNS = s3XMLNamespace
+
+	writeSuccessResponseXML(w, encodeResponse(config))
+}
+
+// PutBucketObjectLockConfigHandler - PUT Bucket object-lock
+// -----------------
+// Enables object lock on a bucket and sets its default retention,
+// once enabled object lock can not be disabled.
+func (api objectAPIHandlers) PutBucketObjectLockConfigHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// PutBucketObjectLockConfig always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	config := objectLockConfiguration{}
+	if err := xmlDecoder(r.Body, &config, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse object lock configuration.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+	if !config.IsValid() {
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	config.XMLNS = ""
+	data, err := xml.Marshal(config)
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketObjectLockConfig, data); err != nil {
+		println(err, "Unable to save object lock configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketObjectLock.Set(bucket, &config)
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// GetObjectRetentionHandler - GET Object retention
+// -----------------
+// Returns the retention of a version of an object.
+func (api objectAPIHandlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	versionID := r.URL.Query().Get("versionId")
+
+	if _, ok := globalBucketObjectLock.Get(bucket); !ok {
+		writeErrorResponse(w, ErrInvalidBucketObjectLockConfiguration, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.GetObjectVersionInfo(bucket, object, versionID)
+	if err != nil {
+		writeErrorResponse(w, toObjectVersionAPIErrorCode(err, versionID), r.URL)
+		return
+	}
+
+	mode, retainUntil := getObjectRetention(objInfo.UserDefined)
+	if mode == "" {
+		writeErrorResponse(w, ErrNoSuchObjectLockConfiguration, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(objectRetention{
+		XMLNS:           s3XMLNamespace,
+		Mode:            mode,
+		RetainUntilDate: retainUntil.Format(time.RFC3339),
+	}))
+}
+
+// PutObjectRetentionHandler - PUT Object retention
+// -----------------
+// Sets the retention of a version of an object. Retention in effect
+// can only be extended, retention in governance mode may be shortened
+// or removed by requests bypassing governance retention.
+func (api objectAPIHandlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	if _, ok := globalBucketObjectLock.Get(bucket); !ok {
+		writeErrorResponse(w, ErrInvalidBucketObjectLockConfiguration, r.URL)
+		return
+	}
+
+	// PutObjectRetention always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	retention := objectRetention{}
+	if err := xmlDecoder(r.Body, &retention, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse object retention.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	var retainUntil time.Time
+	if retention.Mode != "" || retention.RetainUntilDate != "" {
+		if !isValidRetentionMode(retention.Mode) {
+			writeErrorResponse(w, ErrUnknownWORMModeDirective, r.URL)
+			return
+		}
+		var err error
+		if retainUntil, err = parseRetainUntilDate(retention.RetainUntilDate); err != nil {
+			writeErrorResponse(w, ErrInvalidRetentionDate, r.URL)
+			return
+		}
+		if !retainUntil.After(UTCNow()) {
+			writeErrorResponse(w, ErrPastObjectLockRetainDate, r.URL)
+			return
+		}
+	}
+
+	bypassGovernance := isGovernanceBypassRequested(r)
+	versionID := r.URL.Query().Get("versionId")
+	objInfo, err := objectAPI.PutObjectRetention(bucket, object, versionID, retention.Mode, retainUntil, bypassGovernance)
+	if err != nil {
+		println(err, "Unable to set object retention. %s", r.URL.Path)
+		writeErrorResponse(w, toObjectVersionAPIErrorCode(err, versionID), r.URL)
+		return
+	}
+
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// GetObjectLegalHoldHandler - GET Object legal-hold
+// -----------------
+// Returns the legal hold of a version of an object.
+func (api objectAPIHandlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+	versionID := r.URL.Query().Get("versionId")
+
+	if _, ok := globalBucketObjectLock.Get(bucket); !ok {
+		writeErrorResponse(w, ErrInvalidBucketObjectLockConfiguration, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.GetObjectVersionInfo(bucket, object, versionID)
+	if err != nil {
+		writeErrorResponse(w, toObjectVersionAPIErrorCode(err, versionID), r.URL)
+		return
+	}
+
+	status := objInfo.UserDefined[amzObjectLockLegalHold]
+	if status == "" {
+		writeErrorResponse(w, ErrNoSuchObjectLockConfiguration, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(objectLegalHold{
+		XMLNS:  s3XMLNamespace,
+		Status: status,
+	}))
+}
+
+// PutObjectLegalHoldHandler - PUT Object legal-hold
+// -----------------
+// Places or removes the legal hold of a version of an object, a
+// version under legal hold is not deleted regardless of retention.
+func (api objectAPIHandlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	if _, ok := globalBucketObjectLock.Get(bucket); !ok {
+		writeErrorResponse(w, ErrInvalidBucketObjectLockConfiguration, r.URL)
+		return
+	}
+
+	// PutObjectLegalHold always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	legalHold := objectLegalHold{}
+	if err := xmlDecoder(r.Body, &legalHold, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse object legal hold.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+	if legalHold.Status != legalHoldOn && legalHold.Status != legalHoldOff {
+		writeErrorResponse(w, ErrInvalidLegalHoldStatus, r.URL)
+		return
+	}
+
+	versionID := r.URL.Query().Get("versionId")
+	objInfo, err := objectAPI.PutObjectLegalHold(bucket, object, versionID, legalHold.Status)
+	if err != nil {
+		println(err, "Unable to set object legal hold. %s", r.URL.Path)
+		writeErrorResponse(w, toObjectVersionAPIErrorCode(err, versionID), r.URL)
+		return
+	}
+
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
+	writeSuccessResponseHeadersOnly(w)
+}
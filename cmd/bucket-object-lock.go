@@ -0,0 +1,303 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Bucket object lock config name.
+	bucketObjectLockConfig = "object-lock.xml"
+
+	// Object lock can only be enabled, never disabled.
+	objectLockEnabled = "Enabled"
+
+	// Retention modes, retention in governance mode may be
+	// removed or shortened by requests bypassing it.
+	retentionGovernance = "GOVERNANCE"
+	retentionCompliance = "COMPLIANCE"
+
+	// Legal hold states.
+	legalHoldOn  = "ON"
+	legalHoldOff = "OFF"
+)
+
+// Object lock related headers, the object lock of a version is saved
+// in its metadata under the same names.
+const (
+	amzObjectLockMode            = "X-Amz-Object-Lock-Mode"
+	amzObjectLockRetainUntilDate = "X-Amz-Object-Lock-Retain-Until-Date"
+	amzObjectLockLegalHold       = "X-Amz-Object-Lock-Legal-Hold"
+	amzBypassGovernanceRetention = "X-Amz-Bypass-Governance-Retention"
+)
+
+// defaultRetention - retention applied to new versions written
+// without one, for either a number of days or years.
+type defaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+// objectLockRule - rule of an object lock configuration.
+type objectLockRule struct {
+	DefaultRetention defaultRetention `xml:"DefaultRetention"`
+}
+
+// objectLockConfiguration - bucket object lock configuration.
+type objectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	XMLNS             string          `xml:"xmlns,attr,omitempty"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled"`
+	Rule              *objectLockRule `xml:"Rule,omitempty"`
+}
+
+// IsValid - returns true if object lock is enabled and the default
+// retention, if any, has a known mode and a positive period.
+func (config objectLockConfiguration) IsValid() bool {
+	if config.ObjectLockEnabled != objectLockEnabled {
+		return false
+	}
+	if config.Rule == nil {
+		return true
+	}
+	retention := config.Rule.DefaultRetention
+	if !isValidRetentionMode(retention.Mode) {
+		return false
+	}
+	// Exactly one of the days or years must be set.
+	if retention.Days < 0 || retention.Years < 0 || (retention.Days == 0) == (retention.Years == 0) {
+		return false
+	}
+	return true
+}
+
+// objectRetention - retention of a version of an object, empty to
+// remove retention in governance mode.
+type objectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	XMLNS           string   `xml:"xmlns,attr,omitempty"`
+	Mode            string   `xml:"Mode,omitempty"`
+	RetainUntilDate string   `xml:"RetainUntilDate,omitempty"`
+}
+
+// objectLegalHold - legal hold of a version of an object.
+type objectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status"`
+}
+
+// bucketObjectLock - object lock configurations of all buckets which
+// have object lock enabled.
+type bucketObjectLock struct {
+	mu      sync.RWMutex
+	configs map[string]objectLockConfiguration
+}
+
+// Global bucket object lock configurations.
+var globalBucketObjectLock = &bucketObjectLock{
+	configs: make(map[string]objectLockConfiguration),
+}
+
+// Get - returns the object lock configuration of a bucket, ok is false
+// if object lock is not enabled on the bucket.
+func (l *bucketObjectLock) Get(bucket string) (config objectLockConfiguration, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	config, ok = l.configs[bucket]
+	return config, ok
+}
+
+// Set - sets the object lock configuration of a bucket, nil removes
+// the bucket.
+func (l *bucketObjectLock) Set(bucket string, config *objectLockConfiguration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if config == nil {
+		delete(l.configs, bucket)
+		return
+	}
+	l.configs[bucket] = *config
+}
+
+// Initialize object lock configurations of all buckets.
+func initBucketObjectLock(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		config, err := readBucketObjectLockConfig(objAPI, bucket.Name)
+		if err != nil {
+			if err == errConfigNotFound {
+				continue
+			}
+			return err
+		}
+		globalBucketObjectLock.Set(bucket.Name, &config)
+	}
+	return nil
+}
+
+// readBucketObjectLockConfig - reads the object lock configuration of
+// a bucket, returns errConfigNotFound if object lock is not enabled.
+func readBucketObjectLockConfig(objAPI ObjectLayer, bucket string) (config objectLockConfiguration, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketObjectLockConfig)
+	if err != nil {
+		return config, err
+	}
+	if err = xml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// Returns true if mode is a known retention mode.
+func isValidRetentionMode(mode string) bool {
+	return mode == retentionGovernance || mode == retentionCompliance
+}
+
+// parseRetainUntilDate - parses a retain until date in ISO 8601 format.
+func parseRetainUntilDate(date string) (time.Time, error) {
+	retainUntil, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return retainUntil.UTC(), nil
+}
+
+// getObjectRetention - returns the retention saved in the metadata of
+// a version, mode is empty if the version has no retention.
+func getObjectRetention(meta map[string]string) (mode string, retainUntil time.Time) {
+	mode = meta[amzObjectLockMode]
+	if mode == "" {
+		return "", time.Time{}
+	}
+	retainUntil, err := parseRetainUntilDate(meta[amzObjectLockRetainUntilDate])
+	if err != nil {
+		// Unparsable dates never expire.
+		return mode, time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+	return mode, retainUntil
+}
+
+// isObjectLocked - returns true if the object lock saved in the
+// metadata of a version forbids removing it, retention in governance
+// mode does not if bypassGovernance is set.
+func isObjectLocked(meta map[string]string, bypassGovernance bool) bool {
+	if meta[amzObjectLockLegalHold] == legalHoldOn {
+		return true
+	}
+	mode, retainUntil := getObjectRetention(meta)
+	if mode == "" || !UTCNow().Before(retainUntil) {
+		return false
+	}
+	return mode == retentionCompliance || !bypassGovernance
+}
+
+// isObjectRetentionWeakened - returns true if replacing the retention
+// saved in the metadata of a version by mode and retainUntil would
+// remove, shorten or downgrade the retention currently in effect.
+func isObjectRetentionWeakened(meta map[string]string, mode string, retainUntil time.Time) bool {
+	curMode, curRetainUntil := getObjectRetention(meta)
+	if curMode == "" || !UTCNow().Before(curRetainUntil) {
+		return false
+	}
+	if mode == "" || retainUntil.Before(curRetainUntil) {
+		return true
+	}
+	return curMode == retentionCompliance && mode != retentionCompliance
+}
+
+// Clears the object lock saved in the metadata of a version.
+func removeObjectLockMetadata(meta map[string]string) {
+	delete(meta, amzObjectLockMode)
+	delete(meta, amzObjectLockRetainUntilDate)
+	delete(meta, amzObjectLockLegalHold)
+}
+
+// extractObjectLockMetadata - validates the object lock headers of a
+// request writing a new version of an object and saves them in
+// metadata, the default retention of the bucket applies to versions
+// written without retention.
+func extractObjectLockMetadata(bucket string, header http.Header, metadata map[string]string) APIErrorCode {
+	mode := header.Get(amzObjectLockMode)
+	retainUntilDate := header.Get(amzObjectLockRetainUntilDate)
+	legalHold := header.Get(amzObjectLockLegalHold)
+
+	config, enabled := globalBucketObjectLock.Get(bucket)
+	if !enabled {
+		if mode != "" || retainUntilDate != "" || legalHold != "" {
+			return ErrInvalidBucketObjectLockConfiguration
+		}
+		return ErrNone
+	}
+
+	if (mode == "") != (retainUntilDate == "") {
+		return ErrObjectLockInvalidHeaders
+	}
+	if mode != "" {
+		if !isValidRetentionMode(mode) {
+			return ErrUnknownWORMModeDirective
+		}
+		retainUntil, err := parseRetainUntilDate(retainUntilDate)
+		if err != nil {
+			return ErrInvalidRetentionDate
+		}
+		if !retainUntil.After(UTCNow()) {
+			return ErrPastObjectLockRetainDate
+		}
+		metadata[amzObjectLockMode] = mode
+		metadata[amzObjectLockRetainUntilDate] = retainUntil.Format(time.RFC3339)
+	} else if config.Rule != nil {
+		retention := config.Rule.DefaultRetention
+		retainUntil := UTCNow().AddDate(retention.Years, 0, retention.Days)
+		metadata[amzObjectLockMode] = retention.Mode
+		metadata[amzObjectLockRetainUntilDate] = retainUntil.Format(time.RFC3339)
+	}
+
+	switch legalHold {
+	case "":
+	case legalHoldOn, legalHoldOff:
+		metadata[amzObjectLockLegalHold] = legalHold
+	default:
+		return ErrInvalidLegalHoldStatus
+	}
+	return ErrNone
+}
+
+// isGovernanceBypassRequested - returns true if the request asks to
+// bypass retention in governance mode and is signed with the server
+// credential, the only credential allowed to bypass it.
+func isGovernanceBypassRequested(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get(amzBypassGovernanceRetention), "true") {
+		return false
+	}
+	if getRequestAuthType(r) != authTypeSigned {
+		return false
+	}
+	hashedPayload := r.Header.Get("X-Amz-Content-Sha256")
+	return doesSignatureMatch(hashedPayload, r, serverConfig.GetCredential()) == ErrNone
+}
@@ -0,0 +1,132 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+// Returns the metadata of a version with the given retention and
+// legal hold.
+func newObjectLockMeta(mode string, retainUntil time.Time, legalHold string) map[string]string {
+	meta := map[string]string{}
+	if mode != "" {
+		meta[amzObjectLockMode] = mode
+		meta[amzObjectLockRetainUntilDate] = retainUntil.Format(time.RFC3339)
+	}
+	if legalHold != "" {
+		meta[amzObjectLockLegalHold] = legalHold
+	}
+	return meta
+}
+
+func TestIsObjectLocked(t *testing.T) {
+	future := UTCNow().Add(24 * time.Hour)
+	past := UTCNow().Add(-24 * time.Hour)
+
+	testCases := []struct {
+		meta             map[string]string
+		bypassGovernance bool
+		expected         bool
+	}{
+		{nil, false, false},
+		{newObjectLockMeta("", time.Time{}, ""), false, false},
+		{newObjectLockMeta(retentionGovernance, future, ""), false, true},
+		{newObjectLockMeta(retentionGovernance, future, ""), true, false},
+		{newObjectLockMeta(retentionCompliance, future, ""), false, true},
+		{newObjectLockMeta(retentionCompliance, future, ""), true, true},
+		// Expired retention.
+		{newObjectLockMeta(retentionGovernance, past, ""), false, false},
+		{newObjectLockMeta(retentionCompliance, past, ""), false, false},
+		// Legal hold applies regardless of retention and bypass.
+		{newObjectLockMeta("", time.Time{}, legalHoldOn), true, true},
+		{newObjectLockMeta(retentionCompliance, past, legalHoldOn), true, true},
+		{newObjectLockMeta("", time.Time{}, legalHoldOff), false, false},
+		{newObjectLockMeta(retentionGovernance, future, legalHoldOff), true, false},
+		// Unparsable dates never expire.
+		{map[string]string{amzObjectLockMode: retentionCompliance, amzObjectLockRetainUntilDate: "never"}, true, true},
+	}
+	for i, testCase := range testCases {
+		if locked := isObjectLocked(testCase.meta, testCase.bypassGovernance); locked != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, locked)
+		}
+	}
+}
+
+func TestIsObjectRetentionWeakened(t *testing.T) {
+	now := UTCNow()
+	later, muchLater := now.Add(24*time.Hour), now.Add(48*time.Hour)
+	past := now.Add(-24 * time.Hour)
+
+	testCases := []struct {
+		meta        map[string]string
+		mode        string
+		retainUntil time.Time
+		expected    bool
+	}{
+		// Versions without retention in effect can get any retention.
+		{nil, "", time.Time{}, false},
+		{newObjectLockMeta(retentionCompliance, past, ""), "", time.Time{}, false},
+		{newObjectLockMeta(retentionCompliance, past, ""), retentionGovernance, later, false},
+		// Removing retention.
+		{newObjectLockMeta(retentionGovernance, later, ""), "", time.Time{}, true},
+		// Shortening retention.
+		{newObjectLockMeta(retentionGovernance, muchLater, ""), retentionGovernance, later, true},
+		{newObjectLockMeta(retentionCompliance, muchLater, ""), retentionCompliance, later, true},
+		// Extending retention.
+		{newObjectLockMeta(retentionGovernance, later, ""), retentionGovernance, muchLater, false},
+		{newObjectLockMeta(retentionCompliance, later, ""), retentionCompliance, muchLater, false},
+		// Changing the mode.
+		{newObjectLockMeta(retentionCompliance, later, ""), retentionGovernance, muchLater, true},
+		{newObjectLockMeta(retentionGovernance, later, ""), retentionCompliance, later, false},
+	}
+	for i, testCase := range testCases {
+		weakened := isObjectRetentionWeakened(testCase.meta, testCase.mode, testCase.retainUntil)
+		if weakened != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, weakened)
+		}
+	}
+}
+
+func TestObjectLockConfigurationIsValid(t *testing.T) {
+	testCases := []struct {
+		config   objectLockConfiguration
+		expected bool
+	}{
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled}, true},
+		{objectLockConfiguration{}, false},
+		{objectLockConfiguration{ObjectLockEnabled: "Disabled"}, false},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: retentionGovernance, Days: 1}}}, true},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: retentionCompliance, Years: 1}}}, true},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: "LEGAL", Days: 1}}}, false},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: retentionGovernance}}}, false},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: retentionGovernance, Days: 1, Years: 1}}}, false},
+		{objectLockConfiguration{ObjectLockEnabled: objectLockEnabled, Rule: &objectLockRule{
+			DefaultRetention: defaultRetention{Mode: retentionGovernance, Days: -1}}}, false},
+	}
+	for i, testCase := range testCases {
+		if valid := testCase.config.IsValid(); valid != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, valid)
+		}
+	}
+}
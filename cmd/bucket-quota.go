@@ -0,0 +1,190 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+const (
+	// Bucket quota config name.
+	bucketQuotaConfig = "quota.json"
+
+	// Quota types, writes exceeding a hard quota are rejected while
+	// writes exceeding a soft quota only emit an event.
+	quotaHard = "hard"
+	quotaSoft = "soft"
+
+	// API name of the audit entries of soft quota events.
+	quotaExceededAPIName = "BucketQuotaExceeded"
+)
+
+var errInvalidBucketQuota = errors.New("Bucket quota must limit bytes or objects, must not be negative and must be of type hard or soft")
+
+// bucketQuota - limits of the space and the objects of a bucket, zero
+// means no limit.
+type bucketQuota struct {
+	MaxBytes   int64  `json:"maxBytes"`
+	MaxObjects int64  `json:"maxObjects"`
+	Type       string `json:"type"`
+}
+
+// Validate - validates the limits and the type of a quota.
+func (q bucketQuota) Validate() error {
+	if q.MaxBytes < 0 || q.MaxObjects < 0 || (q.MaxBytes == 0 && q.MaxObjects == 0) {
+		return errInvalidBucketQuota
+	}
+	if q.Type != quotaHard && q.Type != quotaSoft {
+		return errInvalidBucketQuota
+	}
+	return nil
+}
+
+// isExceeded - returns true if usage grown by size and objects goes
+// beyond the quota.
+func (q bucketQuota) isExceeded(usage BucketUsage, size, objects int64) bool {
+	if q.MaxBytes > 0 && size > 0 && usage.Size+size > q.MaxBytes {
+		return true
+	}
+	return q.MaxObjects > 0 && objects > 0 && usage.Objects+objects > q.MaxObjects
+}
+
+// bucketQuotas - quotas of all buckets which have one.
+type bucketQuotas struct {
+	mu     sync.RWMutex
+	quotas map[string]bucketQuota
+}
+
+// Global bucket quotas.
+var globalBucketQuota = &bucketQuotas{
+	quotas: make(map[string]bucketQuota),
+}
+
+// Get - returns the quota of a bucket, ok is false if the bucket has
+// none.
+func (q *bucketQuotas) Get(bucket string) (quota bucketQuota, ok bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	quota, ok = q.quotas[bucket]
+	return quota, ok
+}
+
+// Set - sets the quota of a bucket, nil removes the bucket.
+func (q *bucketQuotas) Set(bucket string, quota *bucketQuota) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if quota == nil {
+		delete(q.quotas, bucket)
+		return
+	}
+	q.quotas[bucket] = *quota
+}
+
+// checkBucketQuota - checks if writing object grows the usage of its
+// bucket by size and objects beyond the quota of the bucket. Writes
+// exceeding a hard quota are rejected with BucketQuotaExceeded, writes
+// exceeding a soft quota are sent as quota events.
+func checkBucketQuota(bucket, object string, size, objects int64) error {
+	quota, ok := globalBucketQuota.Get(bucket)
+	if !ok {
+		return nil
+	}
+	usage, ok := globalBucketUsage.Get(bucket)
+	if !ok || !quota.isExceeded(usage, size, objects) {
+		return nil
+	}
+	if quota.Type == quotaHard {
+		return traceError(BucketQuotaExceeded{Bucket: bucket, Object: object})
+	}
+	sendQuotaEvent(bucket, object)
+	return nil
+}
+
+// sendQuotaEvent - records a write exceeding the soft quota of a bucket
+// in the audit log and sends it to the webhook notification targets.
+func sendQuotaEvent(bucket, object string) {
+	event := auditEntry{
+		Version: auditEntryVersion,
+		Time:    UTCNow(),
+		API:     quotaExceededAPIName,
+		Bucket:  bucket,
+		Object:  object,
+	}
+	if logger := getAuditLogger(); logger != nil {
+		logger.Log(event)
+	}
+	if notifier := getEventNotifier(); notifier != nil {
+		notifier.Log(event)
+	}
+}
+
+// putObjectQuotaUsage - returns the growth of the usage of a bucket by
+// size and objects if an object with usage is replaced by a new latest
+// version of size, the replaced version is kept in versioned buckets.
+func putObjectQuotaUsage(bucket string, usage objectUsage, size int64) (int64, int64) {
+	if size < 0 {
+		// Size is unknown until the data is written.
+		size = 0
+	}
+	if usage.latest < 0 {
+		return size, 1
+	}
+	if globalBucketVersioning.Get(bucket) == versioningEnabled {
+		return size, 0
+	}
+	return size - usage.latest, 0
+}
+
+// Initialize quotas of all buckets and the usage of the buckets which
+// have a quota.
+func initBucketQuota(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		quota, err := readBucketQuotaConfig(objAPI, bucket.Name)
+		if err != nil {
+			if err == errConfigNotFound {
+				continue
+			}
+			return err
+		}
+		if _, err = objAPI.GetBucketUsage(bucket.Name); err != nil {
+			return err
+		}
+		globalBucketQuota.Set(bucket.Name, &quota)
+	}
+	return nil
+}
+
+// readBucketQuotaConfig - reads the quota of a bucket, returns
+// errConfigNotFound if the bucket has none.
+func readBucketQuotaConfig(objAPI ObjectLayer, bucket string) (quota bucketQuota, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketQuotaConfig)
+	if err != nil {
+		return quota, err
+	}
+	if err = json.Unmarshal(data, &quota); err != nil {
+		return quota, err
+	}
+	return quota, nil
+}
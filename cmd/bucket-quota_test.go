@@ -0,0 +1,177 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "testing"
+
+// Sets the usage of a bucket as if it was crawled.
+func setTestBucketUsage(bucket string, usage BucketUsage) {
+	e := newBucketUsageEntry()
+	e.BucketUsage = usage
+	globalBucketUsage.Set(bucket, e)
+}
+
+// recordingAuditSink - keeps all the audit entries sent.
+type recordingAuditSink struct {
+	entries []auditEntry
+}
+
+func (s *recordingAuditSink) Send(entry auditEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *recordingAuditSink) Close() error {
+	return nil
+}
+
+func TestBucketQuotaValidate(t *testing.T) {
+	testCases := []struct {
+		quota       bucketQuota
+		expectedErr error
+	}{
+		{bucketQuota{MaxBytes: 1, Type: quotaHard}, nil},
+		{bucketQuota{MaxObjects: 1, Type: quotaSoft}, nil},
+		{bucketQuota{MaxBytes: 1, MaxObjects: 1, Type: quotaHard}, nil},
+		{bucketQuota{Type: quotaHard}, errInvalidBucketQuota},
+		{bucketQuota{MaxBytes: -1, MaxObjects: 1, Type: quotaHard}, errInvalidBucketQuota},
+		{bucketQuota{MaxBytes: 1, MaxObjects: -1, Type: quotaSoft}, errInvalidBucketQuota},
+		{bucketQuota{MaxBytes: 1}, errInvalidBucketQuota},
+		{bucketQuota{MaxBytes: 1, Type: "strict"}, errInvalidBucketQuota},
+	}
+	for i, testCase := range testCases {
+		if err := testCase.quota.Validate(); err != testCase.expectedErr {
+			t.Errorf("Test %d: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestBucketQuotaIsExceeded(t *testing.T) {
+	usage := BucketUsage{Size: 900, Objects: 9}
+	testCases := []struct {
+		quota    bucketQuota
+		size     int64
+		objects  int64
+		expected bool
+	}{
+		{bucketQuota{MaxBytes: 1000}, 100, 1, false},
+		{bucketQuota{MaxBytes: 1000}, 101, 0, true},
+		{bucketQuota{MaxObjects: 10}, 0, 1, false},
+		{bucketQuota{MaxObjects: 10}, 0, 2, true},
+		{bucketQuota{MaxBytes: 1000, MaxObjects: 10}, 200, 1, true},
+		// Writes not growing the usage are allowed beyond the quota.
+		{bucketQuota{MaxBytes: 500, MaxObjects: 5}, 0, 0, false},
+		{bucketQuota{MaxBytes: 500}, -100, 0, false},
+	}
+	for i, testCase := range testCases {
+		if exceeded := testCase.quota.isExceeded(usage, testCase.size, testCase.objects); exceeded != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, exceeded)
+		}
+	}
+}
+
+func TestPutObjectQuotaUsage(t *testing.T) {
+	defer globalBucketVersioning.Set("versioned", "")
+	globalBucketVersioning.Set("versioned", versioningEnabled)
+
+	testCases := []struct {
+		bucket          string
+		usage           objectUsage
+		size            int64
+		expectedSize    int64
+		expectedObjects int64
+	}{
+		// New objects.
+		{"bucket", objectUsage{latest: -1}, 100, 100, 1},
+		{"versioned", objectUsage{latest: -1}, 100, 100, 1},
+		// Size unknown until written.
+		{"bucket", objectUsage{latest: -1}, -1, 0, 1},
+		// Overwritten objects.
+		{"bucket", objectUsage{latest: 300}, 100, -200, 0},
+		{"bucket", objectUsage{latest: 100, noncurrent: []int64{50}}, 300, 200, 0},
+		// The replaced version is kept in versioned buckets.
+		{"versioned", objectUsage{latest: 300}, 100, 100, 0},
+	}
+	for i, testCase := range testCases {
+		size, objects := putObjectQuotaUsage(testCase.bucket, testCase.usage, testCase.size)
+		if size != testCase.expectedSize || objects != testCase.expectedObjects {
+			t.Errorf("Test %d: expected %d bytes and %d objects, got %d and %d",
+				i+1, testCase.expectedSize, testCase.expectedObjects, size, objects)
+		}
+	}
+}
+
+func TestCheckBucketQuota(t *testing.T) {
+	sink, notifySink := &recordingAuditSink{}, &recordingAuditSink{}
+	setAuditLogger(newAuditLogger([]auditSink{sink}, nil, nil))
+	defer setAuditLogger(nil)
+	setEventNotifier(newAuditLogger([]auditSink{notifySink}, nil, nil))
+	defer setEventNotifier(nil)
+
+	for _, bucket := range []string{"hard", "soft", "untracked"} {
+		defer globalBucketQuota.Set(bucket, nil)
+		defer globalBucketUsage.Set(bucket, nil)
+	}
+	globalBucketQuota.Set("hard", &bucketQuota{MaxBytes: 1000, MaxObjects: 10, Type: quotaHard})
+	globalBucketQuota.Set("soft", &bucketQuota{MaxBytes: 1000, Type: quotaSoft})
+	globalBucketQuota.Set("untracked", &bucketQuota{MaxBytes: 1, Type: quotaHard})
+	setTestBucketUsage("hard", BucketUsage{Size: 900, Objects: 9})
+	setTestBucketUsage("soft", BucketUsage{Size: 900, Objects: 9})
+	setTestBucketUsage("none", BucketUsage{Size: 900, Objects: 9})
+	defer globalBucketUsage.Set("none", nil)
+
+	testCases := []struct {
+		bucket        string
+		size          int64
+		objects       int64
+		shouldErr     bool
+		expectedEvent bool
+	}{
+		{"hard", 100, 1, false, false},
+		{"hard", 101, 1, true, false},
+		{"hard", 0, 2, true, false},
+		{"soft", 100, 1, false, false},
+		{"soft", 101, 1, false, true},
+		// Buckets without a quota or a known usage.
+		{"none", 1000, 1, false, false},
+		{"untracked", 1000, 1, false, false},
+	}
+	for i, testCase := range testCases {
+		sink.entries, notifySink.entries = nil, nil
+		err := checkBucketQuota(testCase.bucket, "object", testCase.size, testCase.objects)
+		if testCase.shouldErr {
+			if _, ok := errorCause(err).(BucketQuotaExceeded); !ok {
+				t.Errorf("Test %d: expected BucketQuotaExceeded, got %v", i+1, err)
+			}
+		} else if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i+1, err)
+		}
+		if sent := len(sink.entries) > 0; sent != testCase.expectedEvent {
+			t.Errorf("Test %d: expected a quota event %t, got %t", i+1, testCase.expectedEvent, sent)
+			continue
+		}
+		if len(notifySink.entries) != len(sink.entries) {
+			t.Errorf("Test %d: expected %d quota events notified, got %d", i+1, len(sink.entries), len(notifySink.entries))
+		}
+		if testCase.expectedEvent {
+			entry := sink.entries[0]
+			if entry.API != quotaExceededAPIName || entry.Bucket != testCase.bucket || entry.Object != "object" {
+				t.Errorf("Test %d: expected a quota event of %s/object, got %+v", i+1, testCase.bucket, entry)
+			}
+		}
+	}
+}
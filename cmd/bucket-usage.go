@@ -0,0 +1,406 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// BucketUsage - space and objects used by a bucket. Noncurrent
+// versions take space but are not counted as objects.
+type BucketUsage struct {
+	Size     int64 `json:"size"`
+	Objects  int64 `json:"objects"`
+	Versions int64 `json:"versions"`
+}
+
+// objectUsage - sizes of the versions of an object which have data.
+type objectUsage struct {
+	latest     int64 // -1 if the object has no latest version.
+	noncurrent []int64
+}
+
+// add - adds the usage of an object to u, sign is -1 to subtract it.
+func (u *BucketUsage) add(o objectUsage, sign int64) {
+	if o.latest >= 0 {
+		u.Size += sign * o.latest
+		u.Objects += sign
+	}
+	for _, size := range o.noncurrent {
+		u.Size += sign * size
+		u.Versions += sign
+	}
+}
+
+// objectSizeRange - range of the histogram of the sizes of objects,
+// from start up to but not including end.
+type objectSizeRange struct {
+	name       string
+	start, end int64
+}
+
+// Ranges of the histogram of the sizes of objects.
+var objectSizeRanges = []objectSizeRange{
+	{"LESS_THAN_1_KiB", 0, humanize.KiByte},
+	{"BETWEEN_1_KiB_AND_1_MiB", humanize.KiByte, humanize.MiByte},
+	{"BETWEEN_1_MiB_AND_10_MiB", humanize.MiByte, 10 * humanize.MiByte},
+	{"BETWEEN_10_MiB_AND_64_MiB", 10 * humanize.MiByte, 64 * humanize.MiByte},
+	{"BETWEEN_64_MiB_AND_128_MiB", 64 * humanize.MiByte, 128 * humanize.MiByte},
+	{"BETWEEN_128_MiB_AND_512_MiB", 128 * humanize.MiByte, 512 * humanize.MiByte},
+	{"GREATER_THAN_512_MiB", 512 * humanize.MiByte, math.MaxInt64},
+}
+
+// Returns the index of the histogram range of an object size.
+func objectSizeRangeIndex(size int64) int {
+	for i, r := range objectSizeRanges {
+		if size >= r.start && size < r.end {
+			return i
+		}
+	}
+	return len(objectSizeRanges) - 1
+}
+
+// usagePrefix - returns the top level prefix usage of an object is
+// accounted to, e.g. "team-a/" for "team-a/reports/q1.csv", empty for
+// objects at the top level of the bucket.
+func usagePrefix(object string) string {
+	if i := strings.Index(object, slashSeparator); i >= 0 {
+		return object[:i+1]
+	}
+	return ""
+}
+
+// bucketUsageEntry - usage of a bucket along with the histogram of the
+// sizes of its objects and the usage of each of its top level prefixes.
+type bucketUsageEntry struct {
+	BucketUsage
+	histogram []int64
+	prefixes  map[string]*BucketUsage
+	lastCrawl time.Time
+}
+
+func newBucketUsageEntry() *bucketUsageEntry {
+	return &bucketUsageEntry{
+		histogram: make([]int64, len(objectSizeRanges)),
+		prefixes:  make(map[string]*BucketUsage),
+	}
+}
+
+// add - adds the usage of an object to e, sign is -1 to subtract it.
+func (e *bucketUsageEntry) add(object string, o objectUsage, sign int64) {
+	e.BucketUsage.add(o, sign)
+	if o.latest >= 0 {
+		e.histogram[objectSizeRangeIndex(o.latest)] += sign
+	}
+	prefix := usagePrefix(object)
+	if prefix == "" {
+		return
+	}
+	p, ok := e.prefixes[prefix]
+	if !ok {
+		p = &BucketUsage{}
+		e.prefixes[prefix] = p
+	}
+	p.add(o, sign)
+	if *p == (BucketUsage{}) {
+		delete(e.prefixes, prefix)
+	}
+}
+
+// PrefixUsage - usage of the objects of a bucket under a top level
+// prefix.
+type PrefixUsage struct {
+	Prefix string `json:"prefix"`
+	BucketUsage
+}
+
+// BucketUsageReport - usage of a bucket with the histogram of the sizes
+// of its objects and the usage of its top level prefixes, the largest
+// prefixes first.
+type BucketUsageReport struct {
+	Bucket string `json:"bucket"`
+	BucketUsage
+	ObjectsSizesHistogram map[string]int64 `json:"objectsSizesHistogram"`
+	Prefixes              []PrefixUsage    `json:"prefixes,omitempty"`
+	LastCrawl             time.Time        `json:"lastCrawl"`
+}
+
+// report - returns the report of e with at most maxPrefixes of the
+// largest prefixes, all of them if maxPrefixes is negative.
+func (e *bucketUsageEntry) report(bucket string, maxPrefixes int) BucketUsageReport {
+	report := BucketUsageReport{
+		Bucket:                bucket,
+		BucketUsage:           e.BucketUsage,
+		ObjectsSizesHistogram: make(map[string]int64),
+		LastCrawl:             e.lastCrawl,
+	}
+	for i, r := range objectSizeRanges {
+		report.ObjectsSizesHistogram[r.name] = e.histogram[i]
+	}
+	for prefix, usage := range e.prefixes {
+		report.Prefixes = append(report.Prefixes, PrefixUsage{Prefix: prefix, BucketUsage: *usage})
+	}
+	sort.Slice(report.Prefixes, func(i, j int) bool {
+		if report.Prefixes[i].Size != report.Prefixes[j].Size {
+			return report.Prefixes[i].Size > report.Prefixes[j].Size
+		}
+		return report.Prefixes[i].Prefix < report.Prefixes[j].Prefix
+	})
+	if maxPrefixes >= 0 && len(report.Prefixes) > maxPrefixes {
+		report.Prefixes = report.Prefixes[:maxPrefixes]
+	}
+	return report
+}
+
+// newBucketUsageEntryFromReport - returns the entry of a report with
+// all the prefixes of the bucket, as saved after a crawl.
+func newBucketUsageEntryFromReport(report BucketUsageReport) *bucketUsageEntry {
+	e := newBucketUsageEntry()
+	e.BucketUsage = report.BucketUsage
+	e.lastCrawl = report.LastCrawl
+	for i, r := range objectSizeRanges {
+		e.histogram[i] = report.ObjectsSizesHistogram[r.name]
+	}
+	for _, p := range report.Prefixes {
+		usage := p.BucketUsage
+		e.prefixes[p.Prefix] = &usage
+	}
+	return e
+}
+
+// bucketUsageTracker - usage of all buckets whose usage is known, the
+// usage is computed by crawling a bucket and kept up to date by all
+// the writes and deletes of objects in between crawls.
+type bucketUsageTracker struct {
+	mu      sync.RWMutex
+	entries map[string]*bucketUsageEntry
+	crawls  map[string][]*bucketUsageCrawl
+}
+
+// Global usage of buckets.
+var globalBucketUsage = newBucketUsageTracker()
+
+func newBucketUsageTracker() *bucketUsageTracker {
+	return &bucketUsageTracker{
+		entries: make(map[string]*bucketUsageEntry),
+		crawls:  make(map[string][]*bucketUsageCrawl),
+	}
+}
+
+// objectUsageChange - change of the usage of an object made while its
+// bucket is crawled, before is the usage prior to the first change and
+// after the usage following the last one.
+type objectUsageChange struct {
+	before, after objectUsage
+
+	// Set once the crawl skipped the latest or noncurrent versions
+	// of the object as they were changed already.
+	skippedLatest, skippedNoncurrent bool
+}
+
+// bucketUsageCrawl - changes of the usage of the objects of a bucket
+// made while the bucket is crawled, the crawl walks the bucket without
+// any locks so these are replayed on its result.
+type bucketUsageCrawl struct {
+	changes map[string]*objectUsageChange
+}
+
+// Get - returns the usage of a bucket, ok is false if it is not known.
+func (t *bucketUsageTracker) Get(bucket string) (usage BucketUsage, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	e, ok := t.entries[bucket]
+	if !ok {
+		return BucketUsage{}, false
+	}
+	return e.BucketUsage, true
+}
+
+// Report - returns the report of the usage of a bucket with at most
+// maxPrefixes of its largest prefixes, ok is false if it is not known.
+func (t *bucketUsageTracker) Report(bucket string, maxPrefixes int) (report BucketUsageReport, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	e, ok := t.entries[bucket]
+	if !ok {
+		return BucketUsageReport{}, false
+	}
+	return e.report(bucket, maxPrefixes), true
+}
+
+// Reports - returns the reports of all buckets whose usage is known,
+// sorted by bucket name.
+func (t *bucketUsageTracker) Reports(maxPrefixes int) []BucketUsageReport {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	reports := make([]BucketUsageReport, 0, len(t.entries))
+	for bucket, e := range t.entries {
+		reports = append(reports, e.report(bucket, maxPrefixes))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Bucket < reports[j].Bucket
+	})
+	return reports
+}
+
+// Total - returns the number of buckets whose usage is known and
+// their total usage.
+func (t *bucketUsageTracker) Total() (buckets int, usage BucketUsage) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, e := range t.entries {
+		usage.Size += e.Size
+		usage.Objects += e.Objects
+		usage.Versions += e.Versions
+	}
+	return len(t.entries), usage
+}
+
+// Set - sets the usage of a bucket, nil forgets the bucket.
+func (t *bucketUsageTracker) Set(bucket string, e *bucketUsageEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e == nil {
+		delete(t.entries, bucket)
+		return
+	}
+	t.entries[bucket] = e
+}
+
+// IsTracked - returns true if the usage of a bucket is known.
+func (t *bucketUsageTracker) IsTracked(bucket string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.entries[bucket]
+	return ok
+}
+
+// NeedsUpdates - returns true if the usage of a bucket is known or
+// the bucket is being crawled, changes of the usage of its objects
+// must be passed to Update then.
+func (t *bucketUsageTracker) NeedsUpdates(bucket string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.entries[bucket]
+	return ok || len(t.crawls[bucket]) > 0
+}
+
+// Update - updates the usage of a bucket by the change of the usage
+// of one of its objects and records the change for all the crawls of
+// the bucket in progress, buckets whose usage is not known are ignored.
+func (t *bucketUsageTracker) Update(bucket, object string, before, after objectUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.crawls[bucket] {
+		if change, ok := c.changes[object]; ok {
+			change.after = after
+		} else {
+			c.changes[object] = &objectUsageChange{before: before, after: after}
+		}
+	}
+
+	e, ok := t.entries[bucket]
+	if !ok {
+		return
+	}
+	e.add(object, before, -1)
+	e.add(object, after, 1)
+}
+
+// StartCrawl - starts recording the changes of the usage of the
+// objects of a bucket for a crawl of the bucket, the crawl must be
+// ended by FinishCrawl.
+func (t *bucketUsageTracker) StartCrawl(bucket string) *bucketUsageCrawl {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := &bucketUsageCrawl{changes: make(map[string]*objectUsageChange)}
+	t.crawls[bucket] = append(t.crawls[bucket], c)
+	return c
+}
+
+// SkipCrawled - returns true if the latest or noncurrent versions of
+// an object were changed during a crawl, their usage is then taken
+// from the change instead of the crawl.
+func (t *bucketUsageTracker) SkipCrawled(c *bucketUsageCrawl, object string, noncurrent bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	change, ok := c.changes[object]
+	if !ok {
+		return false
+	}
+	if noncurrent {
+		change.skippedNoncurrent = true
+	} else {
+		change.skippedLatest = true
+	}
+	return true
+}
+
+// FinishCrawl - ends a crawl of a bucket and sets the usage of the
+// bucket to the usage crawled, corrected by the changes made during
+// the crawl, which is returned. A nil entry only ends the crawl.
+func (t *bucketUsageTracker) FinishCrawl(bucket string, c *bucketUsageCrawl, e *bucketUsageEntry) BucketUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	crawls := t.crawls[bucket]
+	for i := range crawls {
+		if crawls[i] == c {
+			crawls = append(crawls[:i], crawls[i+1:]...)
+			break
+		}
+	}
+	if len(crawls) == 0 {
+		delete(t.crawls, bucket)
+	} else {
+		t.crawls[bucket] = crawls
+	}
+
+	if e == nil {
+		return BucketUsage{}
+	}
+	for object, change := range c.changes {
+		// Versions not skipped were crawled before their first change.
+		crawled := objectUsage{latest: -1}
+		if !change.skippedLatest {
+			crawled.latest = change.before.latest
+		}
+		if !change.skippedNoncurrent {
+			crawled.noncurrent = change.before.noncurrent
+		}
+		e.add(object, crawled, -1)
+		e.add(object, change.after, 1)
+	}
+	t.entries[bucket] = e
+	return e.BucketUsage
+}
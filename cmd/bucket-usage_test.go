@@ -0,0 +1,83 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "testing"
+
+// Tests that the changes made while a bucket is crawled are kept once
+// the crawl is done, whether or not the crawl saw the changed objects.
+func TestBucketUsageTrackerCrawl(t *testing.T) {
+	tracker := newBucketUsageTracker()
+	tracker.Set("bucket", newBucketUsageEntry())
+	tracker.Update("bucket", "old", objectUsage{latest: -1}, objectUsage{latest: 10})
+
+	if tracker.NeedsUpdates("other") {
+		t.Fatal("Expected an unknown bucket not to need updates")
+	}
+	otherCrawl := tracker.StartCrawl("other")
+	if !tracker.NeedsUpdates("other") {
+		t.Fatal("Expected a bucket being crawled to need updates")
+	}
+	tracker.FinishCrawl("other", otherCrawl, nil)
+	if tracker.NeedsUpdates("other") || tracker.IsTracked("other") {
+		t.Fatal("Expected a failed crawl not to track the bucket")
+	}
+
+	crawl := tracker.StartCrawl("bucket")
+
+	entry := newBucketUsageEntry()
+	// Crawled before it is overwritten.
+	if tracker.SkipCrawled(crawl, "old", false) {
+		t.Fatal("Expected an unchanged object not to be skipped")
+	}
+	entry.add("old", objectUsage{latest: 10}, 1)
+	tracker.Update("bucket", "old", objectUsage{latest: 10}, objectUsage{latest: 20, noncurrent: []int64{10}})
+	if !tracker.SkipCrawled(crawl, "old", true) {
+		t.Fatal("Expected the noncurrent versions of a changed object to be skipped")
+	}
+
+	// Written before it is crawled.
+	tracker.Update("bucket", "prefix/new", objectUsage{latest: -1}, objectUsage{latest: 5})
+	if !tracker.SkipCrawled(crawl, "prefix/new", false) {
+		t.Fatal("Expected a changed object to be skipped")
+	}
+
+	// Written and deleted while crawling.
+	tracker.Update("bucket", "tmp", objectUsage{latest: -1}, objectUsage{latest: 7})
+	tracker.Update("bucket", "tmp", objectUsage{latest: 7}, objectUsage{latest: -1})
+
+	expected := BucketUsage{Size: 35, Objects: 2, Versions: 1}
+	if usage := tracker.FinishCrawl("bucket", crawl, entry); usage != expected {
+		t.Fatalf("Expected usage %+v, got %+v", expected, usage)
+	}
+	if usage, _ := tracker.Get("bucket"); usage != expected {
+		t.Fatalf("Expected usage %+v, got %+v", expected, usage)
+	}
+	report, _ := tracker.Report("bucket", -1)
+	if len(report.Prefixes) != 1 || report.Prefixes[0].Prefix != "prefix/" || report.Prefixes[0].Size != 5 {
+		t.Fatalf("Expected the usage of prefix/ to be kept, got %+v", report.Prefixes)
+	}
+
+	// Changes after the crawl only update the usage.
+	tracker.Update("bucket", "tmp", objectUsage{latest: -1}, objectUsage{latest: 1})
+	if len(crawl.changes) != 3 {
+		t.Fatalf("Expected changes after the crawl not to be recorded, got %d", len(crawl.changes))
+	}
+	if usage, _ := tracker.Get("bucket"); usage.Size != expected.Size+1 {
+		t.Fatalf("Expected size %d, got %d", expected.Size+1, usage.Size)
+	}
+}
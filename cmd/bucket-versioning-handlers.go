@@ -0,0 +1,146 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// GetBucketVersioningHandler - GET Bucket versioning
+// -----------------
+// Returns the versioning state of a bucket, an empty
+// VersioningConfiguration if versioning was never enabled.
+func (api objectAPIHandlers) GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	config, err := readBucketVersioningConfig(objectAPI, bucket)
+	if err != nil {
+		println(err, "Unable to read versioning configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	config.XMLNS = s3XMLNamespace
+
+	writeSuccessResponseXML(w, encodeResponse(config))
+}
+
+// PutBucketVersioningHandler - PUT Bucket versioning
+// -----------------
+// Enables or suspends versioning of a bucket, once enabled
+// versioning can only be suspended.
+func (api objectAPIHandlers) PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if _, err := objectAPI.GetBucketInfo(bucket); err != nil {
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	// PutBucketVersioning always needs a Content-Length.
+	if r.ContentLength <= 0 {
+		writeErrorResponse(w, ErrMissingContentLength, r.URL)
+		return
+	}
+
+	config := versioningConfiguration{}
+	if err := xmlDecoder(r.Body, &config, r.ContentLength); err != nil && err != io.EOF {
+		println(err, "Unable to parse versioning configuration.")
+		writeErrorResponse(w, ErrMalformedXML, r.URL)
+		return
+	}
+
+	if config.Status != versioningEnabled && config.Status != versioningSuspended {
+		writeErrorResponse(w, ErrIllegalVersioningConfiguration, r.URL)
+		return
+	}
+
+	data, err := xml.Marshal(versioningConfiguration{Status: config.Status})
+	if err != nil {
+		writeErrorResponse(w, ErrInternalError, r.URL)
+		return
+	}
+	if err = saveBucketConfig(objectAPI, bucket, bucketVersioningConfig, data); err != nil {
+		println(err, "Unable to save versioning configuration of bucket", bucket)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	globalBucketVersioning.Set(bucket, config.Status)
+
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// ListObjectVersionsHandler - GET Bucket versions
+// -----------------
+// Returns all versions and delete markers of the objects in a
+// bucket, the versions of each object latest first.
+func (api objectAPIHandlers) ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	//if s3Error := checkRequestAuthType(r, bucket, "s3:ListBucketVersions", serverConfig.GetRegion()); s3Error != ErrNone {
+	//	writeErrorResponse(w, s3Error, r.URL)
+	//	return
+	//}
+
+	prefix, keyMarker, versionIDMarker, delimiter, maxKeys, _ := getListObjectVersionsArgs(r.URL.Query())
+
+	// Validate all the query params before beginning to serve the request.
+	if s3Error := validateListObjectsArgs(prefix, keyMarker, delimiter, maxKeys); s3Error != ErrNone {
+		writeErrorResponse(w, s3Error, r.URL)
+		return
+	}
+
+	listVersionsInfo, err := objectAPI.ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker, delimiter, maxKeys)
+	if err != nil {
+		println(err, "Unable to list object versions.")
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+	response := generateListVersionsResponse(bucket, prefix, keyMarker, versionIDMarker, delimiter, maxKeys, listVersionsInfo)
+
+	// Write success response.
+	writeSuccessResponseXML(w, encodeResponse(response))
+}
@@ -0,0 +1,113 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/xml"
+	"sync"
+)
+
+const (
+	// Bucket versioning config name.
+	bucketVersioningConfig = "versioning.xml"
+
+	// Versioning states of a bucket, a bucket which never had
+	// versioning enabled has no state.
+	versioningEnabled   = "Enabled"
+	versioningSuspended = "Suspended"
+)
+
+// Version related headers.
+const (
+	amzVersionID           = "X-Amz-Version-Id"
+	amzDeleteMarker        = "X-Amz-Delete-Marker"
+	amzCopySourceVersionID = "X-Amz-Copy-Source-Version-Id"
+)
+
+// versioningConfiguration - bucket versioning configuration, an empty
+// status means versioning was never enabled on the bucket.
+type versioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+// bucketVersioning - versioning states of all buckets which have
+// versioning enabled or suspended.
+type bucketVersioning struct {
+	mu       sync.RWMutex
+	statuses map[string]string
+}
+
+// Global bucket versioning states.
+var globalBucketVersioning = &bucketVersioning{
+	statuses: make(map[string]string),
+}
+
+// Get - returns the versioning state of a bucket, empty if versioning
+// was never enabled on the bucket.
+func (v *bucketVersioning) Get(bucket string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.statuses[bucket]
+}
+
+// Set - sets the versioning state of a bucket, an empty state
+// removes the bucket.
+func (v *bucketVersioning) Set(bucket, status string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if status == "" {
+		delete(v.statuses, bucket)
+		return
+	}
+	v.statuses[bucket] = status
+}
+
+// Initialize versioning states of all buckets.
+func initBucketVersioning(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		config, err := readBucketVersioningConfig(objAPI, bucket.Name)
+		if err != nil {
+			return err
+		}
+		globalBucketVersioning.Set(bucket.Name, config.Status)
+	}
+	return nil
+}
+
+// readBucketVersioningConfig - reads the versioning configuration of a
+// bucket, returns an empty configuration if versioning was never enabled.
+func readBucketVersioningConfig(objAPI ObjectLayer, bucket string) (config versioningConfiguration, err error) {
+	data, err := readBucketConfig(objAPI, bucket, bucketVersioningConfig)
+	if err != nil {
+		if err == errConfigNotFound {
+			return config, nil
+		}
+		return config, err
+	}
+	if err = xml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
@@ -0,0 +1,327 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"shareos/cli"
+)
+
+const (
+	// Private key of the local CA, kept outside of the CAs directory
+	// which must only hold certificates.
+	certgenCAKeyFile = "ca.key"
+	// Certificate of the local CA inside the CAs directory.
+	certgenCACertFile = "ca.crt"
+	// Organization of the generated certificates.
+	certgenOrganization = "Minio self-signed"
+)
+
+var (
+	errCertgenNoHost       = errors.New("--host is required")
+	errCertgenKeyType      = errors.New("Invalid key type, supported types are ecdsa and rsa")
+	errCertgenECDSACurve   = errors.New("Invalid ECDSA curve, supported curves are P256, P384 and P521")
+	errCertgenInvalidValid = errors.New("--valid-for must be positive")
+)
+
+var certgenFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "host",
+		Usage: "Comma separated list of host names and IP addresses the certificate is valid for.",
+	},
+	cli.StringFlag{
+		Name:  "domain",
+		Usage: "Write the certificate into the SNI directory of this domain instead of the default certificate.",
+	},
+	cli.StringFlag{
+		Name:  "key-type",
+		Value: "ecdsa",
+		Usage: "Type of the private key: ecdsa or rsa.",
+	},
+	cli.StringFlag{
+		Name:  "ecdsa-curve",
+		Value: "P256",
+		Usage: "Curve of ECDSA keys: P256, P384 or P521.",
+	},
+	cli.IntFlag{
+		Name:  "rsa-bits",
+		Value: 2048,
+		Usage: "Size of RSA keys in bits.",
+	},
+	cli.BoolFlag{
+		Name:  "ca",
+		Usage: "Sign the certificate with a local CA, created on first use, instead of self-signing it.",
+	},
+	cli.DurationFlag{
+		Name:  "valid-for",
+		Value: 365 * 24 * time.Hour,
+		Usage: "Duration the certificate is valid for.",
+	},
+	cli.BoolFlag{
+		Name:  "force",
+		Usage: "Overwrite an existing certificate.",
+	},
+}
+
+var certgenCmd = cli.Command{
+	Name:   "certgen",
+	Usage:  "Generate a TLS certificate for development and testing.",
+	Flags:  append(certgenFlags, globalFlags...),
+	Action: certgenMain,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} {{if .VisibleFlags}}[FLAGS]{{end}}
+{{if .VisibleFlags}}
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}{{end}}
+The private key and the certificate are written into the certs directory of the
+config directory as private.key and public.crt. The certificate, or the local CA
+signing it, is added to the CAs directory so servers and clients using the same
+config directory trust it.
+
+EXAMPLES:
+  1. Generate a self-signed certificate for localhost.
+      $ {{.HelpName}} --host localhost,127.0.0.1
+
+  2. Generate a RSA certificate signed by a local CA for all the nodes of a cluster.
+      $ {{.HelpName}} --ca --key-type rsa --host node1.example.com,node2.example.com
+
+  3. Generate a certificate served to clients requesting example.com through SNI.
+      $ {{.HelpName}} --host example.com,*.example.com --domain example.com
+`,
+}
+
+// Returns the config directory set on the command line.
+func certgenConfigDir(ctx *cli.Context) string {
+	if ctx.IsSet("config-dir") {
+		return ctx.String("config-dir")
+	}
+	return ctx.GlobalString("config-dir")
+}
+
+// generatePrivateKey - generates a private key of the given type.
+func generatePrivateKey(keyType, curve string, rsaBits int) (crypto.Signer, error) {
+	switch keyType {
+	case "ecdsa":
+		var c elliptic.Curve
+		switch strings.ToUpper(curve) {
+		case "P256":
+			c = elliptic.P256()
+		case "P384":
+			c = elliptic.P384()
+		case "P521":
+			c = elliptic.P521()
+		default:
+			return nil, errCertgenECDSACurve
+		}
+		return ecdsa.GenerateKey(c, rand.Reader)
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	}
+	return nil, errCertgenKeyType
+}
+
+// newCertTemplate - returns a certificate template valid from now on
+// for the given duration.
+func newCertTemplate(commonName string, validFor time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := UTCNow()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{certgenOrganization},
+			CommonName:   commonName,
+		},
+		// Allow for clock skew between the nodes.
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		BasicConstraintsValid: true,
+	}, nil
+}
+
+// Writes key in PKCS #8 PEM encoding to keyFile, readable only by
+// the owner.
+func writePrivateKey(keyFile string, key crypto.Signer) error {
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), 0600)
+}
+
+// Writes the DER encoded certificate in PEM encoding to certFile.
+func writeCertificate(certFile string, der []byte) error {
+	return ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// loadOrCreateCA - returns the local CA of certsDir, a new CA is
+// created if there is none yet.
+func loadOrCreateCA(certsDir string, key crypto.Signer, validFor time.Duration) (*x509.Certificate, crypto.Signer, error) {
+	caKeyFile := filepath.Join(certsDir, certgenCAKeyFile)
+	caCertFile := filepath.Join(certsDir, certsCADir, certgenCACertFile)
+
+	if _, err := os.Stat(caKeyFile); err == nil {
+		ca, err := tls.LoadX509KeyPair(caCertFile, caKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to load the local CA: %s", err)
+		}
+		caCert, err := x509.ParseCertificate(ca.Certificate[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		caKey, ok := ca.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, nil, errCertgenKeyType
+		}
+		return caCert, caKey, nil
+	}
+
+	// The CA outlives the certificates it signs.
+	template, err := newCertTemplate(certgenOrganization+" CA", 10*validFor)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = writePrivateKey(caKeyFile, key); err != nil {
+		return nil, nil, err
+	}
+	if err = writeCertificate(caCertFile, der); err != nil {
+		return nil, nil, err
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Printf("Created local CA %s\n", caCertFile)
+	return caCert, key, nil
+}
+
+// certgenMain handler called for 'minio certgen' command.
+func certgenMain(ctx *cli.Context) {
+	if ctx.NArg() != 0 {
+		cli.ShowCommandHelpAndExit(ctx, "certgen", 1)
+	}
+	var hosts []string
+	for _, host := range strings.Split(ctx.String("host"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		adminFatalIf(errCertgenNoHost, "Missing host.")
+	}
+	validFor := ctx.Duration("valid-for")
+	if validFor <= 0 {
+		adminFatalIf(errCertgenInvalidValid, "Invalid validity.")
+	}
+	keyType := strings.ToLower(ctx.String("key-type"))
+	newKey := func() crypto.Signer {
+		key, err := generatePrivateKey(keyType, ctx.String("ecdsa-curve"), ctx.Int("rsa-bits"))
+		adminFatalIf(err, "Unable to generate private key.")
+		return key
+	}
+
+	if configDir := certgenConfigDir(ctx); configDir != "" {
+		configDirAbs, err := filepath.Abs(configDir)
+		adminFatalIf(err, "Unable to fetch absolute path for config directory.")
+		setConfigDir(configDirAbs)
+	}
+	certsDir := getCertsDir()
+	certDir := certsDir
+	if domain := ctx.String("domain"); domain != "" {
+		if strings.ContainsAny(domain, `/\`) || domain == certsCADir || domain == "." || domain == ".." {
+			adminFatalIf(fmt.Errorf("`%s` is not a domain name", domain), "Invalid domain.")
+		}
+		certDir = filepath.Join(certsDir, strings.ToLower(domain))
+	}
+	certFile := filepath.Join(certDir, publicCertFile)
+	keyFile := filepath.Join(certDir, privateKeyFile)
+	if _, err := os.Stat(certFile); err == nil && !ctx.Bool("force") {
+		adminFatalIf(fmt.Errorf("%s already exists, use --force to overwrite it", certFile), "Certificate exists.")
+	}
+	adminFatalIf(mkdirAll(filepath.Join(certsDir, certsCADir), 0700), "Unable to create the certs directory.")
+	adminFatalIf(mkdirAll(certDir, 0700), "Unable to create the certificate directory.")
+
+	template, err := newCertTemplate(hosts[0], validFor)
+	adminFatalIf(err, "Unable to create certificate.")
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	if keyType == "rsa" {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	// Nodes of a cluster use the same certificate as clients.
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	key := newKey()
+	parent, parentKey := template, key
+	if ctx.Bool("ca") {
+		parent, parentKey, err = loadOrCreateCA(certsDir, newKey(), validFor)
+		adminFatalIf(err, "Unable to initialize the local CA.")
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	adminFatalIf(err, "Unable to create certificate.")
+
+	adminFatalIf(writePrivateKey(keyFile, key), "Unable to write private key.")
+	adminFatalIf(writeCertificate(certFile, der), "Unable to write certificate.")
+	fmt.Printf("Created certificate %s\nCreated private key %s\n", certFile, keyFile)
+
+	// A self-signed certificate is its own CA.
+	if !ctx.Bool("ca") {
+		caName := "public.crt"
+		if certDir != certsDir {
+			caName = filepath.Base(certDir) + ".crt"
+		}
+		caFile := filepath.Join(certsDir, certsCADir, caName)
+		adminFatalIf(writeCertificate(caFile, der), "Unable to write certificate.")
+		fmt.Printf("Added certificate to %s\n", caFile)
+	}
+}
@@ -0,0 +1,304 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoCertificate = errors.New("No certificate available")
+
+const (
+	// Interval at which the certs directory is checked for changes.
+	certsReloadInterval = 30 * time.Second
+
+	// Interval at which certificate expiry warnings are repeated.
+	certsExpiryCheckInterval = 24 * time.Hour
+)
+
+// serverCert - a certificate along with the directory it was loaded from.
+type serverCert struct {
+	dir  string
+	cert *tls.Certificate
+}
+
+// loadServerCert - loads public.crt and private.key of dir, returns
+// nil if dir holds no certificate.
+func loadServerCert(dir string) (*serverCert, error) {
+	certFile := filepath.Join(dir, publicCertFile)
+	keyFile := filepath.Join(dir, privateKeyFile)
+	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load certificate from %s: %s", dir, err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("Unable to parse certificate %s: %s", certFile, err)
+	}
+	return &serverCert{dir: dir, cert: &cert}, nil
+}
+
+// certManager - serves the certificates of the certs directory. The
+// top level public.crt and private.key are the default certificate,
+// every other sub-directory except CAs holds a certificate selected
+// through SNI for its directory name and the DNS names it is valid
+// for. Certificates are reloaded when their files change.
+type certManager struct {
+	certsDir string
+
+	mu          sync.RWMutex
+	defaultCert *serverCert
+	sniCerts    map[string]*serverCert
+	certs       []*serverCert
+	fingerprint string
+}
+
+// newCertManager - loads all the certificates of certsDir, returns
+// nil if there are none.
+func newCertManager(certsDir string) (*certManager, error) {
+	m := &certManager{certsDir: certsDir}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	if len(m.certs) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// Returns the directories which may hold a certificate.
+func (m *certManager) certDirs() ([]string, error) {
+	dirs := []string{m.certsDir}
+	entries, err := ioutil.ReadDir(m.certsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != certsCADir {
+			dirs = append(dirs, filepath.Join(m.certsDir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// Returns a string which changes whenever a certificate or key file
+// of the certs directory is added, removed or modified.
+func (m *certManager) getFingerprint() (string, error) {
+	dirs, err := m.certDirs()
+	if err != nil {
+		return "", err
+	}
+	var fingerprint []string
+	for _, dir := range dirs {
+		for _, name := range []string{publicCertFile, privateKeyFile} {
+			path := filepath.Join(dir, name)
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			fingerprint = append(fingerprint, fmt.Sprintf("%s:%d:%d", path, fi.Size(), fi.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(fingerprint, ";"), nil
+}
+
+// load - loads all the certificates, the current ones are kept if
+// any certificate fails to load.
+func (m *certManager) load() error {
+	fingerprint, err := m.getFingerprint()
+	if err != nil {
+		return err
+	}
+	dirs, err := m.certDirs()
+	if err != nil {
+		return err
+	}
+
+	var defaultCert *serverCert
+	var certs []*serverCert
+	sniCerts := make(map[string]*serverCert)
+	for _, dir := range dirs {
+		cert, err := loadServerCert(dir)
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			continue
+		}
+		certs = append(certs, cert)
+		if dir == m.certsDir {
+			defaultCert = cert
+			continue
+		}
+		sniCerts[strings.ToLower(filepath.Base(dir))] = cert
+		for _, name := range cert.cert.Leaf.DNSNames {
+			sniCerts[strings.ToLower(name)] = cert
+		}
+	}
+	// Without a top level certificate clients not sending SNI get the
+	// certificate of the first domain.
+	if defaultCert == nil && len(certs) > 0 {
+		sort.Slice(certs, func(i, j int) bool { return certs[i].dir < certs[j].dir })
+		defaultCert = certs[0]
+	}
+
+	m.mu.Lock()
+	m.defaultCert = defaultCert
+	m.sniCerts = sniCerts
+	m.certs = certs
+	m.fingerprint = fingerprint
+	m.mu.Unlock()
+
+	m.checkExpiry()
+	return nil
+}
+
+// GetCertificate - returns the certificate for the server name sent
+// by the client, the default certificate otherwise.
+func (m *certManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name != "" {
+		if cert, ok := m.sniCerts[name]; ok {
+			return cert.cert, nil
+		}
+		// Look for a wildcard certificate of the parent domain.
+		if i := strings.Index(name, "."); i > 0 {
+			if cert, ok := m.sniCerts["*"+name[i:]]; ok {
+				return cert.cert, nil
+			}
+		}
+	}
+	if m.defaultCert == nil {
+		return nil, errNoCertificate
+	}
+	return m.defaultCert.cert, nil
+}
+
+// checkExpiry - warns about certificates expiring within
+// globalMinioCertExpireWarnDays.
+func (m *certManager) checkExpiry() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := UTCNow()
+	for _, cert := range m.certs {
+		leaf := cert.cert.Leaf
+		if left := leaf.NotAfter.Sub(now); left < globalMinioCertExpireWarnDays {
+			println("WARNING: Certificate", filepath.Join(cert.dir, publicCertFile),
+				"for", strings.Join(leaf.DNSNames, ","), "expires in", int(left.Hours()/24),
+				"days on", leaf.NotAfter.Format(time.RFC1123))
+		}
+	}
+}
+
+// Watch - reloads the certificates whenever their files change and
+// repeats the expiry warnings every day, it never returns.
+func (m *certManager) Watch() {
+	reloadTicker := time.NewTicker(certsReloadInterval)
+	defer reloadTicker.Stop()
+	expiryTicker := time.NewTicker(certsExpiryCheckInterval)
+	defer expiryTicker.Stop()
+
+	for {
+		select {
+		case <-expiryTicker.C:
+			m.checkExpiry()
+		case <-reloadTicker.C:
+			fingerprint, err := m.getFingerprint()
+			if err != nil {
+				println(err, "Unable to check certificates for changes.")
+				continue
+			}
+			m.mu.RLock()
+			changed := fingerprint != m.fingerprint
+			m.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err = m.load(); err != nil {
+				println(err, "Unable to reload certificates, keeping the current ones.")
+				// Retry only once the files change again.
+				m.mu.Lock()
+				m.fingerprint = fingerprint
+				m.mu.Unlock()
+				continue
+			}
+			println("Certificates reloaded.")
+		}
+	}
+}
+
+// loadRootCAs - returns the system root CAs along with all the
+// certificates of caDir, used to verify the servers this server
+// connects to. The CAs are loaded once at startup, the transports
+// built from them are not updated when caDir changes.
+func loadRootCAs(caDir string) (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		// No system CAs, e.g. on windows.
+		rootCAs = x509.NewCertPool()
+	}
+
+	entries, err := ioutil.ReadDir(caDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rootCAs, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		caFile := filepath.Join(caDir, entry.Name())
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !rootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("No PEM encoded certificate found in %s", caFile)
+		}
+	}
+	return rootCAs, nil
+}
+
+// newCustomHTTPTransport - returns a transport trusting the CAs of
+// the certs directory in addition to the system ones.
+func newCustomHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: globalRootCAs}
+	return transport
+}
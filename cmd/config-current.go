@@ -0,0 +1,346 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Config version
+const serverConfigVersion = "2"
+
+// serverConfig server config.
+var serverConfig *serverConfigV2
+
+// newServerConfig - returns a config with default values and a
+// newly generated credential.
+func newServerConfig() *serverConfigV2 {
+	return &serverConfigV2{
+		Version:    serverConfigVersion,
+		Credential: mustGetNewCredential(),
+		Region:     globalMinioDefaultRegion,
+		Browser:    true,
+		Notify: notifierConfig{
+			Webhook: make(map[string]webhookNotify),
+		},
+		Cache: cacheConfig{
+			UsageExpiry: dataUsageCrawlInterval.String(),
+		},
+	}
+}
+
+// GetVersion get current config version.
+func (s *serverConfigV2) GetVersion() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Version
+}
+
+// SetRegion set new region.
+func (s *serverConfigV2) SetRegion(region string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Region = region
+}
+
+// GetRegion get current region.
+func (s *serverConfigV2) GetRegion() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Region
+}
+
+// SetCredential sets new credential.
+func (s *serverConfigV2) SetCredential(creds credential) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Credential = creds
+}
+
+// GetCredential get current credentials.
+func (s *serverConfigV2) GetCredential() credential {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Credential
+}
+
+// SetBrowser set if browser is enabled.
+func (s *serverConfigV2) SetBrowser(b bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Browser = BrowserFlag(b)
+}
+
+// GetBrowser get current browser setting.
+func (s *serverConfigV2) GetBrowser() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return bool(s.Browser)
+}
+
+// GetAudit get current audit logging settings.
+func (s *serverConfigV2) GetAudit() auditConfig {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Logger.Audit
+}
+
+// GetNotifyWebhook get current webhook notification targets.
+func (s *serverConfigV2) GetNotifyWebhook() map[string]webhookNotify {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Notify.Webhook
+}
+
+// GetCacheUsageExpiry get the time the usage of buckets is cached for
+// between two data usage crawls.
+func (s *serverConfigV2) GetCacheUsageExpiry() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+
+	expiry, err := time.ParseDuration(s.Cache.UsageExpiry)
+	if err != nil {
+		return dataUsageCrawlInterval
+	}
+	return expiry
+}
+
+// Validate - checks all the settings of the config.
+func (s *serverConfigV2) Validate() error {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.Version != serverConfigVersion {
+		return fmt.Errorf("configuration version mismatch. Expected: ‘%s’, Got: ‘%s’", serverConfigVersion, s.Version)
+	}
+	if !s.Credential.IsValid() {
+		return errors.New("invalid credential")
+	}
+	if err := s.Logger.Audit.Validate(); err != nil {
+		return fmt.Errorf("invalid audit logger: %s", err)
+	}
+	for id, target := range s.Notify.Webhook {
+		if !target.Enable {
+			continue
+		}
+		u, err := url.Parse(target.Endpoint)
+		if err != nil || (u.Scheme != httpScheme && u.Scheme != httpsScheme) || u.Host == "" {
+			return fmt.Errorf("invalid webhook notification target ‘%s’: endpoint must be a http or https URL", id)
+		}
+	}
+	if expiry, err := time.ParseDuration(s.Cache.UsageExpiry); err != nil || expiry <= 0 {
+		return fmt.Errorf("invalid cache usage expiry ‘%s’", s.Cache.UsageExpiry)
+	}
+	return nil
+}
+
+// applyEnvOverrides - settings passed through the environment take
+// precedence over the config file.
+func (s *serverConfigV2) applyEnvOverrides() {
+	s.Lock()
+	defer s.Unlock()
+
+	if globalIsEnvCreds {
+		s.Credential = globalEnvCreds
+	}
+	if globalIsEnvRegion {
+		s.Region = globalServerRegion
+	}
+	if globalIsEnvBrowser {
+		s.Browser = BrowserFlag(globalIsBrowserEnabled)
+	}
+	if globalIsEnvAudit {
+		s.Logger.Audit = globalEnvAuditConfig
+	}
+	if globalIsEnvDataUsageCrawlInterval {
+		s.Cache.UsageExpiry = globalDataUsageCrawlInterval.String()
+	}
+}
+
+// update - replaces all the settings with the ones of srvCfg.
+func (s *serverConfigV2) update(srvCfg *serverConfigV2) {
+	srvCfg.RLock()
+	defer srvCfg.RUnlock()
+	s.Lock()
+	defer s.Unlock()
+
+	s.Version = srvCfg.Version
+	s.Credential = srvCfg.Credential
+	s.Region = srvCfg.Region
+	s.Browser = srvCfg.Browser
+	s.Logger = srvCfg.Logger
+	s.Notify = srvCfg.Notify
+	s.Cache = srvCfg.Cache
+}
+
+// Save config.
+func (s *serverConfigV2) Save() error {
+	s.RLock()
+	defer s.RUnlock()
+
+	return saveConfigFile(getConfigFile(), s)
+}
+
+// saveConfigFile - writes v as JSON to configFile, the file is
+// replaced atomically so a crash never leaves a partial config.
+func saveConfigFile(configFile string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmpFile := configFile + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, configFile)
+}
+
+// loadConfig - loads and validates config.json, which must be of the
+// current version.
+func loadConfig() (*serverConfigV2, error) {
+	data, err := ioutil.ReadFile(getConfigFile())
+	if err != nil {
+		return nil, err
+	}
+	srvCfg := &serverConfigV2{}
+	if err = json.Unmarshal(data, srvCfg); err != nil {
+		return nil, err
+	}
+	if srvCfg.Notify.Webhook == nil {
+		srvCfg.Notify.Webhook = make(map[string]webhookNotify)
+	}
+	if err = srvCfg.Validate(); err != nil {
+		return nil, err
+	}
+	return srvCfg, nil
+}
+
+// initConfig - creates config.json with default values on first
+// start, otherwise migrates and loads it. Settings passed through
+// the environment override the ones in the file.
+func initConfig() {
+	configFile := getConfigFile()
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		if err = createConfigDir(); err != nil {
+			println(err, "Unable to create configuration directory.")
+			os.Exit(1)
+		}
+		srvCfg := newServerConfig()
+		srvCfg.applyEnvOverrides()
+		if err = srvCfg.Save(); err != nil {
+			println(err, "Unable to save configuration file.")
+			os.Exit(1)
+		}
+		if !globalIsEnvCreds {
+			cred := srvCfg.GetCredential()
+			fmt.Printf("AccessKey: %s\nSecretKey: %s\n", cred.AccessKey, cred.SecretKey)
+		}
+	} else if err != nil {
+		println(err, "Unable to access configuration file.")
+		os.Exit(1)
+	}
+
+	if err := migrateConfig(); err != nil {
+		println(err, "Unable to migrate configuration file.")
+		os.Exit(1)
+	}
+
+	srvCfg, err := loadConfig()
+	if err != nil {
+		println(err, "Unable to load configuration file.")
+		os.Exit(1)
+	}
+	srvCfg.applyEnvOverrides()
+	serverConfig = srvCfg
+	globalIsBrowserEnabled = serverConfig.GetBrowser()
+	globalDataUsageCrawler.SetInterval(serverConfig.GetCacheUsageExpiry())
+
+	auditLog, err := newAuditLoggerFromConfig(serverConfig.GetAudit())
+	if err != nil {
+		// Refuse to serve any API calls which cannot be audited.
+		println(err, "Unable to initialize audit logging.")
+		os.Exit(1)
+	}
+	setAuditLogger(auditLog)
+
+	notifier, err := newEventNotifierFromConfig(serverConfig.GetNotifyWebhook())
+	if err != nil {
+		println(err, "Unable to initialize event notification.")
+		os.Exit(1)
+	}
+	setEventNotifier(notifier)
+}
+
+// reloadConfig - reloads config.json, the running config is kept
+// if the file is invalid.
+func reloadConfig() error {
+	if err := migrateConfig(); err != nil {
+		return err
+	}
+	srvCfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	srvCfg.applyEnvOverrides()
+
+	// Audit sinks and notification targets are only reopened if
+	// their settings changed.
+	auditChanged := !reflect.DeepEqual(serverConfig.GetAudit(), srvCfg.GetAudit())
+	var auditLog *auditLogger
+	if auditChanged {
+		if auditLog, err = newAuditLoggerFromConfig(srvCfg.GetAudit()); err != nil {
+			return err
+		}
+	}
+	notifyChanged := !reflect.DeepEqual(serverConfig.GetNotifyWebhook(), srvCfg.GetNotifyWebhook())
+	var notifier *auditLogger
+	if notifyChanged {
+		if notifier, err = newEventNotifierFromConfig(srvCfg.GetNotifyWebhook()); err != nil {
+			if auditLog != nil {
+				auditLog.Close()
+			}
+			return err
+		}
+	}
+
+	serverConfig.update(srvCfg)
+	globalIsBrowserEnabled = serverConfig.GetBrowser()
+	globalDataUsageCrawler.SetInterval(serverConfig.GetCacheUsageExpiry())
+	if auditChanged {
+		setAuditLogger(auditLog)
+	}
+	if notifyChanged {
+		setEventNotifier(notifier)
+	}
+	return nil
+}
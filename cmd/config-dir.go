@@ -111,6 +111,10 @@ func getConfigDir() string {
 	return configDir.Get()
 }
 
+func getCertsDir() string {
+	return configDir.getCertsDir()
+}
+
 func getCADir() string {
 	return configDir.GetCADir()
 }
@@ -0,0 +1,92 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// migrateConfig - migrates config.json to the latest version, one
+// version at a time.
+func migrateConfig() error {
+	// Migrate version '1' to '2'.
+	if err := migrateV1ToV2(); err != nil {
+		return err
+	}
+	// Add new migration functions here.
+	return nil
+}
+
+// getConfigVersion - returns the version of the config file.
+func getConfigVersion(configFile string) (string, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+	var cv struct {
+		Version string `json:"version"`
+	}
+	if err = json.Unmarshal(data, &cv); err != nil {
+		return "", err
+	}
+	return cv.Version, nil
+}
+
+// Version '1' to '2' adds the logger, notify and cache sections.
+func migrateV1ToV2() error {
+	configFile := getConfigFile()
+
+	version, err := getConfigVersion(configFile)
+	if err != nil {
+		return err
+	}
+	if version != "1" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	cv1 := &serverConfigV1{}
+	if err = json.Unmarshal(data, cv1); err != nil {
+		return fmt.Errorf("Unable to load config version ‘1’. %v", err)
+	}
+
+	// Copy over fields from V1 into V2 config struct.
+	srvConfig := &serverConfigV2{
+		Version:    "2",
+		Credential: cv1.Credential,
+		Region:     cv1.Region,
+		Browser:    cv1.Browser,
+		Notify: notifierConfig{
+			Webhook: make(map[string]webhookNotify),
+		},
+		Cache: cacheConfig{
+			UsageExpiry: dataUsageCrawlInterval.String(),
+		},
+	}
+
+	if err = saveConfigFile(configFile, srvConfig); err != nil {
+		return fmt.Errorf("Failed to migrate config from ‘%s’ to ‘%s’. %v", cv1.Version, srvConfig.Version, err)
+	}
+
+	fmt.Printf("Migration from version ‘%s’ to ‘%s’ completed successfully.\n", cv1.Version, srvConfig.Version)
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// BrowserFlag - wrapper bool type, marshaled as "on" or "off".
+type BrowserFlag bool
+
+// String - returns "on" or "off".
+func (bf BrowserFlag) String() string {
+	if bf {
+		return "on"
+	}
+	return "off"
+}
+
+// MarshalJSON - converts BrowserFlag into JSON.
+func (bf BrowserFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bf.String())
+}
+
+// UnmarshalJSON - parses "on" or "off" into BrowserFlag.
+func (bf *BrowserFlag) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	flag, err := ParseBrowserFlag(s)
+	if err != nil {
+		return err
+	}
+	*bf = flag
+	return nil
+}
+
+// ParseBrowserFlag - parses "on" or "off" into BrowserFlag.
+func ParseBrowserFlag(s string) (BrowserFlag, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("Invalid browser value `%s`, expected on or off", s)
+}
+
+// serverConfigV1 - first version of config.json.
+type serverConfigV1 struct {
+	Version string `json:"version"`
+
+	// S3 API configuration.
+	Credential credential  `json:"credential"`
+	Region     string      `json:"region"`
+	Browser    BrowserFlag `json:"browser"`
+}
+
+// loggerConfig - logging settings.
+type loggerConfig struct {
+	Audit auditConfig `json:"audit"`
+}
+
+// webhookNotify - a webhook receiving bucket event notifications.
+type webhookNotify struct {
+	Enable   bool   `json:"enable"`
+	Endpoint string `json:"endpoint"`
+}
+
+// notifierConfig - bucket event notification targets by target ID.
+type notifierConfig struct {
+	Webhook map[string]webhookNotify `json:"webhook"`
+}
+
+// cacheConfig - settings of the server side caches.
+type cacheConfig struct {
+	// Time the usage of buckets is cached for before a data usage
+	// crawl recounts it, as a duration string e.g. "1h".
+	UsageExpiry string `json:"usageExpiry"`
+}
+
+// serverConfigV2 - adds logger, notify and cache sections.
+type serverConfigV2 struct {
+	sync.RWMutex
+	Version string `json:"version"`
+
+	// S3 API configuration.
+	Credential credential  `json:"credential"`
+	Region     string      `json:"region"`
+	Browser    BrowserFlag `json:"browser"`
+
+	// Logging configuration.
+	Logger loggerConfig `json:"logger"`
+
+	// Bucket notification targets.
+	Notify notifierConfig `json:"notify"`
+
+	// Cache configuration.
+	Cache cacheConfig `json:"cache"`
+}
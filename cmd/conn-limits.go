@@ -0,0 +1,133 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Time a request body may be waited for without receiving
+// globalHTTPMinBodyRate bytes per second on average.
+const minBodyRateWindow = 30 * time.Second
+
+var (
+	errTooManyConnections = errors.New("Too many connections from the same client")
+	errSlowClient         = errors.New("Client is sending data too slowly")
+)
+
+// isTimeoutError - returns true if err is caused by an expired
+// deadline.
+func isTimeoutError(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// logSlowClient - logs a connection dropped for sending its request
+// too slowly.
+func logSlowClient(remoteAddr, reason string) {
+	println(errSlowClient, "Dropped slow client", remoteAddr, reason)
+}
+
+// ipConnCounter - counts the open connections of every client IP.
+type ipConnCounter struct {
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+var globalIPConnCounter = &ipConnCounter{conns: make(map[string]int)}
+
+// acquire - counts a new connection of ip, fails if ip already has
+// limit connections open. A limit of zero means no limit.
+func (c *ipConnCounter) acquire(ip string, limit int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if limit > 0 && c.conns[ip] >= limit {
+		return errTooManyConnections
+	}
+	c.conns[ip]++
+	return nil
+}
+
+// release - forgets a connection of ip.
+func (c *ipConnCounter) release(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[ip]--; c.conns[ip] <= 0 {
+		delete(c.conns, ip)
+	}
+}
+
+// minRateBody - request body which fails once the client sends less
+// than minRate bytes per second over minBodyRateWindow. Only the time
+// spent waiting for the client counts, a handler pausing between two
+// reads does not.
+type minRateBody struct {
+	io.ReadCloser
+	ctrl       *http.ResponseController
+	remoteAddr string
+
+	// Bytes to receive within the window.
+	minBytes int64
+	// Bytes received and time waited in the current window.
+	windowBytes int64
+	windowWait  time.Duration
+}
+
+// newMinRateBody - enforces the minimum throughput of minRate bytes
+// per second on the body of r.
+func newMinRateBody(w http.ResponseWriter, r *http.Request, minRate int64) io.ReadCloser {
+	return &minRateBody{
+		ReadCloser: r.Body,
+		ctrl:       http.NewResponseController(w),
+		remoteAddr: r.RemoteAddr,
+		minBytes:   minRate * int64(minBodyRateWindow/time.Second),
+	}
+}
+
+func (b *minRateBody) Read(p []byte) (n int, err error) {
+	start := UTCNow()
+	if err = b.ctrl.SetReadDeadline(start.Add(minBodyRateWindow - b.windowWait)); err != nil {
+		// Throughput cannot be enforced on this connection.
+		return b.ReadCloser.Read(p)
+	}
+	n, err = b.ReadCloser.Read(p)
+	b.windowWait += UTCNow().Sub(start)
+	b.windowBytes += int64(n)
+	if b.windowBytes >= b.minBytes {
+		// Fast enough, start a new window.
+		b.windowBytes, b.windowWait = 0, 0
+	}
+
+	switch {
+	case err == io.EOF:
+		b.ctrl.SetReadDeadline(time.Time{})
+	case err != nil && isTimeoutError(err):
+		logSlowClient(b.remoteAddr, "while reading the request body")
+	}
+	return n, err
+}
@@ -0,0 +1,176 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deadlineRecorder - response writer recording the read deadlines set
+// through its response controller.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadlines []time.Time
+}
+
+func (w *deadlineRecorder) SetReadDeadline(deadline time.Time) error {
+	w.deadlines = append(w.deadlines, deadline)
+	return nil
+}
+
+// scriptedRead - result of a read of a scriptedBody, returned after
+// delay.
+type scriptedRead struct {
+	n     int
+	delay time.Duration
+	err   error
+}
+
+// scriptedBody - request body returning scripted reads in turn.
+type scriptedBody struct {
+	reads []scriptedRead
+}
+
+func (b *scriptedBody) Read(p []byte) (int, error) {
+	read := b.reads[0]
+	b.reads = b.reads[1:]
+	time.Sleep(read.delay)
+	return read.n, read.err
+}
+
+func (b *scriptedBody) Close() error {
+	return nil
+}
+
+func TestIsTimeoutError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{os.ErrDeadlineExceeded, true},
+		{&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, true},
+		{io.EOF, false},
+		{errors.New("connection reset"), false},
+	}
+	for i, testCase := range testCases {
+		if timeout := isTimeoutError(testCase.err); timeout != testCase.expected {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.expected, timeout)
+		}
+	}
+}
+
+func TestIPConnCounter(t *testing.T) {
+	c := &ipConnCounter{conns: make(map[string]int)}
+	for i := 0; i < 2; i++ {
+		if err := c.acquire("10.0.0.1", 2); err != nil {
+			t.Fatalf("Expected connection %d to be accepted, got %v", i+1, err)
+		}
+	}
+	if err := c.acquire("10.0.0.1", 2); err != errTooManyConnections {
+		t.Fatalf("Expected errTooManyConnections, got %v", err)
+	}
+	if err := c.acquire("10.0.0.2", 2); err != nil {
+		t.Fatalf("Expected the connections of other clients to be accepted, got %v", err)
+	}
+	if err := c.acquire("10.0.0.1", 0); err != nil {
+		t.Fatalf("Expected no limit to accept all connections, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.release("10.0.0.1")
+	}
+	c.release("10.0.0.2")
+	if len(c.conns) != 0 {
+		t.Fatalf("Expected all connections to be forgotten, got %v", c.conns)
+	}
+}
+
+// Tests that the read deadline of a body covers the time left in the
+// current window, which restarts once enough bytes were received.
+func TestMinRateBody(t *testing.T) {
+	// 300 bytes per window.
+	const minRate = 300 / int64(minBodyRateWindow/time.Second)
+	wait := 100 * time.Millisecond
+
+	testCases := []struct {
+		read                scriptedRead
+		expectedWindowBytes int64
+		expectedWindowWait  time.Duration
+		// Time left in the window when reading.
+		expectedLeft time.Duration
+	}{
+		{scriptedRead{100, wait, nil}, 100, wait, minBodyRateWindow},
+		{scriptedRead{100, wait, nil}, 200, 2 * wait, minBodyRateWindow - wait},
+		// Fast enough, the window restarts.
+		{scriptedRead{100, 0, nil}, 0, 0, minBodyRateWindow - 2*wait},
+		{scriptedRead{50, wait, nil}, 50, wait, minBodyRateWindow},
+		{scriptedRead{0, 0, os.ErrDeadlineExceeded}, 50, wait, minBodyRateWindow - wait},
+	}
+
+	body := &scriptedBody{}
+	for _, testCase := range testCases {
+		body.reads = append(body.reads, testCase.read)
+	}
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r := httptest.NewRequest(httpPUT, "/bucket/object", nil)
+	r.Body = body
+	b := newMinRateBody(w, r, minRate).(*minRateBody)
+
+	for i, testCase := range testCases {
+		start := UTCNow()
+		n, err := b.Read(make([]byte, 1024))
+		if n != testCase.read.n || err != testCase.read.err {
+			t.Fatalf("Test %d: expected %d bytes and error %v, got %d and %v", i+1, testCase.read.n, testCase.read.err, n, err)
+		}
+		left := w.deadlines[len(w.deadlines)-1].Sub(start)
+		if diff := left - testCase.expectedLeft; diff > wait/2 || diff < -wait/2 {
+			t.Errorf("Test %d: expected a deadline %s from now, got %s", i+1, testCase.expectedLeft, left)
+		}
+		if b.windowBytes != testCase.expectedWindowBytes {
+			t.Errorf("Test %d: expected %d bytes in the window, got %d", i+1, testCase.expectedWindowBytes, b.windowBytes)
+		}
+		if diff := b.windowWait - testCase.expectedWindowWait; diff < 0 || diff > wait/2 {
+			t.Errorf("Test %d: expected %s waited in the window, got %s", i+1, testCase.expectedWindowWait, b.windowWait)
+		}
+	}
+
+	// The deadline is lifted once the whole body is read.
+	body.reads = []scriptedRead{{10, 0, io.EOF}}
+	if _, err := b.Read(make([]byte, 1024)); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+	if deadline := w.deadlines[len(w.deadlines)-1]; !deadline.IsZero() {
+		t.Fatalf("Expected the deadline to be lifted, got %s", deadline)
+	}
+}
+
+// Tests that bodies are read as is where deadlines are not supported.
+func TestMinRateBodyUnsupported(t *testing.T) {
+	r := httptest.NewRequest(httpPUT, "/bucket/object", ioutil.NopCloser(strings.NewReader("data")))
+	b := newMinRateBody(httptest.NewRecorder(), r, 1)
+	data, err := ioutil.ReadAll(b)
+	if err != nil || string(data) != "data" {
+		t.Fatalf("Expected the body to be read, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// Bucket usage config name, saved after each crawl of the bucket.
+	bucketUsageConfig = "usage.json"
+
+	// Number of the largest prefixes of a bucket reported by default.
+	dataUsageTopPrefixes = 10
+
+	// Default interval between two data usage crawls of all buckets.
+	dataUsageCrawlInterval = time.Hour
+)
+
+// DataUsageInfo - usage of buckets as of their last crawl, kept up to
+// date by the writes and deletes of objects since.
+type DataUsageInfo struct {
+	Buckets []BucketUsageReport `json:"buckets"`
+}
+
+// dataUsageCrawler - recomputes the usage of all buckets in the
+// background, which corrects any drift of the usage kept up to date by
+// writes and deletes.
+type dataUsageCrawler struct {
+	startOnce sync.Once
+
+	mu       sync.Mutex
+	interval time.Duration
+}
+
+// Global data usage crawler.
+var globalDataUsageCrawler = &dataUsageCrawler{interval: dataUsageCrawlInterval}
+
+// SetInterval - sets the time between two crawls, taken into account
+// from the next crawl on.
+func (c *dataUsageCrawler) SetInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interval = interval
+}
+
+// getInterval - returns the time between two crawls.
+func (c *dataUsageCrawler) getInterval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.interval
+}
+
+// Start - crawls all buckets right away and then every crawl interval,
+// only the first call has any effect.
+func (c *dataUsageCrawler) Start(objAPI ObjectLayer) {
+	c.startOnce.Do(func() {
+		go func() {
+			for {
+				c.Crawl(objAPI)
+				time.Sleep(c.getInterval())
+			}
+		}()
+	})
+}
+
+// Crawl - recomputes and saves the usage of all buckets once.
+func (c *dataUsageCrawler) Crawl(objAPI ObjectLayer) {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		println(err, "Unable to list buckets for data usage crawl.")
+		return
+	}
+	for _, bucket := range buckets {
+		if _, err = objAPI.CrawlBucketUsage(bucket.Name); err != nil {
+			println(err, "Unable to crawl data usage of bucket", bucket.Name)
+			continue
+		}
+		if err = saveBucketUsage(objAPI, bucket.Name); err != nil {
+			println(err, "Unable to save data usage of bucket", bucket.Name)
+		}
+	}
+}
+
+// Pauses the crawl after a directory was crawled.
+func (c *dataUsageCrawler) throttle() {
+	if globalDataUsageCrawlDelay > 0 {
+		time.Sleep(globalDataUsageCrawlDelay)
+	}
+}
+
+// saveBucketUsage - saves the usage of a bucket with all its prefixes.
+func saveBucketUsage(objAPI ObjectLayer, bucket string) error {
+	report, ok := globalBucketUsage.Report(bucket, -1)
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return saveBucketConfig(objAPI, bucket, bucketUsageConfig, data)
+}
+
+// Initialize the usage of all buckets not known yet from the usage
+// saved by their last crawl and start the data usage crawler.
+func initDataUsage(objAPI ObjectLayer) error {
+	buckets, err := objAPI.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		if globalBucketUsage.IsTracked(bucket.Name) {
+			continue
+		}
+		data, err := readBucketConfig(objAPI, bucket.Name, bucketUsageConfig)
+		if err != nil {
+			if err == errConfigNotFound {
+				continue
+			}
+			return err
+		}
+		var report BucketUsageReport
+		if err = json.Unmarshal(data, &report); err != nil {
+			// The next crawl saves it again.
+			println(err, "Unable to parse saved data usage of bucket", bucket.Name)
+			continue
+		}
+		globalBucketUsage.Set(bucket.Name, newBucketUsageEntryFromReport(report))
+	}
+	globalDataUsageCrawler.Start(objAPI)
+	return nil
+}
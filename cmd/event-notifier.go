@@ -0,0 +1,82 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// newEventNotifierFromConfig - returns a logger posting events to all
+// the enabled webhook notification targets, nil if none is enabled.
+// Events are delivered the same way as audit entries.
+func newEventNotifierFromConfig(targets map[string]webhookNotify) (*auditLogger, error) {
+	// Open the sinks in a stable order.
+	var ids []string
+	for id, target := range targets {
+		if target.Enable {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var sinks []auditSink
+	for _, id := range ids {
+		sink, err := newWebhookAuditSink(targets[id].Endpoint, 0)
+		if err != nil {
+			for _, sink := range sinks {
+				sink.Close()
+			}
+			return nil, fmt.Errorf("webhook notification target ‘%s’: %s", id, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return newAuditLogger(sinks, nil, nil), nil
+}
+
+var (
+	// Event notifier, nil when no notification target is enabled.
+	globalEventNotifier   *auditLogger
+	globalEventNotifierMu sync.RWMutex
+)
+
+// getEventNotifier - returns the active event notifier, nil when no
+// notification target is enabled.
+func getEventNotifier() *auditLogger {
+	globalEventNotifierMu.RLock()
+	defer globalEventNotifierMu.RUnlock()
+	return globalEventNotifier
+}
+
+// setEventNotifier - replaces the active event notifier, the previous
+// one is closed once its pending events are delivered.
+func setEventNotifier(n *auditLogger) {
+	globalEventNotifierMu.Lock()
+	old := globalEventNotifier
+	globalEventNotifier = n
+	globalEventNotifierMu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			println(err, "Unable to close the previous event notifier.")
+		}
+	}
+}
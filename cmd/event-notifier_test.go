@@ -0,0 +1,52 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "testing"
+
+func TestNewEventNotifierFromConfig(t *testing.T) {
+	testCases := []struct {
+		targets       map[string]webhookNotify
+		expectedSinks int
+		shouldErr     bool
+	}{
+		{nil, 0, false},
+		{map[string]webhookNotify{"1": {Enable: false, Endpoint: "http://localhost:9000"}}, 0, false},
+		{map[string]webhookNotify{
+			"1": {Enable: true, Endpoint: "http://localhost:9000"},
+			"2": {Enable: true, Endpoint: "https://localhost:9001/events"},
+			"3": {Enable: false, Endpoint: "http://localhost:9002"},
+		}, 2, false},
+		{map[string]webhookNotify{"1": {Enable: true, Endpoint: "ftp://localhost"}}, 0, true},
+	}
+	for i, testCase := range testCases {
+		notifier, err := newEventNotifierFromConfig(testCase.targets)
+		if (err != nil) != testCase.shouldErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i+1, testCase.shouldErr, err)
+		}
+		if notifier == nil {
+			if testCase.expectedSinks != 0 {
+				t.Fatalf("Test %d: expected %d sinks, got no notifier", i+1, testCase.expectedSinks)
+			}
+			continue
+		}
+		if len(notifier.sinks) != testCase.expectedSinks {
+			t.Fatalf("Test %d: expected %d sinks, got %d", i+1, testCase.expectedSinks, len(notifier.sinks))
+		}
+		notifier.Close()
+	}
+}
@@ -136,6 +136,26 @@ func (fs fsObjects) abort(uploadID string) {
 	info.abortCh <- struct{}{}
 }
 
+// Called on shutdown to end all the appendParts go-routines, their
+// append files are removed.
+func (fs fsObjects) abortAll() {
+	fs.bgAppend.Lock()
+	infos := make([]bgAppendPartsInfo, 0, len(fs.bgAppend.infoMap))
+	for uploadID, info := range fs.bgAppend.infoMap {
+		infos = append(infos, info)
+		delete(fs.bgAppend.infoMap, uploadID)
+	}
+	fs.bgAppend.Unlock()
+
+	for _, info := range infos {
+		select {
+		case info.abortCh <- struct{}{}:
+		case <-info.timeoutCh:
+			// The go-routine has already ended.
+		}
+	}
+}
+
 // This is run as a go-routine that appends the parts in the background.
 func (fs fsObjects) appendParts(bucket, object, uploadID string, info bgAppendPartsInfo) {
 	appendPath := pathJoin(fs.fsPath, minioMetaTmpBucket, fs.fsUUID, uploadID)
@@ -68,6 +68,10 @@ type fsMetaV1 struct {
 	// Metadata map for current object `fs.json`.
 	Meta  map[string]string `json:"meta,omitempty"`
 	Parts []objectPartInfo  `json:"parts,omitempty"`
+	// Version ID of the object, empty for the null version.
+	VersionID string `json:"versionId,omitempty"`
+	// Transition stub of the object, nil unless it was transitioned.
+	Transition *fsTransition `json:"transition,omitempty"`
 }
 
 // IsValid - tells if the format is sane by validating the version
@@ -99,8 +103,10 @@ func (m fsMetaV1) ToObjectInfo(bucket, object string, fi os.FileInfo) ObjectInfo
 	}
 
 	objInfo := ObjectInfo{
-		Bucket: bucket,
-		Name:   object,
+		Bucket:    bucket,
+		Name:      object,
+		VersionID: objectVersionID(bucket, m.VersionID),
+		IsLatest:  true,
 	}
 
 	// We set file info only if its valid.
@@ -111,6 +117,14 @@ func (m fsMetaV1) ToObjectInfo(bucket, object string, fi os.FileInfo) ObjectInfo
 		objInfo.IsDir = fi.IsDir()
 	}
 
+	// Transitioned objects report their storage class.
+	if m.Transition != nil {
+		objInfo.StorageClass = m.Transition.StorageClass
+		if m.Transition.isRestored() {
+			objInfo.RestoreExpiry = *m.Transition.RestoreExpiry
+		}
+	}
+
 	// Extract etag from metadata.
 	objInfo.ETag = extractETag(m.Meta)
 	objInfo.ContentType = m.Meta["content-type"]
@@ -201,6 +215,16 @@ func parseFSMetaMap(fsMetaBuf []byte) map[string]string {
 	return metaMap
 }
 
+func parseFSVersionID(fsMetaBuf []byte) string {
+	// Objects written while the bucket was not versioned,
+	// or versioning was suspended, have no version id.
+	versionIDResult := gjson.GetBytes(fsMetaBuf, "versionId")
+	if versionIDResult.Type != gjson.String {
+		return ""
+	}
+	return versionIDResult.String()
+}
+
 func parseFSParts(fsMetaBuf []byte) []objectPartInfo {
 	// Parse the FS Parts.
 	partsResult := gjson.GetBytes(fsMetaBuf, "parts").Array()
@@ -253,6 +277,12 @@ func (m *fsMetaV1) ReadFrom(lk *lock.LockedFile) (n int64, err error) {
 	// obtain minio release date.
 	m.Minio.Release = parseFSRelease(fsMetaBuf)
 
+	// obtain version id.
+	m.VersionID = parseFSVersionID(fsMetaBuf)
+
+	// obtain transition stub.
+	m.Transition = parseFSTransition(fsMetaBuf)
+
 	// Success.
 	return int64(len(fsMetaBuf)), nil
 }
@@ -351,7 +381,7 @@ func checkFormatSanityFSV2(fsPath string) error {
 			return rerr
 		}
 
-		var expectedConfigs = append(bucketMetadataConfigs, objectMetaPrefix+"/")
+		var expectedConfigs = append(bucketMetadataConfigs, objectMetaPrefix+"/", objectVersionsPrefix+"/")
 		entriesSet := set.CreateStringSet(entries...)
 		expectedConfigsSet := set.CreateStringSet(expectedConfigs...)
 
@@ -389,6 +419,18 @@ func checkFormatSanityFS(fsPath string, fsFormatVersion string) (err error) {
 func initFormatFS(fsPath, fsUUID string) (err error) {
 	fsFormatPath := pathJoin(fsPath, minioMetaBucket, fsFormatJSONFile)
 
+	// An up to date format.json only needs a shared lock to be
+	// validated, this lets a restarted process initialize while its
+	// parent still holds the read lock on format.json.
+	if rlk, rerr := lock.LockedOpenFile(preparePath(fsFormatPath), os.O_RDONLY, 0600); rerr == nil {
+		var format = &formatConfigV1{}
+		_, rerr = format.ReadFrom(rlk)
+		rlk.Close()
+		if rerr == nil && checkFormatFS(format, fsFormatVersion) == nil {
+			return checkFormatSanityFS(fsPath, format.FS.Version)
+		}
+	}
+
 	// fsFormatJSONFile - format.json file stored in minioMetaBucket(.minio.sys) directory.
 	lk, err := lock.LockedOpenFile(preparePath(fsFormatPath), os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
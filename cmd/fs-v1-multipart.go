@@ -453,6 +453,13 @@ func (fs fsObjects) PutObjectPart(bucket, object, uploadID string, partID int, s
 		return PartInfo{}, toObjectErr(err, bucket)
 	}
 
+	// Parts beyond the hard quota of the bucket are rejected.
+	if size > 0 {
+		if err := checkBucketQuota(bucket, object, size, 0); err != nil {
+			return PartInfo{}, err
+		}
+	}
+
 	// Hold the lock so that two parallel complete-multipart-uploads
 	// do not leave a stale uploads.json behind.
 	//objectMPartPathLock := globalNSMutex.NewNSLock(minioMetaMultipartBucket, pathJoin(bucket, object))
@@ -767,6 +774,27 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 	}
 	defer metaFile.Close()
 
+	// Objects under retention or legal hold are not overwritten.
+	if err = fs.checkReplacedVersionsLock(bucket, object, metaFile, false); err != nil {
+		fs.rwPool.Close(fsMetaPathMultipart)
+		return ObjectInfo{}, err
+	}
+
+	// Objects beyond the hard quota of the bucket are rejected.
+	var objectSize int64
+	for _, part := range parts {
+		if partIdx := fsMeta.ObjectPartIndex(part.PartNumber); partIdx != -1 {
+			objectSize += fsMeta.Parts[partIdx].Size
+		}
+	}
+	usage := fs.getObjectUsage(bucket, object)
+	quotaSize, quotaObjects := putObjectQuotaUsage(bucket, usage, objectSize)
+	if err = checkBucketQuota(bucket, object, quotaSize, quotaObjects); err != nil {
+		fs.rwPool.Close(fsMetaPathMultipart)
+		return ObjectInfo{}, err
+	}
+	defer fs.updateBucketUsage(bucket, object, usage)
+
 	fsNSObjPath := pathJoin(fs.fsPath, bucket, object)
 
 	// This lock is held during rename of the appended tmp file to the actual
@@ -777,6 +805,11 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 		err = fs.complete(bucket, object, uploadID, fsMeta)
 		if err == nil {
 			appendFallback = false
+			// Keep the version being replaced if the bucket is versioned.
+			if fsMeta.VersionID, err = fs.putObjectVersion(bucket, object, metaFile); err != nil {
+				fs.rwPool.Close(fsMetaPathMultipart)
+				return ObjectInfo{}, err
+			}
 			fsTmpObjPath := pathJoin(fs.fsPath, minioMetaTmpBucket, fs.fsUUID, uploadID)
 			if err = fsRenameFile(fsTmpObjPath, fsNSObjPath); err != nil {
 				fs.rwPool.Close(fsMetaPathMultipart)
@@ -858,6 +891,12 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 			reader.Close()
 		}
 
+		// Keep the version being replaced if the bucket is versioned.
+		if fsMeta.VersionID, err = fs.putObjectVersion(bucket, object, metaFile); err != nil {
+			fs.rwPool.Close(fsMetaPathMultipart)
+			return ObjectInfo{}, err
+		}
+
 		if err = fsRenameFile(fsTmpObjPath, fsNSObjPath); err != nil {
 			fs.rwPool.Close(fsMetaPathMultipart)
 			return ObjectInfo{}, toObjectErr(err, minioMetaTmpBucket, uploadID)
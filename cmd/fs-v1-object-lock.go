@@ -0,0 +1,186 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"time"
+
+	"github.com/minio/minio/pkg/lock"
+)
+
+// checkReplacedVersionsLock - returns ObjectLocked if replacing or
+// deleting the latest version of an object would permanently remove a
+// version under retention or legal hold, which is the case unless the
+// bucket has versioning enabled. Must be called with the lock on the
+// `fs.json` of the object held.
+func (fs fsObjects) checkReplacedVersionsLock(bucket, object string, metaLk *lock.LockedFile, bypassGovernance bool) error {
+	if _, ok := globalBucketObjectLock.Get(bucket); !ok {
+		return nil
+	}
+	status := globalBucketVersioning.Get(bucket)
+	if status == versioningEnabled {
+		return nil
+	}
+
+	// Latest version is replaced unless it is kept with its version ID.
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, metaLk)
+	if err != nil {
+		return err
+	}
+	if fi != nil && fsMeta.VersionID == "" && isObjectLocked(fsMeta.Meta, bypassGovernance) {
+		return traceError(ObjectLocked{Bucket: bucket, Object: object, VersionID: objectVersionID(bucket, "")})
+	}
+	if status != versioningSuspended {
+		return nil
+	}
+
+	// With versioning suspended the null version is replaced as well.
+	versionsPath := pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)
+	rlk, err := fs.rwPool.Open(versionsPath)
+	if err != nil {
+		if err == errFileNotFound {
+			return nil
+		}
+		return toObjectErr(traceError(err), bucket, object)
+	}
+	defer fs.rwPool.Close(versionsPath)
+
+	fsVersions := newFSVersionsV1()
+	if _, err = fsVersions.ReadFrom(rlk.LockedFile); err != nil && errorCause(err) != io.EOF {
+		return toObjectErr(err, bucket, object)
+	}
+	if i := indexOfVersion(fsVersions.Versions, nullVersionID); i >= 0 {
+		if isObjectLocked(fsVersions.Versions[i].Meta, bypassGovernance) {
+			return traceError(ObjectLocked{Bucket: bucket, Object: object, VersionID: nullVersionID})
+		}
+	}
+	return nil
+}
+
+// updateObjectVersionMeta - calls fn with the metadata of a version of
+// an object, the latest version if versionID is empty, and saves it.
+func (fs fsObjects) updateObjectVersionMeta(bucket, object, versionID string, fn func(meta map[string]string) error) (ObjectInfo, error) {
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Create(fsMetaPath)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, wlk)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if fi == nil {
+		// `fs.json` was only created by this call.
+		if err = fs.removeLatestMeta(bucket, object); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	if fi != nil && (fsMeta.VersionID == versionID || versionID == "" || (fsMeta.VersionID == "" && versionID == nullVersionID)) {
+		if !fsMeta.IsValid() {
+			// Pre-existing data without metadata.
+			fsMeta = newFSMetaV1()
+		}
+		if fsMeta.Meta == nil {
+			fsMeta.Meta = make(map[string]string)
+		}
+		if err = fn(fsMeta.Meta); err != nil {
+			return ObjectInfo{}, err
+		}
+		if _, err = fsMeta.WriteTo(wlk); err != nil {
+			return ObjectInfo{}, toObjectErr(err, bucket, object)
+		}
+		return fsMeta.ToObjectInfo(bucket, object, fi), nil
+	}
+	if versionID == "" {
+		return ObjectInfo{}, traceError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+
+	var objInfo ObjectInfo
+	err = fs.updateObjectVersions(bucket, object, func(versions []fsObjectVersion) ([]fsObjectVersion, error) {
+		i := indexOfVersion(versions, versionID)
+		if i < 0 {
+			return nil, traceError(VersionNotFound{Bucket: bucket, Object: object, VersionID: versionID})
+		}
+		if versions[i].DeleteMarker {
+			return nil, traceError(VersionIsDeleteMarker{Bucket: bucket, Object: object, VersionID: versionID})
+		}
+		if versions[i].Meta == nil {
+			versions[i].Meta = make(map[string]string)
+		}
+		if err := fn(versions[i].Meta); err != nil {
+			return nil, err
+		}
+		objInfo = versions[i].ToObjectInfo(bucket, object)
+		return versions, nil
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return objInfo, nil
+}
+
+// PutObjectRetention - sets the retention of a version of an object,
+// the latest version if versionID is empty. An empty mode removes the
+// retention. Retention in effect can only be extended, unless it is in
+// governance mode and bypassGovernance is set.
+func (fs fsObjects) PutObjectRetention(bucket, object, versionID, mode string, retainUntil time.Time, bypassGovernance bool) (ObjectInfo, error) {
+	if err := checkGetObjArgs(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket)
+	}
+
+	return fs.updateObjectVersionMeta(bucket, object, versionID, func(meta map[string]string) error {
+		if isObjectRetentionWeakened(meta, mode, retainUntil) {
+			if curMode, _ := getObjectRetention(meta); curMode == retentionCompliance || !bypassGovernance {
+				return traceError(ObjectLocked{Bucket: bucket, Object: object, VersionID: versionID})
+			}
+		}
+		if mode == "" {
+			delete(meta, amzObjectLockMode)
+			delete(meta, amzObjectLockRetainUntilDate)
+			return nil
+		}
+		meta[amzObjectLockMode] = mode
+		meta[amzObjectLockRetainUntilDate] = retainUntil.UTC().Format(time.RFC3339)
+		return nil
+	})
+}
+
+// PutObjectLegalHold - sets the legal hold of a version of an object,
+// the latest version if versionID is empty.
+func (fs fsObjects) PutObjectLegalHold(bucket, object, versionID, status string) (ObjectInfo, error) {
+	if err := checkGetObjArgs(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket)
+	}
+
+	return fs.updateObjectVersionMeta(bucket, object, versionID, func(meta map[string]string) error {
+		meta[amzObjectLockLegalHold] = status
+		return nil
+	})
+}
@@ -0,0 +1,355 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Objects transitioned by lifecycle rules have their data moved to the
+// lifecycle tier, a secondary storage configured by the
+// MINIO_LIFECYCLE_TIER_PATH environment variable i.e
+//
+//    <tier>/<bucket>/<uuid>
+//
+// The data at `bucket/object` is replaced by a sparse file of the same
+// size and modification time, listing and stat keep reporting the
+// object as is, while its `fs.json` keeps a transition stub pointing at
+// the data in the tier. Reads of transitioned objects are served from
+// the tier unless the object is restored, restoring copies the data
+// back for a number of days after which the lifecycle scan replaces it
+// by a sparse file again.
+//
+// Only latest versions are transitioned, they keep their stub once
+// they become noncurrent.
+
+// Returned when transitioned data is read while no tier is configured.
+var errTierNotConfigured = errors.New("Lifecycle tier is not configured")
+
+// Returned when an object due for expiry was replaced since it was listed.
+var errObjectReplaced = errors.New("Object was replaced since it was listed")
+
+// fsTransition - transition stub of a version of an object.
+type fsTransition struct {
+	// Storage class of the lifecycle rule which transitioned the version.
+	StorageClass string `json:"storageClass"`
+
+	// Path of the data in the bucket volume of the tier.
+	TierPath string `json:"tierPath"`
+
+	// Time until which the data is restored, nil if not restored.
+	RestoreExpiry *time.Time `json:"restoreExpiry,omitempty"`
+}
+
+// isRestored - returns true if the data of the version was copied back.
+func (t *fsTransition) isRestored() bool {
+	return t.RestoreExpiry != nil
+}
+
+// parseFSTransition - returns the transition stub of `fs.json`, nil if
+// the object was not transitioned.
+func parseFSTransition(fsMetaBuf []byte) *fsTransition {
+	transitionResult := gjson.GetBytes(fsMetaBuf, "transition")
+	if transitionResult.Type != gjson.JSON {
+		return nil
+	}
+	transition := &fsTransition{}
+	if err := json.Unmarshal([]byte(transitionResult.Raw), transition); err != nil {
+		return nil
+	}
+	return transition
+}
+
+// fsStubFile - replaces the content of a file by a sparse file of the
+// same size and modification time.
+func fsStubFile(filePath string, size int64, modTime time.Time) error {
+	if err := os.Truncate(preparePath(filePath), 0); err != nil {
+		return traceError(err)
+	}
+	if err := os.Truncate(preparePath(filePath), size); err != nil {
+		return traceError(err)
+	}
+	if err := os.Chtimes(preparePath(filePath), modTime, modTime); err != nil {
+		return traceError(err)
+	}
+	return nil
+}
+
+// tierPutObject - copies the file at filePath to a new file in the
+// bucket volume of the tier, returns its path.
+func tierPutObject(bucket, filePath string) (tierPath string, err error) {
+	if err = globalLifecycleTier.MakeVol(bucket); err != nil && err != errVolumeExists {
+		return "", traceError(err)
+	}
+
+	reader, _, err := fsOpenFile(filePath, 0)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	tierPath = mustGetUUID()
+	buf := make([]byte, readSizeV1)
+	for {
+		n, rerr := io.ReadFull(reader, buf)
+		if n > 0 {
+			if err = globalLifecycleTier.AppendFile(bucket, tierPath, buf[:n]); err != nil {
+				globalLifecycleTier.DeleteFile(bucket, tierPath)
+				return "", traceError(err)
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			return tierPath, nil
+		}
+		if rerr != nil {
+			globalLifecycleTier.DeleteFile(bucket, tierPath)
+			return "", traceError(rerr)
+		}
+	}
+}
+
+// tierCopyObject - writes length bytes of a file in the tier from
+// offset to writer, a negative length writes until the end.
+func tierCopyObject(bucket, object, tierPath string, offset int64, length int64, writer io.Writer) error {
+	if globalLifecycleTier == nil {
+		return toObjectErr(traceError(errTierNotConfigured), bucket, object)
+	}
+
+	fi, err := globalLifecycleTier.StatFile(bucket, tierPath)
+	if err != nil {
+		return toObjectErr(traceError(err), bucket, object)
+	}
+
+	// For negative length we read everything.
+	if length < 0 {
+		length = fi.Size - offset
+	}
+
+	// Reply back invalid range if the input offset and length fall out of range.
+	if offset > fi.Size || offset+length > fi.Size {
+		return traceError(InvalidRange{offset, length, fi.Size})
+	}
+
+	bufSize := int64(readSizeV1)
+	if length > 0 && bufSize > length {
+		bufSize = length
+	}
+	buf := make([]byte, int(bufSize))
+	for length > 0 {
+		n := bufSize
+		if n > length {
+			n = length
+		}
+		if _, err = globalLifecycleTier.ReadFile(bucket, tierPath, offset, buf[:n]); err != nil {
+			return toObjectErr(traceError(err), bucket, object)
+		}
+		if _, err = writer.Write(buf[:n]); err != nil {
+			return traceError(err)
+		}
+		offset += n
+		length -= n
+	}
+	return nil
+}
+
+// copyObjectData - writes length bytes of the data of a version from
+// offset to writer, from the tier if the version was transitioned and
+// is not restored.
+func copyObjectData(dataPath string, transition *fsTransition, offset int64, length int64, writer io.Writer, bucket, object string) error {
+	if transition != nil && !transition.isRestored() {
+		return tierCopyObject(bucket, object, transition.TierPath, offset, length, writer)
+	}
+	return fsCopyObject(dataPath, offset, length, writer, bucket, object)
+}
+
+// removeTransitionedData - removes the data of a version from the tier
+// if it was transitioned, the version itself is being removed so errors
+// are only logged.
+func removeTransitionedData(bucket string, transition *fsTransition) {
+	if transition == nil || globalLifecycleTier == nil {
+		return
+	}
+	err := globalLifecycleTier.DeleteFile(bucket, transition.TierPath)
+	if err != nil && err != errFileNotFound {
+		println(err, "Unable to remove transitioned data", bucket, transition.TierPath)
+	}
+}
+
+// TransitionObject - moves the data of the latest version of an object
+// to the lifecycle tier, provided it is still the version written at
+// modTime. A transitioned object whose restore expired is replaced by a
+// sparse file again.
+func (fs fsObjects) TransitionObject(bucket, object, storageClass string, modTime time.Time) error {
+	if err := checkGetObjArgs(bucket, object); err != nil {
+		return err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+
+	if globalLifecycleTier == nil {
+		return toObjectErr(traceError(errTierNotConfigured), bucket, object)
+	}
+
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Create(fsMetaPath)
+	if err != nil {
+		return toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, wlk)
+	if err != nil {
+		return err
+	}
+	if fi == nil {
+		// `fs.json` was only created by this call.
+		if err = fs.removeLatestMeta(bucket, object); err != nil {
+			return err
+		}
+		return traceError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	if !fi.ModTime().Equal(modTime) || fi.Size() == 0 {
+		// Object was replaced since it was listed, or has no data to move.
+		return nil
+	}
+	if !fsMeta.IsValid() {
+		// Pre-existing data without metadata.
+		fsMeta = newFSMetaV1()
+	}
+
+	objPath := pathJoin(fs.fsPath, bucket, object)
+	if fsMeta.Transition != nil {
+		if !fsMeta.Transition.isRestored() || UTCNow().Before(*fsMeta.Transition.RestoreExpiry) {
+			return nil
+		}
+		// Restore expired, the data is still in the tier.
+		fsMeta.Transition.RestoreExpiry = nil
+		if _, err = fsMeta.WriteTo(wlk); err != nil {
+			return toObjectErr(err, bucket, object)
+		}
+		return toObjectErr(fsStubFile(objPath, fi.Size(), fi.ModTime()), bucket, object)
+	}
+
+	tierPath, err := tierPutObject(bucket, objPath)
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	fsMeta.Transition = &fsTransition{
+		StorageClass: storageClass,
+		TierPath:     tierPath,
+	}
+	if _, err = fsMeta.WriteTo(wlk); err != nil {
+		globalLifecycleTier.DeleteFile(bucket, tierPath)
+		return toObjectErr(err, bucket, object)
+	}
+
+	// Reads are now served from the tier, the data can be released.
+	return toObjectErr(fsStubFile(objPath, fi.Size(), fi.ModTime()), bucket, object)
+}
+
+// RestoreObject - copies the data of the transitioned latest version of
+// an object back from the lifecycle tier for days, returns true if the
+// object was already restored in which case only the expiry of the
+// restore is updated.
+func (fs fsObjects) RestoreObject(bucket, object string, days int) (alreadyRestored bool, err error) {
+	if err = checkGetObjArgs(bucket, object); err != nil {
+		return false, err
+	}
+
+	if _, err = fs.statBucketDir(bucket); err != nil {
+		return false, toObjectErr(err, bucket)
+	}
+
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Write(fsMetaPath)
+	if err != nil {
+		if err == errFileNotFound {
+			return false, traceError(ObjectNotFound{Bucket: bucket, Object: object})
+		}
+		return false, toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, wlk)
+	if err != nil {
+		return false, err
+	}
+	if fi == nil {
+		return false, traceError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	if fsMeta.Transition == nil {
+		return false, traceError(InvalidObjectState{Bucket: bucket, Object: object})
+	}
+
+	alreadyRestored = fsMeta.Transition.isRestored()
+	if !alreadyRestored {
+		objPath := pathJoin(fs.fsPath, bucket, object)
+		if err = fs.restoreTransitionedData(bucket, object, objPath, fsMeta.Transition, fi.ModTime()); err != nil {
+			return false, err
+		}
+	}
+
+	restoreExpiry := lifecycleDueTime(UTCNow(), days)
+	fsMeta.Transition.RestoreExpiry = &restoreExpiry
+	if _, err = fsMeta.WriteTo(wlk); err != nil {
+		return false, toObjectErr(err, bucket, object)
+	}
+	return alreadyRestored, nil
+}
+
+// restoreTransitionedData - copies the data of a transitioned version
+// from the tier over its sparse file at dataPath.
+func (fs fsObjects) restoreTransitionedData(bucket, object, dataPath string, transition *fsTransition, modTime time.Time) error {
+	writer, err := os.OpenFile(preparePath(dataPath), os.O_WRONLY, 0666)
+	if err != nil {
+		return toObjectErr(traceError(err), bucket, object)
+	}
+	var startOffset int64 // Read the whole file.
+	err = tierCopyObject(bucket, object, transition.TierPath, startOffset, -1, writer)
+	if cerr := writer.Close(); err == nil && cerr != nil {
+		err = toObjectErr(traceError(cerr), bucket, object)
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chtimes(preparePath(dataPath), modTime, modTime); err != nil {
+		return toObjectErr(traceError(err), bucket, object)
+	}
+	return nil
+}
+
+// removeTier - removes the bucket volume of a deleted bucket from the
+// tier, its transitioned data was removed with its objects.
+func removeTier(bucket string) {
+	if globalLifecycleTier == nil {
+		return
+	}
+	err := globalLifecycleTier.DeleteVol(bucket)
+	if err != nil && err != errVolumeNotFound {
+		println(err, "Unable to remove lifecycle tier of bucket", bucket)
+	}
+}
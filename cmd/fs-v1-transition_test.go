@@ -0,0 +1,197 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the transition stub of the latest version of object, the
+// stub is changed to update unless nil.
+func getTestTransition(t *testing.T, fs *fsObjects, bucket, object string, update func(*fsTransition)) *fsTransition {
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Write(fsMetaPath)
+	if err != nil {
+		t.Fatalf("Unable to lock the metadata of %s/%s: %v", bucket, object, err)
+	}
+	defer wlk.Close()
+
+	fsMeta, _, err := fs.readLatestVersion(bucket, object, wlk)
+	if err != nil {
+		t.Fatalf("Unable to read the metadata of %s/%s: %v", bucket, object, err)
+	}
+	if update != nil && fsMeta.Transition != nil {
+		update(fsMeta.Transition)
+		if _, err = fsMeta.WriteTo(wlk); err != nil {
+			t.Fatalf("Unable to write the metadata of %s/%s: %v", bucket, object, err)
+		}
+	}
+	return fsMeta.Transition
+}
+
+// Checks the data kept at the path of object, zeros once the data is
+// moved to the tier.
+func checkTestObjectData(t *testing.T, fs *fsObjects, bucket, object, expected string) {
+	data, err := ioutil.ReadFile(pathJoin(fs.fsPath, bucket, object))
+	if err != nil {
+		t.Fatalf("Unable to read the data of %s/%s: %v", bucket, object, err)
+	}
+	if string(data) != expected {
+		t.Fatalf("%s/%s: expected data %q, got %q", bucket, object, expected, data)
+	}
+}
+
+func TestFSTransitionObject(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket, object, data := "bucket", "object", "hello world"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatalf("Unable to create bucket: %v", err)
+	}
+	putTestObject(t, fs, bucket, object, data)
+	objInfo, err := fs.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("Unable to stat %s/%s: %v", bucket, object, err)
+	}
+
+	defer func(tier StorageAPI) { globalLifecycleTier = tier }(globalLifecycleTier)
+	globalLifecycleTier = nil
+	err = fs.TransitionObject(bucket, object, "GLACIER", objInfo.ModTime)
+	if errorCause(err) != errTierNotConfigured {
+		t.Fatalf("Expected errTierNotConfigured, got %v", err)
+	}
+	if globalLifecycleTier, err = newPosix(t.TempDir()); err != nil {
+		t.Fatalf("Unable to initialize the tier: %v", err)
+	}
+
+	testCases := []struct {
+		object       string
+		modTime      time.Time
+		expectedErr  error
+		transitioned bool
+	}{
+		{"missing", objInfo.ModTime, ObjectNotFound{Bucket: bucket, Object: "missing"}, false},
+		// Replaced since it was listed.
+		{object, objInfo.ModTime.Add(-time.Second), nil, false},
+		{object, objInfo.ModTime, nil, true},
+		// Transitioned already.
+		{object, objInfo.ModTime, nil, true},
+	}
+	for i, testCase := range testCases {
+		err = fs.TransitionObject(bucket, testCase.object, "GLACIER", testCase.modTime)
+		if errorCause(err) != testCase.expectedErr {
+			t.Fatalf("Test %d: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		transition := getTestTransition(t, fs, bucket, object, nil)
+		if (transition != nil) != testCase.transitioned {
+			t.Fatalf("Test %d: expected transitioned %t, got %+v", i+1, testCase.transitioned, transition)
+		}
+		if !testCase.transitioned {
+			checkTestObjectData(t, fs, bucket, object, data)
+			continue
+		}
+		if transition.StorageClass != "GLACIER" || transition.isRestored() {
+			t.Fatalf("Test %d: expected a GLACIER transition not restored, got %+v", i+1, transition)
+		}
+		// The data is released but the object is reported as is.
+		checkTestObjectData(t, fs, bucket, object, strings.Repeat("\x00", len(data)))
+		if info, err := fs.GetObjectInfo(bucket, object); err != nil || info.Size != objInfo.Size || !info.ModTime.Equal(objInfo.ModTime) {
+			t.Fatalf("Test %d: expected the size and modification time to be kept, got %+v, %v", i+1, info, err)
+		}
+		checkTestObjectVersion(t, fs, bucket, object, "", data)
+	}
+}
+
+func TestFSRestoreObject(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket, object, data := "bucket", "object", "hello world"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatalf("Unable to create bucket: %v", err)
+	}
+	putTestObject(t, fs, bucket, object, data)
+	putTestObject(t, fs, bucket, "plain", data)
+	objInfo, err := fs.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("Unable to stat %s/%s: %v", bucket, object, err)
+	}
+
+	defer func(tier StorageAPI) { globalLifecycleTier = tier }(globalLifecycleTier)
+	if globalLifecycleTier, err = newPosix(t.TempDir()); err != nil {
+		t.Fatalf("Unable to initialize the tier: %v", err)
+	}
+	if err = fs.TransitionObject(bucket, object, "GLACIER", objInfo.ModTime); err != nil {
+		t.Fatalf("Unable to transition %s/%s: %v", bucket, object, err)
+	}
+
+	testCases := []struct {
+		object                  string
+		days                    int
+		expectedErr             error
+		expectedAlreadyRestored bool
+	}{
+		{"missing", 1, ObjectNotFound{Bucket: bucket, Object: "missing"}, false},
+		{"plain", 1, InvalidObjectState{Bucket: bucket, Object: "plain"}, false},
+		{object, 1, nil, false},
+		// Restoring again only extends the restore.
+		{object, 3, nil, true},
+	}
+	for i, testCase := range testCases {
+		alreadyRestored, err := fs.RestoreObject(bucket, testCase.object, testCase.days)
+		if errorCause(err) != testCase.expectedErr {
+			t.Fatalf("Test %d: expected error %v, got %v", i+1, testCase.expectedErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if alreadyRestored != testCase.expectedAlreadyRestored {
+			t.Fatalf("Test %d: expected already restored %t, got %t", i+1, testCase.expectedAlreadyRestored, alreadyRestored)
+		}
+		checkTestObjectData(t, fs, bucket, object, data)
+		transition := getTestTransition(t, fs, bucket, object, nil)
+		if expiry := lifecycleDueTime(UTCNow(), testCase.days); !transition.isRestored() || !transition.RestoreExpiry.Equal(expiry) {
+			t.Fatalf("Test %d: expected the restore to expire at %s, got %+v", i+1, expiry, transition.RestoreExpiry)
+		}
+	}
+
+	// Restored data is kept until the restore expires.
+	if err = fs.TransitionObject(bucket, object, "GLACIER", objInfo.ModTime); err != nil {
+		t.Fatalf("Unable to transition %s/%s: %v", bucket, object, err)
+	}
+	checkTestObjectData(t, fs, bucket, object, data)
+
+	getTestTransition(t, fs, bucket, object, func(transition *fsTransition) {
+		expired := UTCNow().Add(-time.Hour)
+		transition.RestoreExpiry = &expired
+	})
+	if err = fs.TransitionObject(bucket, object, "GLACIER", objInfo.ModTime); err != nil {
+		t.Fatalf("Unable to transition %s/%s: %v", bucket, object, err)
+	}
+	checkTestObjectData(t, fs, bucket, object, strings.Repeat("\x00", len(data)))
+	if transition := getTestTransition(t, fs, bucket, object, nil); transition == nil || transition.isRestored() {
+		t.Fatalf("Expected the restore to be expired, got %+v", transition)
+	}
+	var buf bytes.Buffer
+	if err = fs.GetObject(bucket, object, 0, int64(len(data)), &buf); err != nil || buf.String() != data {
+		t.Fatalf("Expected the data to be read from the tier, got %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"os"
+	pathutil "path"
+	"path/filepath"
+)
+
+// getObjectUsage - returns the sizes of the versions of an object which
+// have data. Must be called with the lock on the `fs.json` of the
+// object held.
+func (fs fsObjects) getObjectUsage(bucket, object string) objectUsage {
+	usage := objectUsage{latest: -1}
+	if fi, err := fsStatFile(pathJoin(fs.fsPath, bucket, object)); err == nil {
+		usage.latest = fi.Size()
+	}
+
+	versionsPath := pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)
+	rlk, err := fs.rwPool.Open(versionsPath)
+	if err != nil {
+		return usage
+	}
+	defer fs.rwPool.Close(versionsPath)
+
+	fsVersions := newFSVersionsV1()
+	if _, err = fsVersions.ReadFrom(rlk.LockedFile); err != nil && errorCause(err) != io.EOF {
+		return usage
+	}
+	for _, v := range fsVersions.Versions {
+		if !v.DeleteMarker {
+			usage.noncurrent = append(usage.noncurrent, v.Size)
+		}
+	}
+	return usage
+}
+
+// updateBucketUsage - updates the usage of a bucket by the change of
+// the usage of an object since before was read, deferred by all the
+// writes and deletes of objects. Must be called with the lock on the
+// `fs.json` of the object held.
+func (fs fsObjects) updateBucketUsage(bucket, object string, before objectUsage) {
+	if !globalBucketUsage.NeedsUpdates(bucket) {
+		return
+	}
+	globalBucketUsage.Update(bucket, object, before, fs.getObjectUsage(bucket, object))
+}
+
+// computeBucketUsage - walks the latest and the noncurrent versions of
+// all objects of a bucket to compute its usage, throttle is called
+// after each directory walked unless nil. Versions changed while
+// walking are skipped and accounted by the changes recorded for crawl.
+func (fs fsObjects) computeBucketUsage(bucket string, crawl *bucketUsageCrawl, throttle func()) (*bucketUsageEntry, error) {
+	entry := newBucketUsageEntry()
+
+	// Objects deleted while walking are skipped.
+	walk := func(root string, add func(object string, fi os.FileInfo)) error {
+		return filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if fi.IsDir() {
+				if throttle != nil {
+					throttle()
+				}
+				return nil
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			add(filepath.ToSlash(rel), fi)
+			return nil
+		})
+	}
+
+	err := walk(pathJoin(fs.fsPath, bucket), func(object string, fi os.FileInfo) {
+		if !globalBucketUsage.SkipCrawled(crawl, object, false) {
+			entry.add(object, objectUsage{latest: fi.Size()}, 1)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Noncurrent versions are kept next to their `fs.json` index in
+	// a directory named after the object.
+	versionsDir := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectVersionsPrefix)
+	err = walk(versionsDir, func(version string, fi os.FileInfo) {
+		if fi.Name() == fsMetaJSONFile {
+			return
+		}
+		object := pathutil.Dir(version)
+		if !globalBucketUsage.SkipCrawled(crawl, object, true) {
+			entry.add(object, objectUsage{latest: -1, noncurrent: []int64{fi.Size()}}, 1)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetBucketUsage - returns the usage of a bucket, it is computed the
+// first time it is asked for unless the bucket was crawled already.
+func (fs fsObjects) GetBucketUsage(bucket string) (BucketUsage, error) {
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return BucketUsage{}, toObjectErr(err, bucket)
+	}
+
+	if usage, ok := globalBucketUsage.Get(bucket); ok {
+		return usage, nil
+	}
+	return fs.crawlBucketUsage(bucket, nil)
+}
+
+// CrawlBucketUsage - recomputes the usage of a bucket, pausing after
+// each directory walked to limit the load on the disk.
+func (fs fsObjects) CrawlBucketUsage(bucket string) (BucketUsage, error) {
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return BucketUsage{}, toObjectErr(err, bucket)
+	}
+
+	return fs.crawlBucketUsage(bucket, globalDataUsageCrawler.throttle)
+}
+
+// crawlBucketUsage - computes the usage of a bucket and sets it along
+// with the writes and deletes of objects made while computing it.
+func (fs fsObjects) crawlBucketUsage(bucket string, throttle func()) (BucketUsage, error) {
+	crawl := globalBucketUsage.StartCrawl(bucket)
+	entry, err := fs.computeBucketUsage(bucket, crawl, throttle)
+	if err != nil {
+		globalBucketUsage.FinishCrawl(bucket, crawl, nil)
+		return BucketUsage{}, toObjectErr(traceError(err), bucket)
+	}
+	entry.lastCrawl = UTCNow()
+	return globalBucketUsage.FinishCrawl(bucket, crawl, entry), nil
+}
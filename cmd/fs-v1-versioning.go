@@ -0,0 +1,886 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	pathutil "path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio/pkg/lock"
+	"shareos/pkg/set"
+)
+
+// Noncurrent versions of objects in versioned buckets are kept in the
+// meta bucket next to the object metadata i.e
+//
+//    .minio.sys/buckets/<bucket>/versions/<object>/fs.json
+//    .minio.sys/buckets/<bucket>/versions/<object>/<version-id>
+//
+// The latest version of an object stays at `bucket/object` with its
+// version ID saved in its `fs.json`. The noncurrent versions and the
+// delete markers are listed newest first in `versions/<object>/fs.json`,
+// an object without a latest version has a delete marker as its newest
+// noncurrent version.
+//
+// All changes to the versions of an object are made while holding the
+// lock on the `fs.json` of the object, readers lock both files in the
+// same order.
+
+const (
+	// Noncurrent versions prefix in the bucket meta directory.
+	objectVersionsPrefix = "versions"
+
+	// Version ID of objects written while versioning was not enabled
+	// or suspended.
+	nullVersionID = "null"
+
+	// FS backend versions meta version.
+	fsVersionsMetaVersion = "1.0.0"
+)
+
+// fsObjectVersion - noncurrent version of an object or a delete marker.
+type fsObjectVersion struct {
+	VersionID    string            `json:"versionId"`
+	DeleteMarker bool              `json:"deleteMarker,omitempty"`
+	ModTime      time.Time         `json:"modTime"`
+	Size         int64             `json:"size,omitempty"`
+	Meta         map[string]string `json:"meta,omitempty"`
+	Transition   *fsTransition     `json:"transition,omitempty"`
+}
+
+// Converts a noncurrent version to object info.
+func (v fsObjectVersion) ToObjectInfo(bucket, object string) ObjectInfo {
+	if v.DeleteMarker {
+		return ObjectInfo{
+			Bucket:       bucket,
+			Name:         object,
+			ModTime:      v.ModTime,
+			VersionID:    v.VersionID,
+			DeleteMarker: true,
+		}
+	}
+	objInfo := fsMetaV1{Meta: v.Meta, Transition: v.Transition}.ToObjectInfo(bucket, object, nil)
+	objInfo.ModTime = v.ModTime
+	objInfo.Size = v.Size
+	objInfo.VersionID = v.VersionID
+	objInfo.IsLatest = false
+	return objInfo
+}
+
+// fsVersionsV1 - noncurrent versions of an object, newest first.
+type fsVersionsV1 struct {
+	Version  string            `json:"version"`
+	Format   string            `json:"format"`
+	Versions []fsObjectVersion `json:"versions"`
+}
+
+// Returns a new versions list.
+func newFSVersionsV1() fsVersionsV1 {
+	return fsVersionsV1{
+		Version: fsVersionsMetaVersion,
+		Format:  fsMetaFormat,
+	}
+}
+
+// Returns the index of a version in versions, -1 if not found.
+func indexOfVersion(versions []fsObjectVersion, versionID string) int {
+	for i, v := range versions {
+		if v.VersionID == versionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (v *fsVersionsV1) WriteTo(lk *lock.LockedFile) (n int64, err error) {
+	var versionsBytes []byte
+	versionsBytes, err = json.Marshal(v)
+	if err != nil {
+		return 0, traceError(err)
+	}
+
+	if err = lk.Truncate(0); err != nil {
+		return 0, traceError(err)
+	}
+
+	if _, err = lk.Write(versionsBytes); err != nil {
+		return 0, traceError(err)
+	}
+
+	// Success.
+	return int64(len(versionsBytes)), nil
+}
+
+func (v *fsVersionsV1) ReadFrom(lk *lock.LockedFile) (n int64, err error) {
+	var versionsBuf []byte
+	fi, err := lk.Stat()
+	if err != nil {
+		return 0, traceError(err)
+	}
+
+	versionsBuf, err = ioutil.ReadAll(io.NewSectionReader(lk, 0, fi.Size()))
+	if err != nil {
+		return 0, traceError(err)
+	}
+
+	if len(versionsBuf) == 0 {
+		return 0, traceError(io.EOF)
+	}
+
+	if err = json.Unmarshal(versionsBuf, v); err != nil {
+		return 0, traceError(err)
+	}
+
+	// Verify if the format is valid, return corrupted format
+	// for unrecognized formats.
+	if v.Version != fsVersionsMetaVersion || v.Format != fsMetaFormat {
+		return 0, traceError(errCorruptedFormat)
+	}
+
+	// Success.
+	return int64(len(versionsBuf)), nil
+}
+
+// objectVersionID - returns the version ID of an object saved in its
+// `fs.json` as reported to clients, objects of buckets which were never
+// versioned have none.
+func objectVersionID(bucket, versionID string) string {
+	if versionID != "" {
+		return versionID
+	}
+	if globalBucketVersioning.Get(bucket) == "" {
+		return ""
+	}
+	return nullVersionID
+}
+
+// Returns the directory of the noncurrent versions of an object.
+func (fs fsObjects) getObjectVersionsDir(bucket, object string) string {
+	return pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectVersionsPrefix, object)
+}
+
+// readLatestVersion - reads the metadata of the latest version of an
+// object from its locked `fs.json`, fi is nil if the object has no
+// latest version.
+func (fs fsObjects) readLatestVersion(bucket, object string, metaLk *lock.LockedFile) (fsMeta fsMetaV1, fi os.FileInfo, err error) {
+	if _, err = fsMeta.ReadFrom(metaLk); err != nil {
+		// `fs.json` is empty for pre-existing data and
+		// previously failed transactions.
+		if errorCause(err) != io.EOF {
+			return fsMeta, nil, toObjectErr(err, bucket, object)
+		}
+	}
+	fi, err = fsStatFile(pathJoin(fs.fsPath, bucket, object))
+	if err != nil {
+		if errorCause(err) == errFileNotFound || errorCause(err) == errFileAccessDenied {
+			return fsMeta, nil, nil
+		}
+		return fsMeta, nil, toObjectErr(err, bucket, object)
+	}
+	return fsMeta, fi, nil
+}
+
+// updateObjectVersions - calls fn with the noncurrent versions of an
+// object and saves the versions it returns, the versions list is
+// removed once no versions are left. Must be called with the lock on
+// the `fs.json` of the object held.
+func (fs fsObjects) updateObjectVersions(bucket, object string, fn func([]fsObjectVersion) ([]fsObjectVersion, error)) error {
+	versionsPath := pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)
+	wlk, err := fs.rwPool.Create(versionsPath)
+	if err != nil {
+		return toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	fsVersions := newFSVersionsV1()
+	if _, err = fsVersions.ReadFrom(wlk); err != nil && errorCause(err) != io.EOF {
+		return toObjectErr(err, bucket, object)
+	}
+
+	versions, err := fn(fsVersions.Versions)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		err = fsDeleteFile(pathJoin(fs.fsPath, minioMetaBucket), versionsPath)
+		if err != nil && errorCause(err) != errFileNotFound {
+			return toObjectErr(err, bucket, object)
+		}
+		return nil
+	}
+
+	fsVersions.Versions = versions
+	if _, err = fsVersions.WriteTo(wlk); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// keepLatestVersion - moves the latest version of an object, if any, to
+// its noncurrent versions and adds marker as the newest version unless
+// nil. With versioning suspended the null version is replaced instead
+// of kept, the caller removes it if it is the latest version. Must be
+// called with the lock on the `fs.json` of the object held.
+func (fs fsObjects) keepLatestVersion(bucket, object, status string, metaLk *lock.LockedFile, marker *fsObjectVersion) error {
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, metaLk)
+	if err != nil {
+		return err
+	}
+	if fi == nil && marker == nil && status == versioningEnabled {
+		// Nothing to keep.
+		return nil
+	}
+
+	versionsDir := fs.getObjectVersionsDir(bucket, object)
+	return fs.updateObjectVersions(bucket, object, func(versions []fsObjectVersion) ([]fsObjectVersion, error) {
+		if status == versioningSuspended {
+			if i := indexOfVersion(versions, nullVersionID); i >= 0 {
+				if !versions[i].DeleteMarker {
+					if err := fsRemoveFile(pathJoin(versionsDir, nullVersionID)); err != nil {
+						return nil, toObjectErr(err, bucket, object)
+					}
+					removeTransitionedData(bucket, versions[i].Transition)
+				}
+				versions = append(versions[:i], versions[i+1:]...)
+			}
+		}
+
+		if fi != nil && (status == versioningEnabled || fsMeta.VersionID != "") {
+			latest := fsObjectVersion{
+				VersionID:  objectVersionID(bucket, fsMeta.VersionID),
+				ModTime:    fi.ModTime(),
+				Size:       fi.Size(),
+				Meta:       fsMeta.Meta,
+				Transition: fsMeta.Transition,
+			}
+			if err := fsRenameFile(pathJoin(fs.fsPath, bucket, object), pathJoin(versionsDir, latest.VersionID)); err != nil {
+				return nil, toObjectErr(err, bucket, object)
+			}
+			versions = append([]fsObjectVersion{latest}, versions...)
+		} else if fi != nil {
+			// The null latest version is replaced, so is its data in the tier.
+			removeTransitionedData(bucket, fsMeta.Transition)
+		}
+
+		if marker != nil {
+			versions = append([]fsObjectVersion{*marker}, versions...)
+		}
+		return versions, nil
+	})
+}
+
+// putObjectVersion - prepares the versions of an object for a new
+// latest version about to be written, returns the version ID of the new
+// version. Must be called with the lock on the `fs.json` of the object
+// held.
+func (fs fsObjects) putObjectVersion(bucket, object string, metaLk *lock.LockedFile) (versionID string, err error) {
+	status := globalBucketVersioning.Get(bucket)
+	if bucket == minioMetaBucket {
+		return "", nil
+	}
+	if status == "" {
+		// The latest version is replaced, so is its data in the tier.
+		if fsMeta, _, err := fs.readLatestVersion(bucket, object, metaLk); err == nil {
+			removeTransitionedData(bucket, fsMeta.Transition)
+		}
+		return "", nil
+	}
+	if status == versioningEnabled {
+		versionID = mustGetUUID()
+	}
+	return versionID, fs.keepLatestVersion(bucket, object, status, metaLk, nil)
+}
+
+// removeLatestData - removes the data of the latest version of an
+// object, if still present, and its empty parent directories.
+func (fs fsObjects) removeLatestData(bucket, object string) error {
+	bucketDir := pathJoin(fs.fsPath, bucket)
+	objPath := pathJoin(bucketDir, object)
+	err := fsDeleteFile(bucketDir, objPath)
+	if err != nil && errorCause(err) == errFileNotFound {
+		// Data was moved to the noncurrent versions.
+		err = fsDeleteFile(bucketDir, pathutil.Dir(objPath))
+	}
+	if err != nil && errorCause(err) != errFileNotFound {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// removeLatestMeta - removes the `fs.json` of an object without a
+// latest version.
+func (fs fsObjects) removeLatestMeta(bucket, object string) error {
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	err := fsDeleteFile(pathJoin(fs.fsPath, minioMetaBucket), fsMetaPath)
+	if err != nil && errorCause(err) != errFileNotFound {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// deleteLatestVersion - replaces the latest version of an object in a
+// versioned bucket with a delete marker. Unless modTime is zero the
+// latest version is only replaced if it was last modified at modTime.
+func (fs fsObjects) deleteLatestVersion(bucket, object, status string, modTime time.Time, bypassGovernance bool) (ObjectInfo, error) {
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Create(fsMetaPath)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	if !modTime.IsZero() {
+		if err = fs.checkLatestModTime(bucket, object, wlk, modTime); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	marker := fsObjectVersion{
+		VersionID:    nullVersionID,
+		DeleteMarker: true,
+		ModTime:      UTCNow(),
+	}
+	if status == versioningEnabled {
+		marker.VersionID = mustGetUUID()
+	}
+	if err = fs.checkReplacedVersionsLock(bucket, object, wlk, bypassGovernance); err != nil {
+		return ObjectInfo{}, err
+	}
+	defer fs.updateBucketUsage(bucket, object, fs.getObjectUsage(bucket, object))
+	if err = fs.keepLatestVersion(bucket, object, status, wlk, &marker); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	// With versioning suspended a null latest version is not kept.
+	if err = fs.removeLatestData(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+	if err = fs.removeLatestMeta(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	objInfo := marker.ToObjectInfo(bucket, object)
+	objInfo.IsLatest = true
+	return objInfo, nil
+}
+
+// deleteObjectVersion - permanently deletes a version of an object, the
+// newest noncurrent version becomes the latest version if the object
+// is left without one, unless it is a delete marker. Versions under
+// retention or legal hold are not deleted.
+func (fs fsObjects) deleteObjectVersion(bucket, object, versionID string, bypassGovernance bool) (ObjectInfo, error) {
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	wlk, err := fs.rwPool.Create(fsMetaPath)
+	if err != nil {
+		return ObjectInfo{}, toObjectErr(traceError(err), bucket, object)
+	}
+	// This close will allow for locks to be synchronized on `fs.json`.
+	defer wlk.Close()
+
+	fsMeta, fi, err := fs.readLatestVersion(bucket, object, wlk)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer fs.updateBucketUsage(bucket, object, fs.getObjectUsage(bucket, object))
+
+	objInfo := ObjectInfo{
+		Bucket:    bucket,
+		Name:      object,
+		VersionID: versionID,
+	}
+
+	objPath := pathJoin(fs.fsPath, bucket, object)
+	latestDeleted := false
+	if fi != nil && (fsMeta.VersionID == versionID || (fsMeta.VersionID == "" && versionID == nullVersionID)) {
+		if isObjectLocked(fsMeta.Meta, bypassGovernance) {
+			return ObjectInfo{}, traceError(ObjectLocked{Bucket: bucket, Object: object, VersionID: versionID})
+		}
+		if err = fsRemoveFile(objPath); err != nil {
+			return ObjectInfo{}, toObjectErr(err, bucket, object)
+		}
+		removeTransitionedData(bucket, fsMeta.Transition)
+		latestDeleted = true
+		fi = nil
+	}
+
+	versionsDir := fs.getObjectVersionsDir(bucket, object)
+	var promoted *fsObjectVersion
+	err = fs.updateObjectVersions(bucket, object, func(versions []fsObjectVersion) ([]fsObjectVersion, error) {
+		if !latestDeleted {
+			i := indexOfVersion(versions, versionID)
+			if i < 0 {
+				// Deleting a version which does not exist is not an error.
+				return versions, nil
+			}
+			if isObjectLocked(versions[i].Meta, bypassGovernance) {
+				return nil, traceError(ObjectLocked{Bucket: bucket, Object: object, VersionID: versionID})
+			}
+			objInfo.DeleteMarker = versions[i].DeleteMarker
+			if !versions[i].DeleteMarker {
+				if err := fsRemoveFile(pathJoin(versionsDir, versionID)); err != nil {
+					return nil, toObjectErr(err, bucket, object)
+				}
+				removeTransitionedData(bucket, versions[i].Transition)
+			}
+			versions = append(versions[:i], versions[i+1:]...)
+		}
+
+		// Newest noncurrent version becomes the latest version.
+		if fi == nil && len(versions) > 0 && !versions[0].DeleteMarker {
+			if err := fsRenameFile(pathJoin(versionsDir, versions[0].VersionID), objPath); err != nil {
+				return nil, toObjectErr(err, bucket, object)
+			}
+			promoted = &versions[0]
+			versions = versions[1:]
+		}
+		return versions, nil
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if promoted != nil {
+		fsMeta = newFSMetaV1()
+		fsMeta.Meta = promoted.Meta
+		fsMeta.Transition = promoted.Transition
+		if promoted.VersionID != nullVersionID {
+			fsMeta.VersionID = promoted.VersionID
+		}
+		if _, err = fsMeta.WriteTo(wlk); err != nil {
+			return ObjectInfo{}, toObjectErr(err, bucket, object)
+		}
+		return objInfo, nil
+	}
+
+	if fi == nil {
+		if err = fs.removeLatestData(bucket, object); err != nil {
+			return ObjectInfo{}, err
+		}
+		if err = fs.removeLatestMeta(bucket, object); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	return objInfo, nil
+}
+
+// DeleteObjectVersion - permanently deletes a version of an object. If
+// versionID is empty the latest version is deleted, in versioned
+// buckets by adding a delete marker as the latest version. Returns the
+// version ID of the deleted version or of the added delete marker.
+// Versions under retention in governance mode are only deleted if
+// bypassGovernance is set.
+func (fs fsObjects) DeleteObjectVersion(bucket, object, versionID string, bypassGovernance bool) (ObjectInfo, error) {
+	if err := checkDelObjArgs(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket)
+	}
+
+	if bucket != minioMetaBucket {
+		if versionID != "" {
+			return fs.deleteObjectVersion(bucket, object, versionID, bypassGovernance)
+		}
+		if status := globalBucketVersioning.Get(bucket); status != "" {
+			return fs.deleteLatestVersion(bucket, object, status, time.Time{}, bypassGovernance)
+		}
+	}
+
+	if err := fs.deleteObject(bucket, object, time.Time{}, bypassGovernance); err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Bucket: bucket, Name: object}, nil
+}
+
+// checkLatestModTime - returns errObjectReplaced unless the latest
+// version of an object was last modified at modTime, ObjectNotFound if
+// the object has no latest version. Must be called with the lock on
+// the `fs.json` of the object held.
+func (fs fsObjects) checkLatestModTime(bucket, object string, metaLk *lock.LockedFile, modTime time.Time) error {
+	_, fi, err := fs.readLatestVersion(bucket, object, metaLk)
+	if err != nil {
+		return err
+	}
+	if fi == nil {
+		// `fs.json` may only have been created by the caller.
+		if err = fs.removeLatestMeta(bucket, object); err != nil {
+			return err
+		}
+		return traceError(ObjectNotFound{Bucket: bucket, Object: object})
+	}
+	if !fi.ModTime().Equal(modTime) {
+		return traceError(errObjectReplaced)
+	}
+	return nil
+}
+
+// ExpireObject - deletes the latest version of an object expired by a
+// lifecycle rule, in versioned buckets by adding a delete marker. The
+// object is left alone if it was replaced since it was listed with
+// modTime, objects under retention or legal hold are not deleted.
+func (fs fsObjects) ExpireObject(bucket, object string, modTime time.Time) error {
+	if err := checkDelObjArgs(bucket, object); err != nil {
+		return err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+
+	var err error
+	if status := globalBucketVersioning.Get(bucket); status != "" {
+		_, err = fs.deleteLatestVersion(bucket, object, status, modTime, false)
+	} else {
+		err = fs.deleteObject(bucket, object, modTime, false)
+	}
+	if errorCause(err) == errObjectReplaced {
+		return nil
+	}
+	return err
+}
+
+// getObjectVersion - looks up a version of an object, returns its info,
+// the path of its data and its transition stub if it was transitioned.
+// The object stays read locked until unlock is called, which must be
+// called on success.
+func (fs fsObjects) getObjectVersion(bucket, object, versionID string) (objInfo ObjectInfo, dataPath string, transition *fsTransition, unlock func(), err error) {
+	var lockedPaths []string
+	unlock = func() {
+		for _, lockedPath := range lockedPaths {
+			fs.rwPool.Close(lockedPath)
+		}
+	}
+
+	fsMeta := fsMetaV1{}
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	rlk, err := fs.rwPool.Open(fsMetaPath)
+	if err == nil {
+		lockedPaths = append(lockedPaths, fsMetaPath)
+		if _, rerr := fsMeta.ReadFrom(rlk.LockedFile); rerr != nil && errorCause(rerr) != io.EOF {
+			unlock()
+			return objInfo, "", nil, nil, toObjectErr(rerr, bucket, object)
+		}
+	} else if err != errFileNotFound {
+		return objInfo, "", nil, nil, toObjectErr(traceError(err), bucket, object)
+	}
+
+	// Look for the latest version first.
+	objPath := pathJoin(fs.fsPath, bucket, object)
+	if fsMeta.VersionID == versionID || (fsMeta.VersionID == "" && versionID == nullVersionID) {
+		if fi, serr := fsStatFile(objPath); serr == nil {
+			return fsMeta.ToObjectInfo(bucket, object, fi), objPath, fsMeta.Transition, unlock, nil
+		}
+	}
+
+	versionsDir := fs.getObjectVersionsDir(bucket, object)
+	versionsPath := pathJoin(versionsDir, fsMetaJSONFile)
+	fsVersions := newFSVersionsV1()
+	rlk, err = fs.rwPool.Open(versionsPath)
+	if err == nil {
+		lockedPaths = append(lockedPaths, versionsPath)
+		if _, rerr := fsVersions.ReadFrom(rlk.LockedFile); rerr != nil && errorCause(rerr) != io.EOF {
+			unlock()
+			return objInfo, "", nil, nil, toObjectErr(rerr, bucket, object)
+		}
+	} else if err != errFileNotFound {
+		unlock()
+		return objInfo, "", nil, nil, toObjectErr(traceError(err), bucket, object)
+	}
+
+	i := indexOfVersion(fsVersions.Versions, versionID)
+	if i < 0 {
+		unlock()
+		return objInfo, "", nil, nil, traceError(VersionNotFound{Bucket: bucket, Object: object, VersionID: versionID})
+	}
+	if fsVersions.Versions[i].DeleteMarker {
+		unlock()
+		return objInfo, "", nil, nil, traceError(VersionIsDeleteMarker{Bucket: bucket, Object: object, VersionID: versionID})
+	}
+	return fsVersions.Versions[i].ToObjectInfo(bucket, object), pathJoin(versionsDir, versionID), fsVersions.Versions[i].Transition, unlock, nil
+}
+
+// GetObjectVersion - reads a version of an object, the latest version
+// if versionID is empty.
+func (fs fsObjects) GetObjectVersion(bucket, object, versionID string, offset int64, length int64, writer io.Writer) error {
+	if versionID == "" {
+		return fs.GetObject(bucket, object, offset, length, writer)
+	}
+
+	if err := checkGetObjArgs(bucket, object); err != nil {
+		return err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return toObjectErr(err, bucket)
+	}
+
+	// Offset cannot be negative.
+	if offset < 0 {
+		return toObjectErr(traceError(errUnexpected), bucket, object)
+	}
+
+	// Writer cannot be nil.
+	if writer == nil {
+		return toObjectErr(traceError(errUnexpected), bucket, object)
+	}
+
+	_, dataPath, transition, unlock, err := fs.getObjectVersion(bucket, object, versionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return copyObjectData(dataPath, transition, offset, length, writer, bucket, object)
+}
+
+// GetObjectVersionInfo - reads metadata of a version of an object, of
+// the latest version if versionID is empty.
+func (fs fsObjects) GetObjectVersionInfo(bucket, object, versionID string) (ObjectInfo, error) {
+	if versionID == "" {
+		objInfo, err := fs.GetObjectInfo(bucket, object)
+		if _, ok := errorCause(err).(ObjectNotFound); ok && globalBucketVersioning.Get(bucket) != "" {
+			if markerID := fs.latestDeleteMarker(bucket, object); markerID != "" {
+				return objInfo, traceError(VersionIsDeleteMarker{Bucket: bucket, Object: object, VersionID: markerID})
+			}
+		}
+		return objInfo, err
+	}
+
+	if err := checkGetObjArgs(bucket, object); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if _, err := fs.statBucketDir(bucket); err != nil {
+		return ObjectInfo{}, toObjectErr(err, bucket)
+	}
+
+	objInfo, _, _, unlock, err := fs.getObjectVersion(bucket, object, versionID)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	unlock()
+
+	return objInfo, nil
+}
+
+// latestDeleteMarker - returns the version ID of the delete marker
+// which is the latest version of an object without data, empty if none.
+func (fs fsObjects) latestDeleteMarker(bucket, object string) string {
+	versionsPath := pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)
+	rlk, err := fs.rwPool.Open(versionsPath)
+	if err != nil {
+		return ""
+	}
+	defer fs.rwPool.Close(versionsPath)
+
+	fsVersions := newFSVersionsV1()
+	if _, err = fsVersions.ReadFrom(rlk.LockedFile); err != nil {
+		return ""
+	}
+	if len(fsVersions.Versions) == 0 || !fsVersions.Versions[0].DeleteMarker {
+		return ""
+	}
+	return fsVersions.Versions[0].VersionID
+}
+
+// getObjectVersions - returns all versions of an object, latest first.
+func (fs fsObjects) getObjectVersions(bucket, object string) ([]ObjectInfo, error) {
+	var objInfos []ObjectInfo
+
+	fsMeta := fsMetaV1{}
+	fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	rlk, err := fs.rwPool.Open(fsMetaPath)
+	if err == nil {
+		defer fs.rwPool.Close(fsMetaPath)
+		if _, rerr := fsMeta.ReadFrom(rlk.LockedFile); rerr != nil && errorCause(rerr) != io.EOF {
+			return nil, toObjectErr(rerr, bucket, object)
+		}
+	} else if err != errFileNotFound {
+		return nil, toObjectErr(traceError(err), bucket, object)
+	}
+
+	if fi, serr := fsStatFile(pathJoin(fs.fsPath, bucket, object)); serr == nil {
+		objInfos = append(objInfos, fsMeta.ToObjectInfo(bucket, object, fi))
+	}
+
+	versionsPath := pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)
+	rlk, err = fs.rwPool.Open(versionsPath)
+	if err == errFileNotFound {
+		return objInfos, nil
+	}
+	if err != nil {
+		return nil, toObjectErr(traceError(err), bucket, object)
+	}
+	defer fs.rwPool.Close(versionsPath)
+
+	fsVersions := newFSVersionsV1()
+	if _, err = fsVersions.ReadFrom(rlk.LockedFile); err != nil && errorCause(err) != io.EOF {
+		return nil, toObjectErr(err, bucket, object)
+	}
+
+	hasLatest := len(objInfos) > 0
+	for i, v := range fsVersions.Versions {
+		objInfo := v.ToObjectInfo(bucket, object)
+		objInfo.IsLatest = i == 0 && !hasLatest
+		objInfos = append(objInfos, objInfo)
+	}
+	return objInfos, nil
+}
+
+// listObjectVersionKeys - returns the sorted names of all objects with
+// a latest or noncurrent version starting with prefix.
+func (fs fsObjects) listObjectVersionKeys(bucket, prefix string) ([]string, error) {
+	keys := set.NewStringSet()
+
+	walkDir := func(dir string, addKey func(name string, fi os.FileInfo)) error {
+		return filepath.Walk(dir, func(walkPath string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			name := strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(walkPath, dir)), slashSeparator)
+			if fi.IsDir() {
+				// Skip directories which cannot hold any object starting with prefix.
+				if name != "" && !hasPrefix(name+slashSeparator, prefix) && !hasPrefix(prefix, name+slashSeparator) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			addKey(name, fi)
+			return nil
+		})
+	}
+
+	// Latest versions are the regular files in the bucket.
+	err := walkDir(pathJoin(fs.fsPath, bucket), func(name string, fi os.FileInfo) {
+		if fi.Mode().IsRegular() && hasPrefix(name, prefix) {
+			keys.Add(name)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Noncurrent versions are listed in the `fs.json` of each object.
+	err = walkDir(pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectVersionsPrefix), func(name string, fi os.FileInfo) {
+		if pathutil.Base(name) != fsMetaJSONFile {
+			return
+		}
+		if key := pathutil.Dir(name); key != "." && hasPrefix(key, prefix) {
+			keys.Add(key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.ToSlice(), nil
+}
+
+// ListObjectVersions - lists all versions and delete markers of the
+// objects in a bucket, the versions of each object latest first.
+func (fs fsObjects) ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker, delimiter string, maxKeys int) (result ListObjectVersionsInfo, err error) {
+	if err = checkListObjsArgs(bucket, prefix, keyMarker, delimiter, fs); err != nil {
+		return result, err
+	}
+
+	if _, err = fs.statBucketDir(bucket); err != nil {
+		return result, toObjectErr(err, bucket)
+	}
+
+	keys, err := fs.listObjectVersionKeys(bucket, prefix)
+	if err != nil {
+		return result, toObjectErr(traceError(err), bucket)
+	}
+
+	var count int
+	var lastPrefix string
+keysLoop:
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix := key[:len(prefix)+i+len(delimiter)]
+				if commonPrefix <= keyMarker || commonPrefix == lastPrefix {
+					continue
+				}
+				if count == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				lastPrefix = commonPrefix
+				result.Prefixes = append(result.Prefixes, commonPrefix)
+				result.NextKeyMarker, result.NextVersionIDMarker = commonPrefix, ""
+				count++
+				continue
+			}
+		}
+
+		if key < keyMarker || (key == keyMarker && versionIDMarker == "") {
+			continue
+		}
+
+		objInfos, err := fs.getObjectVersions(bucket, key)
+		if err != nil {
+			return result, err
+		}
+
+		// Objects of buckets which were never versioned
+		// are listed as null versions.
+		for i := range objInfos {
+			if objInfos[i].VersionID == "" {
+				objInfos[i].VersionID = nullVersionID
+			}
+		}
+
+		if key == keyMarker {
+			// Resume after the version ID marker.
+			for i, objInfo := range objInfos {
+				if objInfo.VersionID == versionIDMarker {
+					objInfos = objInfos[i+1:]
+					break
+				}
+			}
+		}
+
+		for _, objInfo := range objInfos {
+			if count == maxKeys {
+				result.IsTruncated = true
+				break keysLoop
+			}
+			result.Objects = append(result.Objects, objInfo)
+			result.NextKeyMarker, result.NextVersionIDMarker = key, objInfo.VersionID
+			count++
+		}
+	}
+
+	if !result.IsTruncated {
+		result.NextKeyMarker, result.NextVersionIDMarker = "", ""
+	}
+	return result, nil
+}
@@ -0,0 +1,344 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns a new FS object layer in a temporary directory.
+func prepareTestFS(t *testing.T) *fsObjects {
+	objAPI, err := newFSObjectLayer(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unable to initialize FS backend: %v", err)
+	}
+	fs := objAPI.(*fsObjects)
+	t.Cleanup(func() { fs.Shutdown() })
+	return fs
+}
+
+// Writes data as the latest version of object, returns its version ID.
+func putTestObject(t *testing.T, fs *fsObjects, bucket, object, data string) string {
+	objInfo, err := fs.PutObject(bucket, object, int64(len(data)), strings.NewReader(data), nil, "")
+	if err != nil {
+		t.Fatalf("Unable to put %s/%s: %v", bucket, object, err)
+	}
+	return objInfo.VersionID
+}
+
+// Checks the content of a version of object.
+func checkTestObjectVersion(t *testing.T, fs *fsObjects, bucket, object, versionID, expected string) {
+	var buf bytes.Buffer
+	if err := fs.GetObjectVersion(bucket, object, versionID, 0, int64(len(expected)), &buf); err != nil {
+		t.Fatalf("Unable to get version %q of %s/%s: %v", versionID, bucket, object, err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("Version %q of %s/%s: expected %q, got %q", versionID, bucket, object, expected, buf.String())
+	}
+}
+
+// Checks the version IDs of all versions of object, latest first, and
+// which of them are delete markers.
+func checkTestObjectVersions(t *testing.T, fs *fsObjects, bucket, object string, versionIDs []string, deleteMarkers []bool) {
+	objInfos, err := fs.getObjectVersions(bucket, object)
+	if err != nil {
+		t.Fatalf("Unable to list versions of %s/%s: %v", bucket, object, err)
+	}
+	if len(objInfos) != len(versionIDs) {
+		t.Fatalf("%s/%s: expected %d versions, got %d: %+v", bucket, object, len(versionIDs), len(objInfos), objInfos)
+	}
+	for i, objInfo := range objInfos {
+		if objInfo.VersionID != versionIDs[i] || objInfo.DeleteMarker != deleteMarkers[i] {
+			t.Fatalf("%s/%s: expected version %d to be %q (delete marker %t), got %q (delete marker %t)",
+				bucket, object, i, versionIDs[i], deleteMarkers[i], objInfo.VersionID, objInfo.DeleteMarker)
+		}
+		if objInfo.IsLatest != (i == 0) {
+			t.Fatalf("%s/%s: version %q has latest set to %t", bucket, object, objInfo.VersionID, objInfo.IsLatest)
+		}
+	}
+}
+
+func TestObjectVersionID(t *testing.T) {
+	defer globalBucketVersioning.Set("versioned", "")
+	defer globalBucketVersioning.Set("suspended", "")
+	globalBucketVersioning.Set("versioned", versioningEnabled)
+	globalBucketVersioning.Set("suspended", versioningSuspended)
+
+	testCases := []struct {
+		bucket     string
+		versionID  string
+		expectedID string
+	}{
+		{"unversioned", "", ""},
+		{"versioned", "", nullVersionID},
+		{"versioned", "abc", "abc"},
+		{"suspended", "", nullVersionID},
+		{"suspended", "abc", "abc"},
+	}
+	for i, testCase := range testCases {
+		if id := objectVersionID(testCase.bucket, testCase.versionID); id != testCase.expectedID {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.expectedID, id)
+		}
+	}
+}
+
+func TestIndexOfVersion(t *testing.T) {
+	versions := []fsObjectVersion{{VersionID: "c"}, {VersionID: "b", DeleteMarker: true}, {VersionID: nullVersionID}}
+	testCases := []struct {
+		versionID string
+		expected  int
+	}{
+		{"c", 0},
+		{"b", 1},
+		{nullVersionID, 2},
+		{"a", -1},
+		{"", -1},
+	}
+	for i, testCase := range testCases {
+		if index := indexOfVersion(versions, testCase.versionID); index != testCase.expected {
+			t.Errorf("Test %d: expected %d, got %d", i+1, testCase.expected, index)
+		}
+	}
+}
+
+// Tests writing and deleting versions with versioning enabled, delete
+// markers hide the object until they are deleted and noncurrent
+// versions become the latest version once the latest one is deleted.
+func TestFSVersioningEnabled(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket, object := "bucket", "dir/object"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	defer globalBucketVersioning.Set(bucket, "")
+
+	// Written before versioning is enabled, kept as the null version.
+	putTestObject(t, fs, bucket, object, "zero")
+	globalBucketVersioning.Set(bucket, versioningEnabled)
+
+	v1 := putTestObject(t, fs, bucket, object, "one")
+	v2 := putTestObject(t, fs, bucket, object, "two")
+	if v1 == "" || v2 == "" || v1 == v2 || v1 == nullVersionID {
+		t.Fatalf("Expected distinct version IDs, got %q and %q", v1, v2)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{v2, v1, nullVersionID}, []bool{false, false, false})
+	checkTestObjectVersion(t, fs, bucket, object, "", "two")
+	checkTestObjectVersion(t, fs, bucket, object, v1, "one")
+	checkTestObjectVersion(t, fs, bucket, object, nullVersionID, "zero")
+
+	// Deleting without a version ID adds a delete marker.
+	marker, err := fs.DeleteObjectVersion(bucket, object, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !marker.DeleteMarker || marker.VersionID == "" {
+		t.Fatalf("Expected a delete marker, got %+v", marker)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{marker.VersionID, v2, v1, nullVersionID}, []bool{true, false, false, false})
+	if _, err = fs.GetObjectInfo(bucket, object); err == nil {
+		t.Fatal("Expected the object to be hidden by the delete marker")
+	}
+	_, err = fs.GetObjectVersionInfo(bucket, object, "")
+	if _, ok := errorCause(err).(VersionIsDeleteMarker); !ok {
+		t.Fatalf("Expected VersionIsDeleteMarker, got %v", err)
+	}
+	_, err = fs.GetObjectVersionInfo(bucket, object, marker.VersionID)
+	if _, ok := errorCause(err).(VersionIsDeleteMarker); !ok {
+		t.Fatalf("Expected VersionIsDeleteMarker, got %v", err)
+	}
+
+	// Deleting a version which does not exist is not an error.
+	if _, err = fs.DeleteObjectVersion(bucket, object, "unknown", false); err != nil {
+		t.Fatal(err)
+	}
+	_, err = fs.GetObjectVersionInfo(bucket, object, "unknown")
+	if _, ok := errorCause(err).(VersionNotFound); !ok {
+		t.Fatalf("Expected VersionNotFound, got %v", err)
+	}
+
+	// Deleting the delete marker makes v2 the latest version again.
+	objInfo, err := fs.DeleteObjectVersion(bucket, object, marker.VersionID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !objInfo.DeleteMarker {
+		t.Fatalf("Expected the deleted version to be a delete marker, got %+v", objInfo)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{v2, v1, nullVersionID}, []bool{false, false, false})
+	checkTestObjectVersion(t, fs, bucket, object, "", "two")
+
+	// Deleting a noncurrent version keeps the latest version.
+	if _, err = fs.DeleteObjectVersion(bucket, object, v1, false); err != nil {
+		t.Fatal(err)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{v2, nullVersionID}, []bool{false, false})
+
+	// Deleting the latest version promotes the null version.
+	if _, err = fs.DeleteObjectVersion(bucket, object, v2, false); err != nil {
+		t.Fatal(err)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{nullVersionID}, []bool{false})
+	checkTestObjectVersion(t, fs, bucket, object, "", "zero")
+	checkTestObjectVersion(t, fs, bucket, object, nullVersionID, "zero")
+
+	if _, err = fs.DeleteObjectVersion(bucket, object, nullVersionID, false); err != nil {
+		t.Fatal(err)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, nil, nil)
+	if _, err = os.Stat(pathJoin(fs.getObjectVersionsDir(bucket, object), fsMetaJSONFile)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the versions list to be removed, got %v", err)
+	}
+	if err = fs.DeleteBucket(bucket); err != nil {
+		t.Fatalf("Expected the bucket to be empty, got %v", err)
+	}
+}
+
+// Tests that the null version is replaced with versioning suspended,
+// while versions written with versioning enabled are kept.
+func TestFSVersioningSuspended(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket, object := "bucket", "object"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	defer globalBucketVersioning.Set(bucket, "")
+
+	globalBucketVersioning.Set(bucket, versioningEnabled)
+	v1 := putTestObject(t, fs, bucket, object, "one")
+
+	globalBucketVersioning.Set(bucket, versioningSuspended)
+	if versionID := putTestObject(t, fs, bucket, object, "null1"); versionID != nullVersionID {
+		t.Fatalf("Expected the null version, got %q", versionID)
+	}
+	putTestObject(t, fs, bucket, object, "null2")
+	checkTestObjectVersions(t, fs, bucket, object, []string{nullVersionID, v1}, []bool{false, false})
+	checkTestObjectVersion(t, fs, bucket, object, "", "null2")
+	checkTestObjectVersion(t, fs, bucket, object, v1, "one")
+
+	// The delete marker replaces the null version.
+	marker, err := fs.DeleteObjectVersion(bucket, object, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marker.VersionID != nullVersionID || !marker.DeleteMarker {
+		t.Fatalf("Expected a null delete marker, got %+v", marker)
+	}
+	checkTestObjectVersions(t, fs, bucket, object, []string{nullVersionID, v1}, []bool{true, false})
+
+	// So does a new null version replace the delete marker.
+	putTestObject(t, fs, bucket, object, "null3")
+	checkTestObjectVersions(t, fs, bucket, object, []string{nullVersionID, v1}, []bool{false, false})
+	checkTestObjectVersion(t, fs, bucket, object, "", "null3")
+
+	// Versions written with versioning enabled are kept even after
+	// versioning is enabled again.
+	globalBucketVersioning.Set(bucket, versioningEnabled)
+	v2 := putTestObject(t, fs, bucket, object, "two")
+	checkTestObjectVersions(t, fs, bucket, object, []string{v2, nullVersionID, v1}, []bool{false, false, false})
+	checkTestObjectVersion(t, fs, bucket, object, nullVersionID, "null3")
+}
+
+// Tests that buckets are not deleted while holding noncurrent versions
+// or delete markers.
+func TestFSDeleteBucketWithVersions(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket, object := "bucket", "object"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	defer globalBucketVersioning.Set(bucket, "")
+	globalBucketVersioning.Set(bucket, versioningEnabled)
+
+	v1 := putTestObject(t, fs, bucket, object, "one")
+	marker, err := fs.DeleteObjectVersion(bucket, object, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the noncurrent version and the delete marker are left.
+	err = fs.DeleteBucket(bucket)
+	if _, ok := errorCause(err).(BucketNotEmpty); !ok {
+		t.Fatalf("Expected BucketNotEmpty, got %v", err)
+	}
+
+	if _, err = fs.DeleteObjectVersion(bucket, object, v1, false); err != nil {
+		t.Fatal(err)
+	}
+	err = fs.DeleteBucket(bucket)
+	if _, ok := errorCause(err).(BucketNotEmpty); !ok {
+		t.Fatalf("Expected BucketNotEmpty with a delete marker left, got %v", err)
+	}
+
+	if _, err = fs.DeleteObjectVersion(bucket, object, marker.VersionID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.DeleteBucket(bucket); err != nil {
+		t.Fatalf("Expected the bucket to be deleted, got %v", err)
+	}
+	if globalBucketVersioning.Get(bucket) != "" {
+		t.Fatal("Expected the versioning state of the deleted bucket to be removed")
+	}
+}
+
+// Tests that lifecycle expiry leaves objects replaced since they were
+// listed alone.
+func TestFSExpireObject(t *testing.T) {
+	fs := prepareTestFS(t)
+	bucket := "bucket"
+	if err := fs.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	defer globalBucketVersioning.Set(bucket, "")
+
+	for _, status := range []string{"", versioningEnabled} {
+		globalBucketVersioning.Set(bucket, status)
+		object := "object" + status
+
+		putTestObject(t, fs, bucket, object, "old")
+		objInfo, err := fs.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listedModTime := objInfo.ModTime
+
+		// Replaced after it was listed.
+		putTestObject(t, fs, bucket, object, "new")
+		newModTime := listedModTime.Add(time.Second)
+		if err = os.Chtimes(pathJoin(fs.fsPath, bucket, object), newModTime, newModTime); err != nil {
+			t.Fatal(err)
+		}
+		if err = fs.ExpireObject(bucket, object, listedModTime); err != nil {
+			t.Fatalf("Versioning %q: expected the replaced object to be skipped, got %v", status, err)
+		}
+		checkTestObjectVersion(t, fs, bucket, object, "", "new")
+
+		if err = fs.ExpireObject(bucket, object, newModTime); err != nil {
+			t.Fatalf("Versioning %q: unable to expire object: %v", status, err)
+		}
+		if _, err = fs.GetObjectInfo(bucket, object); err == nil {
+			t.Fatalf("Versioning %q: expected the object to be expired", status)
+		}
+		err = fs.ExpireObject(bucket, object, newModTime)
+		if _, ok := errorCause(err).(ObjectNotFound); !ok {
+			t.Fatalf("Versioning %q: expected ObjectNotFound, got %v", status, err)
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"path/filepath"
 	"sort"
 	"syscall"
+	"time"
 
 	"github.com/minio/minio/pkg/lock"
 	"shareos/sha256-simd"
@@ -117,6 +118,13 @@ var bucketMetadataConfigs = []string{
 	bucketNotificationConfig,
 	bucketListenerConfig,
 	bucketPolicyConfig,
+	bucketLoggingConfig,
+	bucketVersioningConfig,
+	bucketObjectLockConfig,
+	bucketLifecycleConfig,
+	bucketCorsConfig,
+	bucketQuotaConfig,
+	bucketUsageConfig,
 }
 
 // Attempts to migrate old object metadata files to newer format
@@ -248,12 +256,50 @@ func newFSObjectLayer(fsPath string) (ObjectLayer, error) {
 		return nil, fmt.Errorf("Unable to initialize event notification. %s", err)
 	}
 
+	// Initialize and load bucket logging configs.
+	if err = initBucketLogging(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket logging configs. %s", err)
+	}
+
+	// Initialize and load bucket versioning configs.
+	if err = initBucketVersioning(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket versioning configs. %s", err)
+	}
+
+	// Initialize and load bucket object lock configs.
+	if err = initBucketObjectLock(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket object lock configs. %s", err)
+	}
+
+	// Initialize and load bucket lifecycle configs.
+	if err = initBucketLifecycle(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket lifecycle configs. %s", err)
+	}
+
+	// Initialize and load bucket CORS configs.
+	if err = initBucketCors(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket CORS configs. %s", err)
+	}
+
+	// Initialize and load bucket quotas.
+	if err = initBucketQuota(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load all bucket quotas. %s", err)
+	}
+
+	// Initialize data usage and start crawling.
+	if err = initDataUsage(fs); err != nil {
+		return nil, fmt.Errorf("Unable to load data usage of all buckets. %s", err)
+	}
+
 	// Return successfully initialized object layer.
 	return fs, nil
 }
 
 // Should be called when process shuts down.
 func (fs fsObjects) Shutdown() error {
+	// End the background appends of incomplete multipart uploads.
+	fs.abortAll()
+
 	// Close the format.json read lock.
 	fs.rwPool.Close(pathJoin(fs.fsPath, minioMetaBucket, fsFormatJSONFile))
 
@@ -312,6 +358,8 @@ func (fs fsObjects) MakeBucket(bucket string) error {
 		return toObjectErr(err, bucket)
 	}
 
+	// Usage of new buckets is known right away.
+	globalBucketUsage.Set(bucket, newBucketUsageEntry())
 	return nil
 }
 
@@ -381,6 +429,12 @@ func (fs fsObjects) DeleteBucket(bucket string) error {
 		return toObjectErr(err, bucket)
 	}
 
+	// Noncurrent versions of objects have to be deleted as well.
+	versionsDir := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectVersionsPrefix)
+	if entries, rerr := readDir(versionsDir); rerr == nil && len(entries) > 0 {
+		return toObjectErr(traceError(errVolumeNotEmpty), bucket)
+	}
+
 	// Attempt to delete regular bucket.
 	if err = fsRemoveDir(bucketDir); err != nil {
 		return toObjectErr(err, bucket)
@@ -397,6 +451,13 @@ func (fs fsObjects) DeleteBucket(bucket string) error {
 	if err = fsRemoveAll(minioMetadataBucketDir); err != nil {
 		return toObjectErr(err, bucket)
 	}
+	globalBucketVersioning.Set(bucket, "")
+	globalBucketObjectLock.Set(bucket, nil)
+	globalBucketLifecycle.Set(bucket, nil)
+	globalBucketCors.Set(bucket, nil)
+	globalBucketQuota.Set(bucket, nil)
+	globalBucketUsage.Set(bucket, nil)
+	removeTier(bucket)
 
 	return nil
 }
@@ -407,19 +468,34 @@ func (fs fsObjects) DeleteBucket(bucket string) error {
 // if source object and destination object are same we only
 // update metadata.
 func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
+	return fs.CopyObjectVersion(srcBucket, srcObject, "", dstBucket, dstObject, metadata)
+}
+
+// CopyObjectVersion - copy a version of the source object to the
+// destination object, the latest version if srcVersionID is empty.
+// If source object and destination object are same and the bucket
+// was never versioned we only update metadata.
+func (fs fsObjects) CopyObjectVersion(srcBucket, srcObject, srcVersionID, dstBucket, dstObject string, metadata map[string]string) (ObjectInfo, error) {
 	if _, err := fs.statBucketDir(srcBucket); err != nil {
 		return ObjectInfo{}, toObjectErr(err, srcBucket)
 	}
 
-	// Stat the file to get file size.
-	fi, err := fsStatFile(pathJoin(fs.fsPath, srcBucket, srcObject))
+	// Stat the source version to get its size.
+	srcInfo, err := fs.GetObjectVersionInfo(srcBucket, srcObject, srcVersionID)
 	if err != nil {
-		return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+		return ObjectInfo{}, err
 	}
 
 	// Check if this request is only metadata update.
-	cpMetadataOnly := isStringEqual(pathJoin(srcBucket, srcObject), pathJoin(dstBucket, dstObject))
+	cpSameObject := isStringEqual(pathJoin(srcBucket, srcObject), pathJoin(dstBucket, dstObject))
+	cpMetadataOnly := cpSameObject && srcVersionID == "" && globalBucketVersioning.Get(srcBucket) == ""
 	if cpMetadataOnly {
+		// Stat the file to get file size.
+		fi, err := fsStatFile(pathJoin(fs.fsPath, srcBucket, srcObject))
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+		}
+
 		fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, srcBucket, objectMetaPrefix, srcObject, fsMetaJSONFile)
 		var wlk *lock.LockedFile
 		wlk, err = fs.rwPool.Write(fsMetaPath)
@@ -429,9 +505,18 @@ func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string
 		// This close will allow for locks to be synchronized on `fs.json`.
 		defer wlk.Close()
 
-		// Save objects' metadata in `fs.json`.
+		// Metadata of objects under retention or legal hold is not replaced.
+		if err = fs.checkReplacedVersionsLock(srcBucket, srcObject, wlk, false); err != nil {
+			return ObjectInfo{}, err
+		}
+
+		// Save objects' metadata in `fs.json`, a transitioned object
+		// keeps its data in the lifecycle tier.
 		fsMeta := newFSMetaV1()
 		fsMeta.Meta = metadata
+		if curMeta, _, rerr := fs.readLatestVersion(srcBucket, srcObject, wlk); rerr == nil {
+			fsMeta.Transition = curMeta.Transition
+		}
 		if _, err = fsMeta.WriteTo(wlk); err != nil {
 			return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
 		}
@@ -441,14 +526,45 @@ func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string
 	}
 
 	// Length of the file to read.
-	length := fi.Size()
+	length := srcInfo.Size
+
+	if cpSameObject {
+		// PutObject() holds the lock on `fs.json` of the object while
+		// reading, the source version is first copied to the temporary
+		// location and written back from there.
+		fsTmpObjPath := pathJoin(fs.fsPath, minioMetaTmpBucket, fs.fsUUID, mustGetUUID())
+		defer fsRemoveFile(fsTmpObjPath)
+
+		wfile, err := os.OpenFile(preparePath(fsTmpObjPath), os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(traceError(err), srcBucket, srcObject)
+		}
+		var startOffset int64 // Read the whole file.
+		err = fs.GetObjectVersion(srcBucket, srcObject, srcVersionID, startOffset, length, wfile)
+		wfile.Close()
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+
+		reader, _, err := fsOpenFile(fsTmpObjPath, startOffset)
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(err, srcBucket, srcObject)
+		}
+		defer reader.Close()
+
+		objInfo, err := fs.PutObject(dstBucket, dstObject, length, reader, metadata, "")
+		if err != nil {
+			return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
+		}
+		return objInfo, nil
+	}
 
 	// Initialize pipe.
 	pipeReader, pipeWriter := io.Pipe()
 
 	go func() {
 		var startOffset int64 // Read the whole file.
-		if gerr := fs.GetObject(srcBucket, srcObject, startOffset, length, pipeWriter); gerr != nil {
+		if gerr := fs.GetObjectVersion(srcBucket, srcObject, srcVersionID, startOffset, length, pipeWriter); gerr != nil {
 			println(gerr, "Unable to read %s/%s.", srcBucket, srcObject)
 			pipeWriter.CloseWithError(gerr)
 			return
@@ -458,6 +574,7 @@ func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string
 
 	objInfo, err := fs.PutObject(dstBucket, dstObject, length, pipeReader, metadata, "")
 	if err != nil {
+		pipeReader.CloseWithError(err)
 		return ObjectInfo{}, toObjectErr(err, dstBucket, dstObject)
 	}
 
@@ -492,17 +609,31 @@ func (fs fsObjects) GetObject(bucket, object string, offset int64, length int64,
 		return toObjectErr(traceError(errUnexpected), bucket, object)
 	}
 
+	var transition *fsTransition
 	if bucket != minioMetaBucket {
 		fsMetaPath := pathJoin(fs.fsPath, minioMetaBucket, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
-		_, err = fs.rwPool.Open(fsMetaPath)
-		if err != nil && err != errFileNotFound {
-			return toObjectErr(traceError(err), bucket, object)
+		rlk, rerr := fs.rwPool.Open(fsMetaPath)
+		if rerr != nil && rerr != errFileNotFound {
+			return toObjectErr(traceError(rerr), bucket, object)
+		}
+		if rerr == nil {
+			defer fs.rwPool.Close(fsMetaPath)
+
+			// Transitioned objects are read from the lifecycle tier.
+			fsMeta := fsMetaV1{}
+			if _, rerr = fsMeta.ReadFrom(rlk.LockedFile); rerr == nil {
+				transition = fsMeta.Transition
+			}
 		}
-		defer fs.rwPool.Close(fsMetaPath)
 	}
 
 	// Read the object, doesn't exist returns an s3 compatible error.
-	fsObjPath := pathJoin(fs.fsPath, bucket, object)
+	return copyObjectData(pathJoin(fs.fsPath, bucket, object), transition, offset, length, writer, bucket, object)
+}
+
+// fsCopyObject - writes length bytes of the file at fsObjPath from
+// offset to writer, a negative length writes until the end.
+func fsCopyObject(fsObjPath string, offset int64, length int64, writer io.Writer, bucket, object string) error {
 	reader, size, err := fsOpenFile(fsObjPath, offset)
 	if err != nil {
 		return toObjectErr(err, bucket, object)
@@ -647,6 +778,20 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 		}
 		// This close will allow for locks to be synchronized on `fs.json`.
 		defer wlk.Close()
+
+		// Objects under retention or legal hold are not overwritten.
+		if err = fs.checkReplacedVersionsLock(bucket, object, wlk, false); err != nil {
+			return ObjectInfo{}, err
+		}
+
+		// Writes beyond the hard quota of the bucket are rejected.
+		usage := fs.getObjectUsage(bucket, object)
+		quotaSize, quotaObjects := putObjectQuotaUsage(bucket, usage, size)
+		if err = checkBucketQuota(bucket, object, quotaSize, quotaObjects); err != nil {
+			return ObjectInfo{}, err
+		}
+		defer fs.updateBucketUsage(bucket, object, usage)
+
 		defer func() {
 			// Remove meta file when PutObject encounters any error
 			if retErr != nil {
@@ -732,6 +877,13 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 		}
 	}
 
+	if bucket != minioMetaBucket {
+		// Keep the version being replaced if the bucket is versioned.
+		if fsMeta.VersionID, err = fs.putObjectVersion(bucket, object, wlk); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
 	// Entire object was written to the temp location, now it's safe to rename it to the actual location.
 	fsNSObjPath := pathJoin(fs.fsPath, bucket, object)
 	if err = fsRenameFile(fsTmpObjPath, fsNSObjPath); err != nil {
@@ -756,27 +908,53 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 }
 
 // DeleteObject - deletes an object from a bucket, this operation is destructive
-// and there are no rollbacks supported.
+// and there are no rollbacks supported. In versioned buckets the object is
+// replaced by a delete marker instead.
 func (fs fsObjects) DeleteObject(bucket, object string) error {
-	if err := checkDelObjArgs(bucket, object); err != nil {
-		return err
-	}
-
-	if _, err := fs.statBucketDir(bucket); err != nil {
-		return toObjectErr(err, bucket)
-	}
+	_, err := fs.DeleteObjectVersion(bucket, object, "", false)
+	return err
+}
 
+// deleteObject - deletes an object from a bucket which was never
+// versioned. Unless modTime is zero the object is only deleted if it
+// was last modified at modTime.
+func (fs fsObjects) deleteObject(bucket, object string, modTime time.Time, bypassGovernance bool) error {
 	minioMetaBucketDir := pathJoin(fs.fsPath, minioMetaBucket)
 	fsMetaPath := pathJoin(minioMetaBucketDir, bucketMetaPrefix, bucket, objectMetaPrefix, object, fsMetaJSONFile)
+	var transition *fsTransition
 	if bucket != minioMetaBucket {
-		rwlk, lerr := fs.rwPool.Write(fsMetaPath)
+		var rwlk *lock.LockedFile
+		var lerr error
+		if modTime.IsZero() {
+			rwlk, lerr = fs.rwPool.Write(fsMetaPath)
+		} else {
+			// Objects without `fs.json` are locked as well
+			// while checking they were not replaced.
+			rwlk, lerr = fs.rwPool.Create(fsMetaPath)
+		}
 		if lerr == nil {
 			// This close will allow for fs locks to be synchronized on `fs.json`.
 			defer rwlk.Close()
+
+			if !modTime.IsZero() {
+				if err := fs.checkLatestModTime(bucket, object, rwlk, modTime); err != nil {
+					return err
+				}
+			}
+
+			// Objects under retention or legal hold are not deleted.
+			if err := fs.checkReplacedVersionsLock(bucket, object, rwlk, bypassGovernance); err != nil {
+				return err
+			}
+
+			if fsMeta, _, err := fs.readLatestVersion(bucket, object, rwlk); err == nil {
+				transition = fsMeta.Transition
+			}
 		}
 		if lerr != nil && lerr != errFileNotFound {
 			return toObjectErr(traceError(lerr), bucket, object)
 		}
+		defer fs.updateBucketUsage(bucket, object, fs.getObjectUsage(bucket, object))
 	}
 
 	// Delete the object.
@@ -784,6 +962,9 @@ func (fs fsObjects) DeleteObject(bucket, object string) error {
 		return toObjectErr(err, bucket, object)
 	}
 
+	// Data of transitioned objects in the tier is deleted as well.
+	removeTransitionedData(bucket, transition)
+
 	if bucket != minioMetaBucket {
 		// Delete the metadata object.
 		err := fsDeleteFile(minioMetaBucketDir, fsMetaPath)
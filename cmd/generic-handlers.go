@@ -18,6 +18,8 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/xml"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -25,7 +27,6 @@ import (
 
 	humanize "github.com/dustin/go-humanize"
 	router "github.com/gorilla/mux"
-	"github.com/rs/cors"
 )
 
 // HandlerFunc - useful to chain different middleware http.Handler
@@ -261,26 +262,63 @@ const (
 	httpOPTIONS = "OPTIONS"
 )
 
-// List of default allowable HTTP methods.
-var defaultAllowableHTTPMethods = []string{
-	httpGET,
-	httpPUT,
-	httpHEAD,
-	httpPOST,
-	httpDELETE,
-	httpOPTIONS,
+// corsHandler - evaluates cross origin requests to a bucket against
+// the CORS configuration of the bucket.
+type corsHandler struct {
+	handler http.Handler
 }
 
 // setCorsHandler handler for CORS (Cross Origin Resource Sharing)
 func setCorsHandler(h http.Handler) http.Handler {
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   defaultAllowableHTTPMethods,
-		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"ETag"},
-		AllowCredentials: true,
-	})
-	return c.Handler(h)
+	return corsHandler{handler: h}
+}
+
+func (h corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	bucket, _ := urlPath2BucketObjectName(r.URL)
+	if origin == "" || bucket == "" || isMinioReservedBucket(bucket) {
+		// Not a cross origin request to a bucket.
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	config, ok := globalBucketCors.Get(bucket)
+	if ok {
+		// Responses depend on the origin of the request.
+		w.Header().Add("Vary", "Origin")
+	}
+
+	// Preflight requests are answered from the CORS configuration.
+	reqMethod := r.Header.Get("Access-Control-Request-Method")
+	if r.Method == httpOPTIONS && reqMethod != "" {
+		var reqHeaders []string
+		for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				reqHeaders = append(reqHeaders, header)
+			}
+		}
+		var rule *corsRule
+		if ok {
+			rule = config.match(origin, reqMethod, reqHeaders)
+		}
+		if rule == nil {
+			writeErrorResponse(w, ErrCORSForbidden, r.URL)
+			return
+		}
+		setCorsHeaders(w, rule, origin)
+		if len(reqHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+		}
+		writeSuccessResponseHeadersOnly(w)
+		return
+	}
+
+	if ok {
+		if rule := config.match(origin, r.Method, nil); rule != nil {
+			setCorsHeaders(w, rule, origin)
+		}
+	}
+	h.handler.ServeHTTP(w, r)
 }
 
 // setIgnoreResourcesHandler -
@@ -314,14 +352,9 @@ func ignoreNotImplementedObjectResources(req *http.Request) bool {
 // List of not implemented bucket queries
 var notimplementedBucketResourceNames = map[string]bool{
 	"acl":            true,
-	"cors":           true,
-	"lifecycle":      true,
-	"logging":        true,
 	"replication":    true,
 	"tagging":        true,
-	"versions":       true,
 	"requestPayment": true,
-	"versioning":     true,
 	"website":        true,
 }
 
@@ -365,11 +398,51 @@ func (h resourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type httpResponseRecorder struct {
 	http.ResponseWriter
 	respStatusCode int
+	// Total number of response body bytes written.
+	bytesWritten int64
+	// Leading bytes of an error response body.
+	errBody []byte
 }
 
-// Wraps ResponseWriter's Write()
+// Maximum number of error response body bytes recorded, more
+// than enough to hold the error code of an S3 error response.
+const maxRecordedErrBodySize = 1024
+
+// Wraps ResponseWriter's Write() and record the
+// number of bytes written.
 func (rww *httpResponseRecorder) Write(b []byte) (int, error) {
-	return rww.ResponseWriter.Write(b)
+	if rww.respStatusCode >= http.StatusBadRequest && len(rww.errBody) < maxRecordedErrBodySize {
+		remaining := maxRecordedErrBodySize - len(rww.errBody)
+		if remaining > len(b) {
+			remaining = len(b)
+		}
+		rww.errBody = append(rww.errBody, b[:remaining]...)
+	}
+	n, err := rww.ResponseWriter.Write(b)
+	rww.bytesWritten += int64(n)
+	return n, err
+}
+
+// errorCode - returns the S3 error code of an error response,
+// empty string if the response is not an S3 error response.
+func (rww *httpResponseRecorder) errorCode() string {
+	if len(rww.errBody) == 0 {
+		return ""
+	}
+	var errResp APIErrorResponse
+	if err := xml.Unmarshal(rww.errBody, &errResp); err != nil {
+		return ""
+	}
+	return errResp.Code
+}
+
+// statusCode - returns the recorded response status code, an
+// unset status code means an implicit 200 OK.
+func (rww *httpResponseRecorder) statusCode() int {
+	if rww.respStatusCode == 0 {
+		return http.StatusOK
+	}
+	return rww.respStatusCode
 }
 
 // Wraps ResponseWriter's Flush()
@@ -402,6 +475,9 @@ func (h httpStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Wraps w to record http response information
 	ww := &httpResponseRecorder{ResponseWriter: w}
 
+	globalHTTPStats.currentRequests.Inc()
+	defer globalHTTPStats.currentRequests.Dec()
+
 	// Time start before the call is about to start.
 	tBefore := UTCNow()
 
@@ -421,6 +497,110 @@ func (h httpStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	globalHTTPStats.updateStats(r, ww, durationSecs)
 }
 
+// httpRequestBodyCounter wraps request body to count
+// the number of bytes read by the handlers.
+type httpRequestBodyCounter struct {
+	io.ReadCloser
+	bytesRead int64
+}
+
+// Wraps request body's Read() and record the number of bytes read.
+func (rb *httpRequestBodyCounter) Read(p []byte) (int, error) {
+	n, err := rb.ReadCloser.Read(p)
+	rb.bytesRead += int64(n)
+	return n, err
+}
+
+// bucketLoggingHandler definition: records an access record for
+// every API call on buckets with logging enabled.
+type bucketLoggingHandler struct {
+	handler http.Handler
+}
+
+// setBucketLoggingHandler sets a bucket access logging handler.
+func setBucketLoggingHandler(h http.Handler) http.Handler {
+	return bucketLoggingHandler{handler: h}
+}
+
+func (h bucketLoggingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, _ := urlPath2BucketObjectName(r.URL)
+	target, ok := globalBucketAccessLogger.GetConfig(bucket)
+	if !ok {
+		// Logging is not enabled for this bucket.
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	ww := &httpResponseRecorder{ResponseWriter: w}
+	tBefore := UTCNow()
+	h.handler.ServeHTTP(ww, r)
+	totalTime := UTCNow().Sub(tBefore)
+
+	globalBucketAccessLogger.Log(target, formatAccessLogRecord(r, ww, tBefore, totalTime))
+}
+
+// auditHandler definition: records an audit entry for every API call.
+type auditHandler struct {
+	handler http.Handler
+}
+
+// setAuditHandler sets an audit log handler.
+func setAuditHandler(h http.Handler) http.Handler {
+	return auditHandler{handler: h}
+}
+
+func (h auditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auditLog := getAuditLogger()
+	if auditLog == nil {
+		// Audit logging is not enabled.
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	// Wraps w and r.Body to record the bytes transferred.
+	ww := &httpResponseRecorder{ResponseWriter: w}
+	body := &httpRequestBodyCounter{ReadCloser: r.Body}
+	r.Body = body
+
+	// Compute API name, bucket and object before the handlers
+	// get a chance to modify the request.
+	api := getAPIName(r)
+	bucket, object := urlPath2BucketObjectName(r.URL)
+	aType := getRequestAuthType(r)
+	reqHeader := selectHeaders(r.Header, auditLog.reqHeaders)
+
+	tBefore := UTCNow()
+	h.handler.ServeHTTP(ww, r)
+	duration := UTCNow().Sub(tBefore)
+
+	// Use the request ID sent to the client, if no response headers
+	// were set by the handlers generate one.
+	requestID := ww.Header().Get(responseRequestIDKey)
+	if requestID == "" {
+		requestID = mustGetRequestID(tBefore)
+	}
+
+	auditLog.Log(auditEntry{
+		Version:   auditEntryVersion,
+		Time:      tBefore,
+		RequestID: requestID,
+		API:       api,
+		Bucket:    bucket,
+		Object:    object,
+		Identity: auditIdentity{
+			AccessKey: getRequestAccessKey(r),
+			AuthType:  aType.String(),
+		},
+		RemoteAddr:  r.RemoteAddr,
+		StatusCode:  ww.statusCode(),
+		InputBytes:  body.bytesRead,
+		OutputBytes: ww.bytesWritten,
+		Duration:    duration,
+		ReqHeader:   reqHeader,
+		RespHeader:  selectHeaders(ww.Header(), auditLog.respHeaders),
+	})
+}
+
 // pathValidityHandler validates all the incoming paths for
 // any bad components and rejects them.
 type pathValidityHandler struct {
@@ -17,10 +17,13 @@
 package cmd
 
 import (
+	"crypto/x509"
+	"net"
 	"time"
 "runtime"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	"go.uber.org/atomic"
 )
 
 // minio configuration related constants.
@@ -67,12 +70,19 @@ var (
 
 	// Set to true if credentials were passed from env, default is false.
 	globalIsEnvCreds = false
+	// Access and secret keys set through the environment.
+	globalEnvCreds credential
 
 	// This flag is set to 'true' wen MINIO_REGION env is set.
 	globalIsEnvRegion = false
 	// This flag is set to 'us-east-1' by default
 	globalServerRegion = globalMinioDefaultRegion
 
+	// Set to true if an audit sink was set through env.
+	globalIsEnvAudit = false
+	// Audit logging settings set through the environment.
+	globalEnvAuditConfig auditConfig
+
 	// Maximum size of internal objects parts
 	globalPutPartSize = int64(64 * 1024 * 1024)
 
@@ -82,10 +92,87 @@ var (
 	globalMinioPort = "9000"
 	// Holds the host that was passed using --address
 	globalMinioHost = ""
+	// All the addresses passed using --address, including Unix
+	// domain sockets.
+	globalMinioAddrs []string
+	// Permissions of the Unix domain sockets.
+	globalUnixSocketMode = defaultUnixSocketMode
+
+	// Setup type of the server, FS, XL or distributed XL.
+	globalSetupType SetupType
+
+	// Set to true if the server serves TLS, certificates are loaded
+	// from the certs directory.
+	globalIsSSL = false
+	// Certificates served by the server, nil if TLS is disabled.
+	globalTLSCerts *certManager
+	// System root CAs along with the ones of the certs directory.
+	globalRootCAs *x509.CertPool
+
+	// Time when object layer was initialized on start up.
+	globalBootTime time.Time
 
 	globalServerUserAgent = "Minio/" + ReleaseTag + " (" + runtime.GOOS + "; " + runtime.GOARCH + ")"
 	globalEndpoints EndpointList
 	globalHTTPStats = newHTTPStats()
+	globalConnStats = newConnStats()
+
+	// Set to true once the server starts draining its connections.
+	globalIsServerDraining = atomic.NewBool(false)
+
+	// Time to wait for in-flight requests to finish on shutdown or
+	// restart before closing their connections forcibly.
+	globalServerShutdownTimeout = 30 * time.Second
+
+	// Timeouts of client connections, see ServerMux.
+	globalHTTPReadHeaderTimeout = 10 * time.Second
+	globalHTTPIdleTimeout       = 30 * time.Second
+	globalHTTPWriteTimeout      = 30 * time.Second
+
+	// Maximum size of the request headers.
+	globalHTTPMaxHeaderBytes = 64 * humanize.KiByte
+
+	// Maximum number of open connections per client IP, 0 for no limit.
+	globalMaxConnsPerIP = 512
+
+	// Minimum rate in bytes per second clients must send request
+	// bodies with, 0 for no limit.
+	globalHTTPMinBodyRate int64 = humanize.KiByte
+
+	// Maximum number of read, write and list API calls in progress,
+	// 0 for no limit.
+	globalAPIReadRequests  = 512
+	globalAPIWriteRequests = 256
+	globalAPIListRequests  = 64
+	// Time an API call waits for its turn before SlowDown is returned.
+	globalAPIRequestsDeadline = 10 * time.Second
+
+	// Maximum number of concurrent requests per HTTP/2 connection.
+	globalHTTP2MaxConcurrentStreams = 250
+
+	// Load balancers allowed to send the client address through the
+	// PROXY protocol.
+	globalTrustedProxies []*net.IPNet
+
+	// Interval between two lifecycle scans of all buckets, and the
+	// pause after each object or upload scanned which limits the
+	// load a scan puts on the disk.
+	globalLifecycleScanInterval = time.Hour
+	globalLifecycleScanDelay    = time.Millisecond
+
+	// Secondary storage objects are transitioned to by lifecycle
+	// rules, nil if not configured.
+	globalLifecycleTier StorageAPI
+
+	// Set to true if the data usage crawl interval was set through
+	// env, it overrides the cache usage expiry of the config.
+	globalIsEnvDataUsageCrawlInterval = false
+	// Data usage crawl interval set through the environment.
+	globalDataUsageCrawlInterval time.Duration
+
+	// Pause after each directory crawled which limits the load a data
+	// usage crawl puts on the disk.
+	globalDataUsageCrawlDelay = time.Millisecond
 )
 
 var (
@@ -38,16 +38,17 @@ func parseLocationConstraint(r *http.Request) (location string, s3Error APIError
 	} // else for both err as nil or io.EOF
 	location = locationConstraint.Location
 	if location == "" {
-		//location = serverConfig.GetRegion()
+		location = serverConfig.GetRegion()
 	}
 	return location, ErrNone
 }
 
 // Validates input location is same as configured region
 // of Minio server.
-//func isValidLocation(location string) bool {
-//	return serverConfig.GetRegion() == "" || serverConfig.GetRegion() == location
-//}
+func isValidLocation(location string) bool {
+	region := serverConfig.GetRegion()
+	return region == "" || region == location
+}
 
 // Supported headers that needs to be extracted.
 var supportedHeaders = []string{
@@ -236,3 +237,170 @@ func extractPostPolicyFormValues(form *multipart.Form) (filePart io.ReadCloser,
 
 	return filePart, fileName, fileSize, formValues, nil
 }
+
+// Bucket level APIs selected by a resource query, these are checked
+// in order and mirror the bucket routes registered in api-router.go.
+var bucketResourceAPINames = []struct {
+	method   string
+	resource string
+	name     string
+}{
+	{httpGET, "location", "GetBucketLocation"},
+	{httpGET, "policy", "GetBucketPolicy"},
+	{httpGET, "notification", "GetBucketNotification"},
+	{httpGET, "events", "ListenBucketNotification"},
+	{httpGET, "uploads", "ListMultipartUploads"},
+	{httpGET, "logging", "GetBucketLogging"},
+	{httpGET, "versioning", "GetBucketVersioning"},
+	{httpGET, "versions", "ListObjectVersions"},
+	{httpGET, "object-lock", "GetBucketObjectLockConfig"},
+	{httpGET, "lifecycle", "GetBucketLifecycle"},
+	{httpGET, "cors", "GetBucketCors"},
+	{httpPUT, "policy", "PutBucketPolicy"},
+	{httpPUT, "notification", "PutBucketNotification"},
+	{httpPUT, "logging", "PutBucketLogging"},
+	{httpPUT, "versioning", "PutBucketVersioning"},
+	{httpPUT, "object-lock", "PutBucketObjectLockConfig"},
+	{httpPUT, "lifecycle", "PutBucketLifecycle"},
+	{httpPUT, "cors", "PutBucketCors"},
+	{httpPOST, "delete", "DeleteMultipleObjects"},
+	{httpDELETE, "policy", "DeleteBucketPolicy"},
+	{httpDELETE, "lifecycle", "DeleteBucketLifecycle"},
+	{httpDELETE, "cors", "DeleteBucketCors"},
+}
+
+// Names of the internal APIs served from the reserved bucket, paths
+// serving several APIs are prefixed with the method.
+var reservedPathAPINames = map[string]string{
+	prometheusMetricsPath: "PrometheusMetrics",
+	healthLivenessPath:    "HealthLiveness",
+	healthReadinessPath:   "HealthReadiness",
+
+	adminAPIPathPrefix + "/info":               adminServerInfoAPIName,
+	adminAPIPathPrefix + "/storageinfo":        adminStorageInfoAPIName,
+	adminAPIPathPrefix + "/httpstats":          adminHTTPStatsAPIName,
+	adminAPIPathPrefix + "/service/restart":    adminServiceRestartAPIName,
+	adminAPIPathPrefix + "/service/stop":       adminServiceStopAPIName,
+	adminAPIPathPrefix + "/trace":              adminTraceAPIName,
+	adminAPIPathPrefix + "/profiling/start":    adminStartProfilingAPIName,
+	adminAPIPathPrefix + "/profiling/stop":     adminStopProfilingAPIName,
+	adminAPIPathPrefix + "/profiling/download": adminDownloadProfileAPIName,
+	adminAPIPathPrefix + "/datausage":          adminDataUsageAPIName,
+
+	httpGET + " " + adminAPIPathPrefix + "/bandwidth": adminGetBandwidthAPIName,
+	httpPUT + " " + adminAPIPathPrefix + "/bandwidth": adminSetBandwidthAPIName,
+
+	httpGET + " " + adminAPIPathPrefix + "/quota":    adminGetBucketQuotaAPIName,
+	httpPUT + " " + adminAPIPathPrefix + "/quota":    adminSetBucketQuotaAPIName,
+	httpDELETE + " " + adminAPIPathPrefix + "/quota": adminDelBucketQuotaAPIName,
+}
+
+// getAPIName - returns the S3 API name of an incoming request. The
+// name is derived from the method, path and resource queries the
+// same way the API router dispatches them, which allows middlewares
+// running outside of the router to label requests.
+func getAPIName(r *http.Request) string {
+	bucket, object := urlPath2BucketObjectName(r.URL)
+	values := r.URL.Query()
+	hasQuery := func(name string) bool {
+		_, ok := values[name]
+		return ok
+	}
+
+	// Internal operations served from the reserved bucket.
+	if isMinioReservedBucket(bucket) {
+		if name, ok := reservedPathAPINames[r.URL.Path]; ok {
+			return name
+		}
+		if name, ok := reservedPathAPINames[r.Method+" "+r.URL.Path]; ok {
+			return name
+		}
+		return "Unknown"
+	}
+
+	// Root operations.
+	if bucket == "" {
+		if r.Method == httpGET {
+			return "ListBuckets"
+		}
+		return "Unknown"
+	}
+
+	// Object operations.
+	if object != "" {
+		isCopy := r.Header.Get("X-Amz-Copy-Source") != ""
+		switch r.Method {
+		case httpHEAD:
+			return "HeadObject"
+		case httpGET:
+			if hasQuery("uploadId") {
+				return "ListObjectParts"
+			}
+			if hasQuery("retention") {
+				return "GetObjectRetention"
+			}
+			if hasQuery("legal-hold") {
+				return "GetObjectLegalHold"
+			}
+			return "GetObject"
+		case httpPUT:
+			if hasQuery("partNumber") && hasQuery("uploadId") {
+				if isCopy {
+					return "CopyObjectPart"
+				}
+				return "PutObjectPart"
+			}
+			if hasQuery("retention") {
+				return "PutObjectRetention"
+			}
+			if hasQuery("legal-hold") {
+				return "PutObjectLegalHold"
+			}
+			if isCopy {
+				return "CopyObject"
+			}
+			return "PutObject"
+		case httpPOST:
+			if hasQuery("uploadId") {
+				return "CompleteMultipartUpload"
+			}
+			if hasQuery("uploads") {
+				return "NewMultipartUpload"
+			}
+			if hasQuery("restore") {
+				return "RestoreObject"
+			}
+		case httpDELETE:
+			if hasQuery("uploadId") {
+				return "AbortMultipartUpload"
+			}
+			return "DeleteObject"
+		}
+		return "Unknown"
+	}
+
+	// Bucket operations.
+	for _, api := range bucketResourceAPINames {
+		if r.Method == api.method && hasQuery(api.resource) {
+			return api.name
+		}
+	}
+	switch r.Method {
+	case httpGET:
+		if values.Get("list-type") == "2" {
+			return "ListObjectsV2"
+		}
+		return "ListObjectsV1"
+	case httpPUT:
+		return "PutBucket"
+	case httpHEAD:
+		return "HeadBucket"
+	case httpPOST:
+		if isRequestPostPolicySignatureV4(r) {
+			return "PostPolicyBucket"
+		}
+	case httpDELETE:
+		return "DeleteBucket"
+	}
+	return "Unknown"
+}
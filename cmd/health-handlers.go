@@ -0,0 +1,96 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+
+	router "github.com/gorilla/mux"
+)
+
+// Health check paths.
+const (
+	healthCheckPathPrefix = minioReservedBucketPath + "/health"
+	healthLivenessPath    = healthCheckPathPrefix + "/live"
+	healthReadinessPath   = healthCheckPathPrefix + "/ready"
+)
+
+// registerHealthRouter - add handler functions for health checks.
+func registerHealthRouter(mux *router.Router) {
+	healthRouter := mux.NewRoute().PathPrefix(healthCheckPathPrefix).Subrouter()
+
+	// Liveness handler.
+	healthRouter.Methods(httpGET, httpHEAD).Path("/live").HandlerFunc(livenessCheckHandler)
+	// Readiness handler.
+	healthRouter.Methods(httpGET, httpHEAD).Path("/ready").HandlerFunc(readinessCheckHandler)
+}
+
+// livenessCheckHandler - returns 200 OK as long as the server
+// process is able to serve http requests.
+func livenessCheckHandler(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// readinessCheckHandler - returns 200 OK if the server is ready
+// to serve S3 requests, 503 Service Unavailable with the reason
+// otherwise.
+func readinessCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkServerReady(newObjectLayerFn()); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if r.Method != httpHEAD {
+			w.Write([]byte(err.Error() + "\n"))
+		}
+		return
+	}
+	writeSuccessResponseHeadersOnly(w)
+}
+
+// checkServerReady - validates that the object layer is initialized,
+// its disks are found, have free space and can be written to, and the
+// server is not draining its connections.
+func checkServerReady(objAPI ObjectLayer) error {
+	if globalIsServerDraining.Load() {
+		return errServerDraining
+	}
+	if objAPI == nil {
+		return errServerNotInitialized
+	}
+	switch obj := objAPI.(type) {
+	case *fsObjects:
+		disk := &posix{diskPath: obj.fsPath}
+		if err := disk.checkDiskFound(); err != nil {
+			return err
+		}
+		if err := checkDiskFree(obj.fsPath, 0); err != nil {
+			return err
+		}
+		return checkFSWritable(obj)
+	}
+	return nil
+}
+
+// checkFSWritable - creates and removes a file in the tmp directory of
+// the backend, which fails once its disk is remounted read-only.
+func checkFSWritable(fs *fsObjects) error {
+	probePath := pathJoin(fs.fsPath, minioMetaTmpBucket, fs.fsUUID, "health-"+mustGetUUID())
+	if _, err := fsCreateFile(probePath, bytes.NewReader([]byte("ok")), nil, 0); err != nil {
+		return errorCause(err)
+	}
+	return errorCause(fsRemoveFile(probePath))
+}
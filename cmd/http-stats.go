@@ -18,8 +18,11 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
-"net/http"
+
 	"go.uber.org/atomic"
 )
 
@@ -51,7 +54,6 @@ func (s *ConnStats) getTotalOutputBytes() uint64 {
 	return s.totalOutputBytes.Load()
 }
 
-
 // Prepare new ConnStats structure
 func newConnStats() *ConnStats {
 	return &ConnStats{}
@@ -86,16 +88,95 @@ type HTTPStats struct {
 	// DELETE request stats.
 	totalDELETEs   HTTPMethodStats
 	successDELETEs HTTPMethodStats
+
+	// Number of requests currently being served.
+	currentRequests atomic.Int64
+
+	// Request stats per API and response status code.
+	mu       sync.Mutex
+	apiStats map[apiStatsKey]*apiStats
+}
+
+// Upper bounds in seconds of the request latency histogram buckets.
+var httpLatencyBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// apiStatsKey identifies the requests of an API answered
+// with a given response status code.
+type apiStatsKey struct {
+	API        string
+	StatusCode int
+}
+
+// apiStats holds the request count and a cumulative latency
+// histogram of an API and response status code.
+type apiStats struct {
+	Counter  uint64
+	Duration float64
+	// Number of requests faster than or equal to the
+	// corresponding upper bound in httpLatencyBuckets.
+	Buckets []uint64
 }
 
 func durationStr(totalDuration, totalCount float64) string {
-	return fmt.Sprint(time.Duration(totalDuration/totalCount) * time.Second)
+	if totalCount == 0 {
+		return fmt.Sprint(time.Duration(0))
+	}
+	return fmt.Sprint(time.Duration(totalDuration / totalCount * float64(time.Second)))
+}
+
+// ServerHTTPMethodStats - represents the number of requests of an
+// HTTP method and their average duration.
+type ServerHTTPMethodStats struct {
+	Count       uint64 `json:"count"`
+	AvgDuration string `json:"avgDuration"`
+}
+
+// ServerHTTPStats - holds the HTTP statistics of the server per
+// method, for all requests and for successful requests.
+type ServerHTTPStats struct {
+	TotalHEADStats     ServerHTTPMethodStats `json:"totalHEADs"`
+	SuccessHEADStats   ServerHTTPMethodStats `json:"successHEADs"`
+	TotalGETStats      ServerHTTPMethodStats `json:"totalGETs"`
+	SuccessGETStats    ServerHTTPMethodStats `json:"successGETs"`
+	TotalPUTStats      ServerHTTPMethodStats `json:"totalPUTs"`
+	SuccessPUTStats    ServerHTTPMethodStats `json:"successPUTs"`
+	TotalPOSTStats     ServerHTTPMethodStats `json:"totalPOSTs"`
+	SuccessPOSTStats   ServerHTTPMethodStats `json:"successPOSTs"`
+	TotalDELETEStats   ServerHTTPMethodStats `json:"totalDELETEs"`
+	SuccessDELETEStats ServerHTTPMethodStats `json:"successDELETEs"`
+}
+
+// Converts the statistics of an HTTP method.
+func (s *HTTPMethodStats) toServerHTTPMethodStats() ServerHTTPMethodStats {
+	count := s.Counter.Load()
+	return ServerHTTPMethodStats{
+		Count:       count,
+		AvgDuration: durationStr(s.Duration.Load(), float64(count)),
+	}
+}
+
+// Return a point in time copy of the HTTP statistics.
+func (st *HTTPStats) toServerHTTPStats() ServerHTTPStats {
+	return ServerHTTPStats{
+		TotalHEADStats:     st.totalHEADs.toServerHTTPMethodStats(),
+		SuccessHEADStats:   st.successHEADs.toServerHTTPMethodStats(),
+		TotalGETStats:      st.totalGETs.toServerHTTPMethodStats(),
+		SuccessGETStats:    st.successGETs.toServerHTTPMethodStats(),
+		TotalPUTStats:      st.totalPUTs.toServerHTTPMethodStats(),
+		SuccessPUTStats:    st.successPUTs.toServerHTTPMethodStats(),
+		TotalPOSTStats:     st.totalPOSTs.toServerHTTPMethodStats(),
+		SuccessPOSTStats:   st.successPOSTs.toServerHTTPMethodStats(),
+		TotalDELETEStats:   st.totalDELETEs.toServerHTTPMethodStats(),
+		SuccessDELETEStats: st.successDELETEs.toServerHTTPMethodStats(),
+	}
 }
 
 // Update statistics from http request and response data
 func (st *HTTPStats) updateStats(r *http.Request, w *httpResponseRecorder, durationSecs float64) {
 	// A successful request has a 2xx response code
-	successReq := (w.respStatusCode >= 200 && w.respStatusCode < 300)
+	successReq := (w.statusCode() >= 200 && w.statusCode() < 300)
 	// Update stats according to method verb
 	switch r.Method {
 	case "HEAD":
@@ -117,14 +198,14 @@ func (st *HTTPStats) updateStats(r *http.Request, w *httpResponseRecorder, durat
 		st.totalPUTs.Duration.Add(durationSecs)
 		if successReq {
 			st.successPUTs.Counter.Inc()
-			st.totalPUTs.Duration.Add(durationSecs)
+			st.successPUTs.Duration.Add(durationSecs)
 		}
 	case "POST":
 		st.totalPOSTs.Counter.Inc()
 		st.totalPOSTs.Duration.Add(durationSecs)
 		if successReq {
 			st.successPOSTs.Counter.Inc()
-			st.totalPOSTs.Duration.Add(durationSecs)
+			st.successPOSTs.Duration.Add(durationSecs)
 		}
 	case "DELETE":
 		st.totalDELETEs.Counter.Inc()
@@ -134,8 +215,58 @@ func (st *HTTPStats) updateStats(r *http.Request, w *httpResponseRecorder, durat
 			st.successDELETEs.Duration.Add(durationSecs)
 		}
 	}
+
+	st.updateAPIStats(getAPIName(r), w.statusCode(), durationSecs)
 }
 
+// Update per API statistics.
+func (st *HTTPStats) updateAPIStats(api string, statusCode int, durationSecs float64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.apiStats == nil {
+		st.apiStats = make(map[apiStatsKey]*apiStats)
+	}
+	key := apiStatsKey{API: api, StatusCode: statusCode}
+	stats, ok := st.apiStats[key]
+	if !ok {
+		stats = &apiStats{Buckets: make([]uint64, len(httpLatencyBuckets))}
+		st.apiStats[key] = stats
+	}
+	stats.Counter++
+	stats.Duration += durationSecs
+	for i, bound := range httpLatencyBuckets {
+		if durationSecs <= bound {
+			stats.Buckets[i]++
+		}
+	}
+}
+
+// apiStatsEntry is a point in time copy of the statistics of an API.
+type apiStatsEntry struct {
+	apiStatsKey
+	apiStats
+}
+
+// Returns a copy of the per API statistics sorted by API and status code.
+func (st *HTTPStats) getAPIStats() []apiStatsEntry {
+	st.mu.Lock()
+	entries := make([]apiStatsEntry, 0, len(st.apiStats))
+	for key, stats := range st.apiStats {
+		entry := apiStatsEntry{apiStatsKey: key, apiStats: *stats}
+		entry.Buckets = append([]uint64(nil), stats.Buckets...)
+		entries = append(entries, entry)
+	}
+	st.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].API != entries[j].API {
+			return entries[i].API < entries[j].API
+		}
+		return entries[i].StatusCode < entries[j].StatusCode
+	})
+	return entries
+}
 
 // Prepare new HTTPStats structure
 func newHTTPStats() *HTTPStats {
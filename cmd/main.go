@@ -96,6 +96,8 @@ func newApp() *cli.App {
 
 	// Register all commands.
 	registerCommand(serverCmd)
+	registerCommand(adminCmd)
+	registerCommand(certgenCmd)
 	//registerCommand(versionCmd)
 	//registerCommand(updateCmd)
 	//registerCommand(gatewayCmd)
@@ -0,0 +1,175 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	router "github.com/gorilla/mux"
+)
+
+const (
+	// Prometheus metrics path.
+	prometheusMetricsPath = minioReservedBucketPath + "/prometheus/metrics"
+
+	// Content type of the prometheus text exposition format.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// registerMetricsRouter - add handler functions for metrics.
+func registerMetricsRouter(mux *router.Router) {
+	mux.Methods(httpGET).Path(prometheusMetricsPath).HandlerFunc(metricsHandler)
+}
+
+// Escapes a prometheus label value.
+var metricLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Writes the HELP and TYPE lines of a metric.
+func writeMetricHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// Writes a sample of a metric, labels are given as name, value pairs.
+func writeMetric(w io.Writer, name string, value float64, labels ...string) {
+	fmt.Fprint(w, name)
+	if len(labels) > 0 {
+		fmt.Fprint(w, "{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labels[i], metricLabelReplacer.Replace(labels[i+1]))
+		}
+		fmt.Fprint(w, "}")
+	}
+	fmt.Fprintf(w, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// Writes the per API request counts and latency histograms.
+func writeHTTPMetrics(w io.Writer, st *HTTPStats) {
+	entries := st.getAPIStats()
+
+	writeMetricHeader(w, "minio_http_requests_total", "Total number of S3 requests by API and status code.", "counter")
+	for _, entry := range entries {
+		writeMetric(w, "minio_http_requests_total", float64(entry.Counter),
+			"api", entry.API, "code", strconv.Itoa(entry.StatusCode))
+	}
+
+	writeMetricHeader(w, "minio_http_request_duration_seconds", "Time taken to serve S3 requests by API and status code.", "histogram")
+	for _, entry := range entries {
+		code := strconv.Itoa(entry.StatusCode)
+		for i, bound := range httpLatencyBuckets {
+			writeMetric(w, "minio_http_request_duration_seconds_bucket", float64(entry.Buckets[i]),
+				"api", entry.API, "code", code, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		writeMetric(w, "minio_http_request_duration_seconds_bucket", float64(entry.Counter),
+			"api", entry.API, "code", code, "le", "+Inf")
+		writeMetric(w, "minio_http_request_duration_seconds_sum", entry.Duration,
+			"api", entry.API, "code", code)
+		writeMetric(w, "minio_http_request_duration_seconds_count", float64(entry.Counter),
+			"api", entry.API, "code", code)
+	}
+
+	writeMetricHeader(w, "minio_http_requests_inflight", "Number of requests currently being served.", "gauge")
+	writeMetric(w, "minio_http_requests_inflight", float64(st.currentRequests.Load()))
+}
+
+// Writes the network traffic counters.
+func writeNetworkMetrics(w io.Writer, s *ConnStats) {
+	writeMetricHeader(w, "minio_network_received_bytes_total", "Total number of bytes received.", "counter")
+	writeMetric(w, "minio_network_received_bytes_total", float64(s.getTotalInputBytes()))
+	writeMetricHeader(w, "minio_network_sent_bytes_total", "Total number of bytes sent.", "counter")
+	writeMetric(w, "minio_network_sent_bytes_total", float64(s.getTotalOutputBytes()))
+}
+
+// Writes the disk and namespace usage, nothing is written
+// while the object layer is not initialized yet.
+func writeStorageMetrics(w io.Writer, objAPI ObjectLayer) {
+	if objAPI == nil {
+		return
+	}
+
+	storageInfo := objAPI.StorageInfo()
+	writeMetricHeader(w, "minio_disk_storage_total_bytes", "Total disk space in bytes.", "gauge")
+	writeMetric(w, "minio_disk_storage_total_bytes", float64(storageInfo.Total))
+	writeMetricHeader(w, "minio_disk_storage_free_bytes", "Free disk space in bytes.", "gauge")
+	writeMetric(w, "minio_disk_storage_free_bytes", float64(storageInfo.Free))
+
+	bucketCount, usage := globalBucketUsage.Total()
+	writeMetricHeader(w, "minio_bucket_count", "Total number of buckets.", "gauge")
+	writeMetric(w, "minio_bucket_count", float64(bucketCount))
+	writeMetricHeader(w, "minio_object_count", "Total number of objects.", "gauge")
+	writeMetric(w, "minio_object_count", float64(usage.Objects))
+}
+
+// Writes the usage of all buckets whose usage is known and of their
+// largest top level prefixes.
+func writeDataUsageMetrics(w io.Writer) {
+	reports := globalBucketUsage.Reports(dataUsageTopPrefixes)
+
+	writeMetricHeader(w, "minio_bucket_usage_total_bytes", "Total size of the latest and noncurrent versions of the objects of a bucket.", "gauge")
+	for _, report := range reports {
+		writeMetric(w, "minio_bucket_usage_total_bytes", float64(report.Size), "bucket", report.Bucket)
+	}
+	writeMetricHeader(w, "minio_bucket_usage_object_total", "Total number of objects of a bucket.", "gauge")
+	for _, report := range reports {
+		writeMetric(w, "minio_bucket_usage_object_total", float64(report.Objects), "bucket", report.Bucket)
+	}
+	writeMetricHeader(w, "minio_bucket_usage_version_total", "Total number of noncurrent versions of the objects of a bucket.", "gauge")
+	for _, report := range reports {
+		writeMetric(w, "minio_bucket_usage_version_total", float64(report.Versions), "bucket", report.Bucket)
+	}
+	writeMetricHeader(w, "minio_bucket_objects_size_distribution", "Number of objects of a bucket by size range.", "gauge")
+	for _, report := range reports {
+		for _, r := range objectSizeRanges {
+			writeMetric(w, "minio_bucket_objects_size_distribution", float64(report.ObjectsSizesHistogram[r.name]),
+				"bucket", report.Bucket, "range", r.name)
+		}
+	}
+	writeMetricHeader(w, "minio_bucket_prefix_usage_total_bytes", "Total size of the objects under the largest top level prefixes of a bucket.", "gauge")
+	for _, report := range reports {
+		for _, p := range report.Prefixes {
+			writeMetric(w, "minio_bucket_prefix_usage_total_bytes", float64(p.Size), "bucket", report.Bucket, "prefix", p.Prefix)
+		}
+	}
+	writeMetricHeader(w, "minio_bucket_prefix_usage_object_total", "Total number of objects under the largest top level prefixes of a bucket.", "gauge")
+	for _, report := range reports {
+		for _, p := range report.Prefixes {
+			writeMetric(w, "minio_bucket_prefix_usage_object_total", float64(p.Objects), "bucket", report.Bucket, "prefix", p.Prefix)
+		}
+	}
+}
+
+// metricsHandler - serves the server metrics in the prometheus
+// text exposition format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	writeHTTPMetrics(&buf, globalHTTPStats)
+	writeNetworkMetrics(&buf, globalConnStats)
+	writeStorageMetrics(&buf, newObjectLayerFn())
+	writeDataUsageMetrics(&buf)
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
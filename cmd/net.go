@@ -143,9 +143,9 @@ func getAPIEndpoints(serverAddr string) (apiEndpoints []string) {
 	}
 
 	scheme := httpScheme
-	//if globalIsSSL {
-	//	scheme = httpsScheme
-	//}
+	if globalIsSSL {
+		scheme = httpsScheme
+	}
 
 	for _, ip := range ipList {
 		apiEndpoints = append(apiEndpoints, fmt.Sprintf("%s://%s:%s", scheme, ip, port))
@@ -115,6 +115,23 @@ type ObjectInfo struct {
 	// User-Defined metadata
 	UserDefined    map[string]string
 	HealObjectInfo *HealObjectInfo `xml:"HealObjectInfo,omitempty"`
+
+	// Version ID of the object, only set in versioned buckets.
+	VersionID string
+
+	// IsLatest indicates if this is the latest version of the object.
+	IsLatest bool
+
+	// DeleteMarker indicates if this version is a delete marker.
+	DeleteMarker bool
+
+	// Storage class of the lifecycle tier the object was transitioned
+	// to, empty if the object is in the standard storage class.
+	StorageClass string
+
+	// Time until which a transitioned object is restored, zero if the
+	// object is not restored.
+	RestoreExpiry time.Time
 }
 
 // ListPartsInfo - represents list of all parts.
@@ -245,6 +262,24 @@ type ListObjectsV2Info struct {
 	Prefixes []string
 }
 
+// ListObjectVersionsInfo - container for list object versions.
+type ListObjectVersionsInfo struct {
+	// Indicates whether the returned list of versions is truncated.
+	IsTruncated bool
+
+	// When the response is truncated, the key and version ID to use as
+	// key-marker and version-id-marker in the subsequent request.
+	NextKeyMarker       string
+	NextVersionIDMarker string
+
+	// List of versions and delete markers, latest version of
+	// an object first.
+	Objects []ObjectInfo
+
+	// List of prefixes for this request.
+	Prefixes []string
+}
+
 // PartInfo - represents individual part metadata.
 type PartInfo struct {
 	// Part number that identifies the part. This is a positive integer between
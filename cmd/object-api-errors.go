@@ -283,6 +283,61 @@ func (e ObjectTooSmall) Error() string {
 	return "size of the object less than what is expected"
 }
 
+/// Versioning related errors.
+
+// VersionNotFound version of an object does not exist.
+type VersionNotFound struct {
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
+func (e VersionNotFound) Error() string {
+	return "Version not found: " + e.Bucket + "#" + e.Object + " (" + e.VersionID + ")"
+}
+
+// VersionIsDeleteMarker version of an object is a delete marker.
+type VersionIsDeleteMarker struct {
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
+func (e VersionIsDeleteMarker) Error() string {
+	return "Version is a delete marker: " + e.Bucket + "#" + e.Object + " (" + e.VersionID + ")"
+}
+
+/// Object lock related errors.
+
+// ObjectLocked version of an object is under retention or legal hold.
+type ObjectLocked struct {
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
+func (e ObjectLocked) Error() string {
+	return "Object is WORM protected: " + e.Bucket + "#" + e.Object + " (" + e.VersionID + ")"
+}
+
+/// Lifecycle related errors.
+
+// InvalidObjectState object was not transitioned to the lifecycle tier.
+type InvalidObjectState GenericError
+
+func (e InvalidObjectState) Error() string {
+	return "Object is not in the lifecycle tier: " + e.Bucket + "#" + e.Object
+}
+
+/// Quota related errors.
+
+// BucketQuotaExceeded write would take a bucket beyond its hard quota.
+type BucketQuotaExceeded GenericError
+
+func (e BucketQuotaExceeded) Error() string {
+	return "Bucket quota exceeded: " + e.Bucket + "#" + e.Object
+}
+
 /// Multipart related errors.
 
 // MalformedUploadID malformed upload id.
@@ -16,7 +16,10 @@
 
 package cmd
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // ObjectLayer implements primitives for object API layer.
 type ObjectLayer interface {
@@ -29,6 +32,8 @@ type ObjectLayer interface {
 	GetBucketInfo(bucket string) (bucketInfo BucketInfo, err error)
 	ListBuckets() (buckets []BucketInfo, err error)
 	DeleteBucket(bucket string) error
+	GetBucketUsage(bucket string) (usage BucketUsage, err error)
+	CrawlBucketUsage(bucket string) (usage BucketUsage, err error)
 	ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (result ListObjectsInfo, err error)
 
 	// Object operations.
@@ -38,6 +43,22 @@ type ObjectLayer interface {
 	CopyObject(srcBucket, srcObject, destBucket, destObject string, metadata map[string]string) (objInfo ObjectInfo, err error)
 	DeleteObject(bucket, object string) error
 
+	// Object version operations.
+	GetObjectVersion(bucket, object, versionID string, startOffset int64, length int64, writer io.Writer) (err error)
+	GetObjectVersionInfo(bucket, object, versionID string) (objInfo ObjectInfo, err error)
+	CopyObjectVersion(srcBucket, srcObject, srcVersionID, destBucket, destObject string, metadata map[string]string) (objInfo ObjectInfo, err error)
+	DeleteObjectVersion(bucket, object, versionID string, bypassGovernance bool) (objInfo ObjectInfo, err error)
+	ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker, delimiter string, maxKeys int) (result ListObjectVersionsInfo, err error)
+
+	// Object lock operations.
+	PutObjectRetention(bucket, object, versionID, mode string, retainUntil time.Time, bypassGovernance bool) (objInfo ObjectInfo, err error)
+	PutObjectLegalHold(bucket, object, versionID, status string) (objInfo ObjectInfo, err error)
+
+	// Lifecycle operations.
+	TransitionObject(bucket, object, storageClass string, modTime time.Time) error
+	ExpireObject(bucket, object string, modTime time.Time) error
+	RestoreObject(bucket, object string, days int) (alreadyRestored bool, err error)
+
 	// Multipart operations.
 	ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (result ListMultipartsInfo, err error)
 	NewMultipartUpload(bucket, object string, metadata map[string]string) (uploadID string, err error)
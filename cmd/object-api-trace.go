@@ -0,0 +1,300 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// traceObjectLayer wraps an ObjectLayer and records the timing of
+// every call made on behalf of a traced request.
+//
+// NOTE: only the FS backend is implemented, which does not go through
+// StorageAPI, hence the ObjectLayer calls are the lowest level traced.
+type traceObjectLayer struct {
+	ObjectLayer
+	calls *traceCalls
+}
+
+// traceAPI - wraps an API handler to serve traced requests with an
+// object layer recording the calls made on behalf of the request.
+func traceAPI(api objectAPIHandlers, f func(objectAPIHandlers, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls := getTraceCalls(r)
+		if calls == nil {
+			f(api, w, r)
+			return
+		}
+		tracedAPI := objectAPIHandlers{
+			ObjectAPI: func() ObjectLayer {
+				objAPI := api.ObjectAPI()
+				if objAPI == nil {
+					return nil
+				}
+				return traceObjectLayer{ObjectLayer: objAPI, calls: calls}
+			},
+		}
+		f(tracedAPI, w, r)
+	}
+}
+
+// StorageInfo - traces ObjectLayer.StorageInfo.
+func (t traceObjectLayer) StorageInfo() StorageInfo {
+	startTime := UTCNow()
+	defer t.calls.record("StorageInfo", startTime, nil)
+	return t.ObjectLayer.StorageInfo()
+}
+
+// MakeBucket - traces ObjectLayer.MakeBucket.
+func (t traceObjectLayer) MakeBucket(bucket string) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("MakeBucket", startTime, err) }()
+	return t.ObjectLayer.MakeBucket(bucket)
+}
+
+// GetBucketInfo - traces ObjectLayer.GetBucketInfo.
+func (t traceObjectLayer) GetBucketInfo(bucket string) (bucketInfo BucketInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetBucketInfo", startTime, err) }()
+	return t.ObjectLayer.GetBucketInfo(bucket)
+}
+
+// ListBuckets - traces ObjectLayer.ListBuckets.
+func (t traceObjectLayer) ListBuckets() (buckets []BucketInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListBuckets", startTime, err) }()
+	return t.ObjectLayer.ListBuckets()
+}
+
+// DeleteBucket - traces ObjectLayer.DeleteBucket.
+func (t traceObjectLayer) DeleteBucket(bucket string) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("DeleteBucket", startTime, err) }()
+	return t.ObjectLayer.DeleteBucket(bucket)
+}
+
+// GetBucketUsage - traces ObjectLayer.GetBucketUsage.
+func (t traceObjectLayer) GetBucketUsage(bucket string) (usage BucketUsage, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetBucketUsage", startTime, err) }()
+	return t.ObjectLayer.GetBucketUsage(bucket)
+}
+
+// CrawlBucketUsage - traces ObjectLayer.CrawlBucketUsage.
+func (t traceObjectLayer) CrawlBucketUsage(bucket string) (usage BucketUsage, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("CrawlBucketUsage", startTime, err) }()
+	return t.ObjectLayer.CrawlBucketUsage(bucket)
+}
+
+// ListObjects - traces ObjectLayer.ListObjects.
+func (t traceObjectLayer) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (result ListObjectsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListObjects", startTime, err) }()
+	return t.ObjectLayer.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+}
+
+// GetObject - traces ObjectLayer.GetObject.
+func (t traceObjectLayer) GetObject(bucket, object string, startOffset int64, length int64, writer io.Writer) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetObject", startTime, err) }()
+	return t.ObjectLayer.GetObject(bucket, object, startOffset, length, writer)
+}
+
+// GetObjectInfo - traces ObjectLayer.GetObjectInfo.
+func (t traceObjectLayer) GetObjectInfo(bucket, object string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetObjectInfo", startTime, err) }()
+	return t.ObjectLayer.GetObjectInfo(bucket, object)
+}
+
+// PutObject - traces ObjectLayer.PutObject.
+func (t traceObjectLayer) PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string, sha256sum string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("PutObject", startTime, err) }()
+	return t.ObjectLayer.PutObject(bucket, object, size, data, metadata, sha256sum)
+}
+
+// CopyObject - traces ObjectLayer.CopyObject.
+func (t traceObjectLayer) CopyObject(srcBucket, srcObject, destBucket, destObject string, metadata map[string]string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("CopyObject", startTime, err) }()
+	return t.ObjectLayer.CopyObject(srcBucket, srcObject, destBucket, destObject, metadata)
+}
+
+// DeleteObject - traces ObjectLayer.DeleteObject.
+func (t traceObjectLayer) DeleteObject(bucket, object string) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("DeleteObject", startTime, err) }()
+	return t.ObjectLayer.DeleteObject(bucket, object)
+}
+
+// GetObjectVersion - traces ObjectLayer.GetObjectVersion.
+func (t traceObjectLayer) GetObjectVersion(bucket, object, versionID string, startOffset int64, length int64, writer io.Writer) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetObjectVersion", startTime, err) }()
+	return t.ObjectLayer.GetObjectVersion(bucket, object, versionID, startOffset, length, writer)
+}
+
+// GetObjectVersionInfo - traces ObjectLayer.GetObjectVersionInfo.
+func (t traceObjectLayer) GetObjectVersionInfo(bucket, object, versionID string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("GetObjectVersionInfo", startTime, err) }()
+	return t.ObjectLayer.GetObjectVersionInfo(bucket, object, versionID)
+}
+
+// CopyObjectVersion - traces ObjectLayer.CopyObjectVersion.
+func (t traceObjectLayer) CopyObjectVersion(srcBucket, srcObject, srcVersionID, destBucket, destObject string, metadata map[string]string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("CopyObjectVersion", startTime, err) }()
+	return t.ObjectLayer.CopyObjectVersion(srcBucket, srcObject, srcVersionID, destBucket, destObject, metadata)
+}
+
+// DeleteObjectVersion - traces ObjectLayer.DeleteObjectVersion.
+func (t traceObjectLayer) DeleteObjectVersion(bucket, object, versionID string, bypassGovernance bool) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("DeleteObjectVersion", startTime, err) }()
+	return t.ObjectLayer.DeleteObjectVersion(bucket, object, versionID, bypassGovernance)
+}
+
+// ListObjectVersions - traces ObjectLayer.ListObjectVersions.
+func (t traceObjectLayer) ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker, delimiter string, maxKeys int) (result ListObjectVersionsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListObjectVersions", startTime, err) }()
+	return t.ObjectLayer.ListObjectVersions(bucket, prefix, keyMarker, versionIDMarker, delimiter, maxKeys)
+}
+
+// PutObjectRetention - traces ObjectLayer.PutObjectRetention.
+func (t traceObjectLayer) PutObjectRetention(bucket, object, versionID, mode string, retainUntil time.Time, bypassGovernance bool) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("PutObjectRetention", startTime, err) }()
+	return t.ObjectLayer.PutObjectRetention(bucket, object, versionID, mode, retainUntil, bypassGovernance)
+}
+
+// PutObjectLegalHold - traces ObjectLayer.PutObjectLegalHold.
+func (t traceObjectLayer) PutObjectLegalHold(bucket, object, versionID, status string) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("PutObjectLegalHold", startTime, err) }()
+	return t.ObjectLayer.PutObjectLegalHold(bucket, object, versionID, status)
+}
+
+// TransitionObject - traces ObjectLayer.TransitionObject.
+func (t traceObjectLayer) TransitionObject(bucket, object, storageClass string, modTime time.Time) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("TransitionObject", startTime, err) }()
+	return t.ObjectLayer.TransitionObject(bucket, object, storageClass, modTime)
+}
+
+// ExpireObject - traces ObjectLayer.ExpireObject.
+func (t traceObjectLayer) ExpireObject(bucket, object string, modTime time.Time) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ExpireObject", startTime, err) }()
+	return t.ObjectLayer.ExpireObject(bucket, object, modTime)
+}
+
+// RestoreObject - traces ObjectLayer.RestoreObject.
+func (t traceObjectLayer) RestoreObject(bucket, object string, days int) (alreadyRestored bool, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("RestoreObject", startTime, err) }()
+	return t.ObjectLayer.RestoreObject(bucket, object, days)
+}
+
+// ListMultipartUploads - traces ObjectLayer.ListMultipartUploads.
+func (t traceObjectLayer) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (result ListMultipartsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListMultipartUploads", startTime, err) }()
+	return t.ObjectLayer.ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
+}
+
+// NewMultipartUpload - traces ObjectLayer.NewMultipartUpload.
+func (t traceObjectLayer) NewMultipartUpload(bucket, object string, metadata map[string]string) (uploadID string, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("NewMultipartUpload", startTime, err) }()
+	return t.ObjectLayer.NewMultipartUpload(bucket, object, metadata)
+}
+
+// CopyObjectPart - traces ObjectLayer.CopyObjectPart.
+func (t traceObjectLayer) CopyObjectPart(srcBucket, srcObject, destBucket, destObject string, uploadID string, partID int, startOffset int64, length int64) (info PartInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("CopyObjectPart", startTime, err) }()
+	return t.ObjectLayer.CopyObjectPart(srcBucket, srcObject, destBucket, destObject, uploadID, partID, startOffset, length)
+}
+
+// PutObjectPart - traces ObjectLayer.PutObjectPart.
+func (t traceObjectLayer) PutObjectPart(bucket, object, uploadID string, partID int, size int64, data io.Reader, md5Hex string, sha256sum string) (info PartInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("PutObjectPart", startTime, err) }()
+	return t.ObjectLayer.PutObjectPart(bucket, object, uploadID, partID, size, data, md5Hex, sha256sum)
+}
+
+// ListObjectParts - traces ObjectLayer.ListObjectParts.
+func (t traceObjectLayer) ListObjectParts(bucket, object, uploadID string, partNumberMarker int, maxParts int) (result ListPartsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListObjectParts", startTime, err) }()
+	return t.ObjectLayer.ListObjectParts(bucket, object, uploadID, partNumberMarker, maxParts)
+}
+
+// AbortMultipartUpload - traces ObjectLayer.AbortMultipartUpload.
+func (t traceObjectLayer) AbortMultipartUpload(bucket, object, uploadID string) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("AbortMultipartUpload", startTime, err) }()
+	return t.ObjectLayer.AbortMultipartUpload(bucket, object, uploadID)
+}
+
+// CompleteMultipartUpload - traces ObjectLayer.CompleteMultipartUpload.
+func (t traceObjectLayer) CompleteMultipartUpload(bucket, object, uploadID string, uploadedParts []completePart) (objInfo ObjectInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("CompleteMultipartUpload", startTime, err) }()
+	return t.ObjectLayer.CompleteMultipartUpload(bucket, object, uploadID, uploadedParts)
+}
+
+// HealBucket - traces ObjectLayer.HealBucket.
+func (t traceObjectLayer) HealBucket(bucket string) (err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("HealBucket", startTime, err) }()
+	return t.ObjectLayer.HealBucket(bucket)
+}
+
+// ListBucketsHeal - traces ObjectLayer.ListBucketsHeal.
+func (t traceObjectLayer) ListBucketsHeal() (buckets []BucketInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListBucketsHeal", startTime, err) }()
+	return t.ObjectLayer.ListBucketsHeal()
+}
+
+// HealObject - traces ObjectLayer.HealObject.
+func (t traceObjectLayer) HealObject(bucket, object string) (numOfflineDisks int, numHealedDisks int, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("HealObject", startTime, err) }()
+	return t.ObjectLayer.HealObject(bucket, object)
+}
+
+// ListObjectsHeal - traces ObjectLayer.ListObjectsHeal.
+func (t traceObjectLayer) ListObjectsHeal(bucket, prefix, marker, delimiter string, maxKeys int) (result ListObjectsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListObjectsHeal", startTime, err) }()
+	return t.ObjectLayer.ListObjectsHeal(bucket, prefix, marker, delimiter, maxKeys)
+}
+
+// ListUploadsHeal - traces ObjectLayer.ListUploadsHeal.
+func (t traceObjectLayer) ListUploadsHeal(bucket, prefix, marker, uploadIDMarker, delimiter string, maxUploads int) (result ListMultipartsInfo, err error) {
+	startTime := UTCNow()
+	defer func() { t.calls.record("ListUploadsHeal", startTime, err) }()
+	return t.ObjectLayer.ListUploadsHeal(bucket, prefix, marker, uploadIDMarker, delimiter, maxUploads)
+}
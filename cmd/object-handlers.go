@@ -72,13 +72,17 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 	//objectLock.RLock()
 	//defer objectLock.RUnlock()
 
-	objInfo, err := objectAPI.GetObjectInfo(bucket, object)
+	// Version of the object to read, the latest if not set.
+	versionID := r.URL.Query().Get("versionId")
+
+	objInfo, err := objectAPI.GetObjectVersionInfo(bucket, object, versionID)
 	if err != nil {
 		println(err, "Unable to fetch object info.")
-		apiErr := toAPIErrorCode(err)
+		apiErr := toObjectVersionAPIErrorCode(err, versionID)
 		if apiErr == ErrNoSuchKey {
 			apiErr = errAllowableObjectNotFound(bucket, r)
 		}
+		setDeleteMarkerHeaders(w, err)
 		writeErrorResponse(w, apiErr, r.URL)
 		return
 	}
@@ -130,8 +134,10 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return w.Write(p)
 	})
 
-	// Reads the object at startOffset and writes to mw.
-	if err = objectAPI.GetObject(bucket, object, startOffset, length, writer); err != nil {
+	// Reads the object at startOffset and writes to mw, at the
+	// download rate allowed for the bucket and the access key.
+	throttledWriter := globalBandwidthLimiter.DownloadWriter(bucket, getRequestAccessKey(r), writer)
+	if err = objectAPI.GetObjectVersion(bucket, object, versionID, startOffset, length, throttledWriter); err != nil {
 		println(err, "Unable to write to client.")
 		if !dataWritten {
 			// Error response only if no data has been written to client yet. i.e if
@@ -189,13 +195,17 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 	//objectLock.RLock()
 	//defer objectLock.RUnlock()
 
-	objInfo, err := objectAPI.GetObjectInfo(bucket, object)
+	// Version of the object to read, the latest if not set.
+	versionID := r.URL.Query().Get("versionId")
+
+	objInfo, err := objectAPI.GetObjectVersionInfo(bucket, object, versionID)
 	if err != nil {
 		println(err, "Unable to fetch object info.")
-		apiErr := toAPIErrorCode(err)
+		apiErr := toObjectVersionAPIErrorCode(err, versionID)
 		if apiErr == ErrNoSuchKey {
 			apiErr = errAllowableObjectNotFound(bucket, r)
 		}
+		setDeleteMarkerHeaders(w, err)
 		writeErrorResponseHeadersOnly(w, apiErr)
 		return
 	}
@@ -316,6 +326,16 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	// Make sure we hex encode md5sum here.
 	metadata["etag"] = hex.EncodeToString(md5Bytes)
 
+	// Save the object lock and the tags of the new version.
+	if s3Error := extractObjectLockMetadata(bucket, r.Header, metadata); s3Error != ErrNone {
+		writeErrorResponse(w, s3Error, r.URL)
+		return
+	}
+	if s3Error := extractObjectTagging(r.Header, metadata); s3Error != ErrNone {
+		writeErrorResponse(w, s3Error, r.URL)
+		return
+	}
+
 	sha256sum := ""
 
 	// Lock the object.
@@ -323,6 +343,10 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	//objectLock.Lock()
 	//defer objectLock.Unlock()
 
+	// Receive the object at the upload rate allowed for the bucket
+	// and the access key.
+	r.Body = globalBandwidthLimiter.UploadReader(bucket, getRequestAccessKey(r), r.Body)
+
 	var objInfo ObjectInfo
 	objInfo, err = objectAPI.PutObject(bucket, object, size, r.Body, metadata, sha256sum)
 	//switch rAuthType {
@@ -374,6 +398,9 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	w.Header().Set("ETag", "\""+objInfo.ETag+"\"")
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
 	writeSuccessResponseHeadersOnly(w)
 
 	// Get host and port from Request.RemoteAddr.
@@ -395,6 +422,185 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 }
 
 
+// CopyObjectHandler - Copy Object
+// ----------
+// This implementation of the PUT operation adds an object to a bucket
+// while reading the object from another source, x-amz-copy-source may
+// name a version of the source with ?versionId=.
+func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dstBucket := vars["bucket"]
+	dstObject := vars["object"]
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	//if s3Error := checkRequestAuthType(r, dstBucket, "s3:PutObject", serverConfig.GetRegion()); s3Error != ErrNone {
+	//	writeErrorResponse(w, s3Error, r.URL)
+	//	return
+	//}
+
+	// Copy source path, optionally with the version of the source.
+	cpSrcURL, err := url.Parse(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeErrorResponse(w, ErrInvalidCopySource, r.URL)
+		return
+	}
+	srcVersionID := cpSrcURL.Query().Get("versionId")
+
+	srcBucket, srcObject := path2BucketAndObject(cpSrcURL.Path)
+	// If source object is empty or bucket is empty, reply back invalid copy source.
+	if srcObject == "" || srcBucket == "" {
+		writeErrorResponse(w, ErrInvalidCopySource, r.URL)
+		return
+	}
+
+	// Check if metadata directive is valid.
+	if !isMetadataDirectiveValid(r.Header) {
+		writeErrorResponse(w, ErrInvalidMetadataDirective, r.URL)
+		return
+	}
+
+	// Check if tagging directive is valid.
+	if !isTaggingDirectiveValid(r.Header) {
+		writeErrorResponse(w, ErrInvalidTaggingDirective, r.URL)
+		return
+	}
+
+	cpSrcDstSame := srcBucket == dstBucket && srcObject == dstObject
+	// Copying the latest version of an object to itself has to replace
+	// its metadata, copying an older version restores it.
+	if cpSrcDstSame && srcVersionID == "" && !isMetadataReplace(r.Header) {
+		writeErrorResponse(w, ErrInvalidCopyDest, r.URL)
+		return
+	}
+
+	objInfo, err := objectAPI.GetObjectVersionInfo(srcBucket, srcObject, srcVersionID)
+	if err != nil {
+		println(err, "Unable to fetch object info.")
+		writeErrorResponse(w, toObjectVersionAPIErrorCode(err, srcVersionID), r.URL)
+		return
+	}
+
+	/// maximum Upload size for object in a single CopyObject operation.
+	if isMaxObjectSize(objInfo.Size) {
+		writeErrorResponse(w, ErrEntityTooLarge, r.URL)
+		return
+	}
+
+	// Metadata of the source is copied unless it is replaced.
+	var newMetadata map[string]string
+	if isMetadataReplace(r.Header) {
+		newMetadata = extractMetadataFromHeader(r.Header)
+	} else {
+		newMetadata = make(map[string]string)
+		for k, v := range objInfo.UserDefined {
+			newMetadata[k] = v
+		}
+	}
+	// Make sure to remove saved etag, CopyObject calculates a new one.
+	delete(newMetadata, "etag")
+
+	// Object lock of the source is not copied, the new version is
+	// locked as requested or by the default retention.
+	removeObjectLockMetadata(newMetadata)
+	if s3Error := extractObjectLockMetadata(dstBucket, r.Header, newMetadata); s3Error != ErrNone {
+		writeErrorResponse(w, s3Error, r.URL)
+		return
+	}
+
+	// Tags of the source are copied unless they are replaced,
+	// independently of the metadata directive.
+	delete(newMetadata, amzObjectTagging)
+	if isTaggingReplace(r.Header) {
+		if s3Error := extractObjectTagging(r.Header, newMetadata); s3Error != ErrNone {
+			writeErrorResponse(w, s3Error, r.URL)
+			return
+		}
+	} else if tagging, ok := objInfo.UserDefined[amzObjectTagging]; ok {
+		newMetadata[amzObjectTagging] = tagging
+	}
+
+	srcInfo := objInfo
+	objInfo, err = objectAPI.CopyObjectVersion(srcBucket, srcObject, srcVersionID, dstBucket, dstObject, newMetadata)
+	if err != nil {
+		println(err, "Unable to copy object. %s", r.URL.Path)
+		writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+		return
+	}
+
+	if srcInfo.VersionID != "" {
+		w.Header().Set(amzCopySourceVersionID, srcInfo.VersionID)
+	}
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
+	response := generateCopyObjectResponse(objInfo.ETag, objInfo.ModTime)
+	writeSuccessResponseXML(w, encodeResponse(response))
+}
+
+// DeleteObjectHandler - delete an object
+// ----------
+// In versioned buckets the latest version is replaced by a delete
+// marker, ?versionId= permanently deletes a version instead.
+func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	objectAPI := api.ObjectAPI()
+	if objectAPI == nil {
+		writeErrorResponse(w, ErrServerNotInitialized, r.URL)
+		return
+	}
+
+	//if s3Error := checkRequestAuthType(r, bucket, "s3:DeleteObject", serverConfig.GetRegion()); s3Error != ErrNone {
+	//	writeErrorResponse(w, s3Error, r.URL)
+	//	return
+	//}
+
+	bypassGovernance := isGovernanceBypassRequested(r)
+	objInfo, err := objectAPI.DeleteObjectVersion(bucket, object, r.URL.Query().Get("versionId"), bypassGovernance)
+	if err != nil {
+		// Deleting an object which does not exist is not an error.
+		if _, ok := errorCause(err).(ObjectNotFound); !ok {
+			println(err, "Unable to delete object. %s", r.URL.Path)
+			writeErrorResponse(w, toAPIErrorCode(err), r.URL)
+			return
+		}
+	}
+
+	if objInfo.VersionID != "" {
+		w.Header().Set(amzVersionID, objInfo.VersionID)
+	}
+	if objInfo.DeleteMarker {
+		w.Header().Set(amzDeleteMarker, "true")
+	}
+	writeSuccessNoContent(w)
+}
+
+// setDeleteMarkerHeaders - marks error responses for versions which
+// are delete markers.
+func setDeleteMarkerHeaders(w http.ResponseWriter, err error) {
+	if e, ok := errorCause(err).(VersionIsDeleteMarker); ok {
+		w.Header().Set(amzDeleteMarker, "true")
+		w.Header().Set(amzVersionID, e.VersionID)
+	}
+}
+
+// toObjectVersionAPIErrorCode - converts errors reading a version of
+// an object, reading the latest version of an object which is a delete
+// marker is not found rather than not allowed.
+func toObjectVersionAPIErrorCode(err error, versionID string) APIErrorCode {
+	if _, ok := errorCause(err).(VersionIsDeleteMarker); ok && versionID == "" {
+		return ErrNoSuchKey
+	}
+	return toAPIErrorCode(err)
+}
+
 func errAllowableObjectNotFound(bucket string, r *http.Request) APIErrorCode {
 	if getRequestAuthType(r) == authTypeAnonymous {
 		//we care about the bucket as a whole, not a particular resource
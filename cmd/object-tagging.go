@@ -0,0 +1,97 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Object tagging related headers, the tags of a version are saved
+// url encoded in its metadata under the tagging header.
+const (
+	amzObjectTagging    = "X-Amz-Tagging"
+	amzTaggingDirective = "X-Amz-Tagging-Directive"
+)
+
+// Limits of object tags as per S3 spec.
+const (
+	maxObjectTags        = 10
+	maxObjectTagKeyLen   = 128
+	maxObjectTagValueLen = 256
+)
+
+// parseObjectTags - parses url encoded object tags, returns
+// errInvalidArgument if the tags do not respect the S3 limits.
+func parseObjectTags(tagging string) (url.Values, error) {
+	tags, err := url.ParseQuery(tagging)
+	if err != nil {
+		return nil, traceError(errInvalidArgument)
+	}
+	if len(tags) > maxObjectTags {
+		return nil, traceError(errInvalidArgument)
+	}
+	for key, values := range tags {
+		// Keys must be unique.
+		if len(values) != 1 {
+			return nil, traceError(errInvalidArgument)
+		}
+		if key == "" || len(key) > maxObjectTagKeyLen || len(values[0]) > maxObjectTagValueLen {
+			return nil, traceError(errInvalidArgument)
+		}
+	}
+	return tags, nil
+}
+
+// getObjectTags - returns the tags saved in the metadata of a version.
+func getObjectTags(meta map[string]string) url.Values {
+	tags, err := parseObjectTags(meta[amzObjectTagging])
+	if err != nil {
+		return url.Values{}
+	}
+	return tags
+}
+
+// isTaggingDirectiveValid - check if tagging-directive is valid, a
+// missing tagging-directive is treated as 'COPY'.
+func isTaggingDirectiveValid(h http.Header) bool {
+	if _, ok := h[http.CanonicalHeaderKey(amzTaggingDirective)]; !ok {
+		return true
+	}
+	directive := h.Get(amzTaggingDirective)
+	return directive == "COPY" || directive == "REPLACE"
+}
+
+// Check if the tagging REPLACE is requested.
+func isTaggingReplace(h http.Header) bool {
+	return h.Get(amzTaggingDirective) == "REPLACE"
+}
+
+// extractObjectTagging - validates the tags of a request writing a new
+// version of an object and saves them in metadata.
+func extractObjectTagging(header http.Header, metadata map[string]string) APIErrorCode {
+	tagging := header.Get(amzObjectTagging)
+	if tagging == "" {
+		return ErrNone
+	}
+	tags, err := parseObjectTags(tagging)
+	if err != nil {
+		return ErrInvalidTag
+	}
+	metadata[amzObjectTagging] = tags.Encode()
+	return ErrNone
+}
@@ -0,0 +1,233 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Supported profiler types.
+const (
+	profilerCPU       = "cpu"
+	profilerMem       = "mem"
+	profilerBlock     = "block"
+	profilerMutex     = "mutex"
+	profilerGoroutine = "goroutine"
+)
+
+var profilerTypes = []string{profilerCPU, profilerMem, profilerBlock, profilerMutex, profilerGoroutine}
+
+var (
+	errInvalidProfilerType = errors.New("Invalid profiler type, supported types are " + strings.Join(profilerTypes, ", "))
+	errProfilerRunning     = errors.New("Profiling is already running")
+	errProfilerNotRunning  = errors.New("Profiling is not running")
+	errProfilerNoProfiles  = errors.New("No profiles recorded yet")
+)
+
+// parseProfilerTypes - parses a comma separated list of profiler
+// types, e.g. "cpu,mem".
+func parseProfilerTypes(s string) ([]string, error) {
+	var types []string
+	for _, profilerType := range strings.Split(s, ",") {
+		profilerType = strings.TrimSpace(profilerType)
+		if !contains(profilerTypes, profilerType) {
+			return nil, errInvalidProfilerType
+		}
+		if !contains(types, profilerType) {
+			types = append(types, profilerType)
+		}
+	}
+	return types, nil
+}
+
+// Starts recording a profile of the given type into dir, the
+// returned function finishes the profile.
+func startProfile(profilerType, dir string) (stopFn func() error, err error) {
+	f, err := os.Create(filepath.Join(dir, profilerType+".pprof"))
+	if err != nil {
+		return nil, err
+	}
+	// Writes the named runtime profile and closes the file.
+	writeProfile := func(name string) error {
+		if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	switch profilerType {
+	case profilerCPU:
+		if err = pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		}, nil
+	case profilerMem:
+		// Allocations are sampled all the time, the heap
+		// profile is taken when profiling stops.
+		return func() error {
+			runtime.GC()
+			return writeProfile("heap")
+		}, nil
+	case profilerBlock:
+		runtime.SetBlockProfileRate(1)
+		return func() error {
+			runtime.SetBlockProfileRate(0)
+			return writeProfile("block")
+		}, nil
+	case profilerMutex:
+		old := runtime.SetMutexProfileFraction(1)
+		return func() error {
+			runtime.SetMutexProfileFraction(old)
+			return writeProfile("mutex")
+		}, nil
+	case profilerGoroutine:
+		// Goroutines are captured when profiling stops.
+		return func() error {
+			return writeProfile("goroutine")
+		}, nil
+	}
+	f.Close()
+	return nil, errInvalidProfilerType
+}
+
+// profiler - records runtime profiles on demand. Every profiling
+// session writes its profiles into a new temporary directory which
+// is kept until the next session starts.
+type profiler struct {
+	mu      sync.Mutex
+	dir     string
+	stopFns []func() error
+}
+
+var globalProfiler = &profiler{}
+
+// Start - starts profiling of all the given types.
+func (p *profiler) Start(types []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopFns != nil {
+		return errProfilerRunning
+	}
+
+	dir, err := ioutil.TempDir("", "minio-profile-")
+	if err != nil {
+		return err
+	}
+	var stopFns []func() error
+	for _, profilerType := range types {
+		stopFn, err := startProfile(profilerType, dir)
+		if err != nil {
+			for _, stopFn = range stopFns {
+				stopFn()
+			}
+			os.RemoveAll(dir)
+			return err
+		}
+		stopFns = append(stopFns, stopFn)
+	}
+
+	if p.dir != "" {
+		os.RemoveAll(p.dir)
+	}
+	p.dir, p.stopFns = dir, stopFns
+	return nil
+}
+
+// Stop - stops profiling, the recorded profiles are kept on disk.
+func (p *profiler) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopFns == nil {
+		return errProfilerNotRunning
+	}
+	var err error
+	for _, stopFn := range p.stopFns {
+		if serr := stopFn(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	p.stopFns = nil
+	return err
+}
+
+// Dir - returns the directory holding the profiles of the last session.
+func (p *profiler) Dir() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dir
+}
+
+// WriteArchive - writes the profiles of the last stopped session
+// as a zip archive into w.
+func (p *profiler) WriteArchive(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopFns != nil {
+		return errProfilerRunning
+	}
+	if p.dir == "" {
+		return errProfilerNoProfiles
+	}
+
+	names, err := filepath.Glob(filepath.Join(p.dir, "*.pprof"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	zipWriter := zip.NewWriter(w)
+	for _, name := range names {
+		if err = addFileToZip(zipWriter, name); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// Copies the file at path into the zip archive under its base name.
+func addFileToZip(zipWriter *zip.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := zipWriter.Create(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,197 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PROXY protocol as sent by HAProxy and other TCP load balancers in
+// front of the server, specified at
+// https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+const (
+	// Longest possible version 1 header, including CRLF.
+	proxyV1MaxHeaderLen = 107
+	// Fixed part of a version 2 header.
+	proxyV2HeaderLen = 16
+)
+
+var (
+	proxyV1Prefix    = []byte("PROXY")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+var (
+	errProxyHeaderInvalid   = errors.New("Invalid PROXY protocol header")
+	errProxyHeaderUntrusted = errors.New("PROXY protocol header sent by an untrusted peer")
+)
+
+// parseTrustedProxies - parses a comma separated list of CIDRs, single
+// IP addresses are trusted on their own.
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, errors.New("Invalid IP address " + cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// isTrustedProxy - returns true if addr is allowed to send PROXY
+// protocol headers.
+func isTrustedProxy(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range globalTrustedProxies {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProxyHeader - consumes a PROXY protocol header at the start of
+// the connection, if there is one, and returns the client address it
+// carries. A nil address is returned if there is no header or the
+// header carries no address, e.g. for health checks of the balancer.
+func (c *ConnMux) readProxyHeader() (net.Addr, error) {
+	// 5 bytes tell both versions apart from HTTP and TLS, peeking
+	// more would block on clients sending very short requests.
+	buf, err := c.peeker.Peek(len(proxyV1Prefix))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(buf, proxyV1Prefix):
+		return c.readProxyV1Header()
+	case bytes.Equal(buf, proxyV2Signature[:len(proxyV1Prefix)]):
+		return c.readProxyV2Header()
+	}
+	return nil, nil
+}
+
+// Parses a human readable version 1 header, e.g.
+// "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n".
+func (c *ConnMux) readProxyV1Header() (net.Addr, error) {
+	line, err := c.peeker.ReadSlice('\n')
+	if err != nil || len(line) > proxyV1MaxHeaderLen || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errProxyHeaderInvalid
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, errProxyHeaderInvalid
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		// The balancer could not tell the client address.
+		return nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, errProxyHeaderInvalid
+	}
+	if len(fields) != 6 {
+		return nil, errProxyHeaderInvalid
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, errProxyHeaderInvalid
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, errProxyHeaderInvalid
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// Parses a binary version 2 header.
+func (c *ConnMux) readProxyV2Header() (net.Addr, error) {
+	header, err := c.peeker.Peek(proxyV2HeaderLen)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(proxyV2Signature)], proxyV2Signature) || header[12]>>4 != 2 {
+		return nil, errProxyHeaderInvalid
+	}
+	command, family := header[12]&0x0f, header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+	if _, err = c.peeker.Discard(proxyV2HeaderLen); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, length)
+	if _, err = io.ReadFull(c.peeker, payload); err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL, sent by the balancer on its own behalf.
+		return nil, nil
+	case 0x1:
+		// PROXY
+	default:
+		return nil, errProxyHeaderInvalid
+	}
+	switch family {
+	case 0x11:
+		// TCP over IPv4: source and destination addresses, then ports.
+		if length < 12 {
+			return nil, errProxyHeaderInvalid
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:4]),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+	case 0x21:
+		// TCP over IPv6.
+		if length < 36 {
+			return nil, errProxyHeaderInvalid
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	}
+	// Other transports carry no usable client address.
+	return nil, nil
+}
@@ -0,0 +1,178 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+// readerConn - net.Conn reading from a fixed reader.
+type readerConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c readerConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// Returns a ConnMux reading data.
+func newTestConnMux(data string) *ConnMux {
+	return NewConnMux(readerConn{r: strings.NewReader(data)})
+}
+
+// Returns a version 2 header with the given command, family and payload.
+func newProxyV2Header(command, family byte, payload []byte) string {
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:16], uint16(len(payload)))
+	return string(append(header, payload...))
+}
+
+// Tests the client address and the remaining data of a connection
+// starting with a PROXY protocol header.
+func testProxyHeader(t *testing.T, i int, data string, expectedAddr string, expectedErr error) {
+	c := newTestConnMux(data + "GET / HTTP/1.1\r\n")
+	addr, err := c.readProxyHeader()
+	if err != expectedErr {
+		t.Fatalf("Test %d: expected error %v, got %v", i+1, expectedErr, err)
+	}
+	if err != nil {
+		return
+	}
+	if addr == nil {
+		if expectedAddr != "" {
+			t.Fatalf("Test %d: expected address %s, got none", i+1, expectedAddr)
+		}
+	} else if addr.String() != expectedAddr {
+		t.Fatalf("Test %d: expected address %s, got %s", i+1, expectedAddr, addr)
+	}
+	rest, err := ioutil.ReadAll(c.peeker)
+	if err != nil {
+		t.Fatalf("Test %d: unable to read the request: %v", i+1, err)
+	}
+	if string(rest) != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("Test %d: expected the request after the header, got %q", i+1, rest)
+	}
+}
+
+func TestReadProxyV1Header(t *testing.T) {
+	testCases := []struct {
+		header       string
+		expectedAddr string
+		expectedErr  error
+	}{
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", "192.168.0.1:56324", nil},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n", "[2001:db8::1]:56324", nil},
+		{"PROXY UNKNOWN\r\n", "", nil},
+		{"PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n", "", nil},
+		// No header at all.
+		{"", "", nil},
+		// Address not matching the family.
+		{"PROXY TCP4 2001:db8::1 2001:db8::2 56324 443\r\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP6 192.168.0.1 192.168.0.11 56324 443\r\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP4 192.168.0 192.168.0.11 56324 443\r\n", "", errProxyHeaderInvalid},
+		{"PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 65536 443\r\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n", "", errProxyHeaderInvalid},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443 " + strings.Repeat("x", proxyV1MaxHeaderLen) + "\r\n", "", errProxyHeaderInvalid},
+	}
+	for i, testCase := range testCases {
+		testProxyHeader(t, i, testCase.header, testCase.expectedAddr, testCase.expectedErr)
+	}
+}
+
+func TestReadProxyV2Header(t *testing.T) {
+	ipv4Payload := []byte{
+		192, 168, 0, 1, // source address
+		192, 168, 0, 11, // destination address
+		0xdc, 0x04, // source port 56324
+		0x01, 0xbb, // destination port 443
+	}
+	ipv6Payload := make([]byte, 36)
+	copy(ipv6Payload, net.ParseIP("2001:db8::1"))
+	copy(ipv6Payload[16:], net.ParseIP("2001:db8::2"))
+	binary.BigEndian.PutUint16(ipv6Payload[32:], 56324)
+	binary.BigEndian.PutUint16(ipv6Payload[34:], 443)
+
+	badVersion := []byte(newProxyV2Header(0x1, 0x11, ipv4Payload))
+	badVersion[12] = 0x11
+
+	testCases := []struct {
+		header       string
+		expectedAddr string
+		expectedErr  error
+	}{
+		{newProxyV2Header(0x1, 0x11, ipv4Payload), "192.168.0.1:56324", nil},
+		{newProxyV2Header(0x1, 0x21, ipv6Payload), "[2001:db8::1]:56324", nil},
+		// TLVs following the addresses are skipped.
+		{newProxyV2Header(0x1, 0x11, append(ipv4Payload, 0x04, 0x00, 0x01, 0x00)), "192.168.0.1:56324", nil},
+		// LOCAL command and transports without a usable address.
+		{newProxyV2Header(0x0, 0x11, ipv4Payload), "", nil},
+		{newProxyV2Header(0x0, 0x00, nil), "", nil},
+		{newProxyV2Header(0x1, 0x12, ipv4Payload), "", nil},
+		{newProxyV2Header(0x1, 0x31, make([]byte, 216)), "", nil},
+		// Addresses shorter than the family requires.
+		{newProxyV2Header(0x1, 0x11, ipv4Payload[:8]), "", errProxyHeaderInvalid},
+		{newProxyV2Header(0x1, 0x21, ipv6Payload[:32]), "", errProxyHeaderInvalid},
+		{newProxyV2Header(0x2, 0x11, ipv4Payload), "", errProxyHeaderInvalid},
+		{string(badVersion), "", errProxyHeaderInvalid},
+	}
+	for i, testCase := range testCases {
+		testProxyHeader(t, i, testCase.header, testCase.expectedAddr, testCase.expectedErr)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	testCases := []struct {
+		proxies   string
+		trusted   []string
+		untrusted []string
+		shouldErr bool
+	}{
+		{"10.0.0.0/8, 192.168.1.10", []string{"10.1.2.3", "192.168.1.10"}, []string{"192.168.1.11", "11.0.0.1"}, false},
+		{"2001:db8::/32,::1", []string{"2001:db8::5", "::1"}, []string{"2001:db9::5", "127.0.0.1"}, false},
+		{"", nil, []string{"127.0.0.1"}, false},
+		{"10.0.0.0/33", nil, nil, true},
+		{"localhost", nil, nil, true},
+	}
+	defer func(proxies []*net.IPNet) { globalTrustedProxies = proxies }(globalTrustedProxies)
+
+	for i, testCase := range testCases {
+		proxies, err := parseTrustedProxies(testCase.proxies)
+		if testCase.shouldErr != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, got %v", i+1, testCase.shouldErr, err)
+		}
+		globalTrustedProxies = proxies
+		for _, ip := range testCase.trusted {
+			if !isTrustedProxy(&net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}) {
+				t.Errorf("Test %d: expected %s to be trusted", i+1, ip)
+			}
+		}
+		for _, ip := range testCase.untrusted {
+			if isTrustedProxy(&net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}) {
+				t.Errorf("Test %d: expected %s to be untrusted", i+1, ip)
+			}
+		}
+	}
+}
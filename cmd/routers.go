@@ -37,10 +37,32 @@ func configureServerHandler(endpoints EndpointList) (http.Handler, error) {
 
 	// Initialize distributed NS lock.
 
+	// Add health check router.
+	registerHealthRouter(mux)
+
+	// Add metrics router.
+	registerMetricsRouter(mux)
+
+	// Add Admin router.
+	registerAdminRouter(mux)
+
 	// Add API router.
 	registerAPIRouter(mux)
 
-	var handlerFns = []HandlerFunc{}
+	var handlerFns = []HandlerFunc{
+		// Evaluate cross origin requests against the bucket CORS configuration.
+		setCorsHandler,
+		// Limit the number of API calls in progress.
+		setAdmissionControlHandler,
+		// Record access logs of buckets with logging enabled.
+		setBucketLoggingHandler,
+		// Audit all the API calls.
+		setAuditHandler,
+		// Gather HTTP statistics of all the API calls.
+		setHTTPStatsHandler,
+		// Trace all the API calls while anyone is tracing.
+		setTraceHandler,
+	}
 
 	// Register rest of the handlers.
 	return registerHandlers(mux, handlerFns...), nil
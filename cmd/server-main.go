@@ -18,16 +18,26 @@ package cmd
 
 import (
 "errors"
+"fmt"
+"os"
 "runtime"
+"strconv"
+"strings"
+"time"
+	humanize "github.com/dustin/go-humanize"
 	"shareos/cli"
 	"path/filepath"
 )
 
 var serverFlags = []cli.Flag{
-	cli.StringFlag{
+	cli.StringSliceFlag{
 		Name:  "address",
-		Value: ":9000",
-		Usage: "Bind to a specific ADDRESS:PORT, ADDRESS can be an IP or hostname.",
+		Usage: "Bind to a specific ADDRESS:PORT, ADDRESS can be an IP or hostname, or to a Unix domain socket unix:///PATH. Repeat to bind to several addresses, defaults to :9000.",
+	},
+	cli.StringFlag{
+		Name:  "socket-mode",
+		Value: "0660",
+		Usage: "Permissions of the Unix domain sockets.",
 	},
 }
 
@@ -53,6 +63,65 @@ ENVIRONMENT VARIABLES:
   BROWSER:
      MINIO_BROWSER: To disable web browser access, set this value to "off".
 
+  REGION:
+     MINIO_REGION: To set custom region.
+
+  AUDIT:
+     Audit settings set through the environment replace the ones of the config file.
+     MINIO_AUDIT_FILE: Append audit entries of all API calls as JSON lines to this file.
+     MINIO_AUDIT_WEBHOOK_ENDPOINT: Post audit entries of all API calls to this HTTP endpoint.
+     MINIO_AUDIT_WEBHOOK_QUEUE_SIZE: Number of audit entries buffered for the webhook, defaults to 10000.
+     MINIO_AUDIT_REQUEST_HEADERS: Comma separated list of request headers to record.
+     MINIO_AUDIT_RESPONSE_HEADERS: Comma separated list of response headers to record.
+
+  SHUTDOWN:
+     MINIO_SHUTDOWN_TIMEOUT: Time to wait for in-flight requests to finish on shutdown, defaults to 30s.
+
+  REQUESTS:
+     MINIO_API_REQUESTS_READ: Maximum number of GET and HEAD calls in progress, defaults to 512.
+     MINIO_API_REQUESTS_WRITE: Maximum number of PUT, POST and DELETE calls in progress, defaults to 256.
+     MINIO_API_REQUESTS_LIST: Maximum number of list calls in progress, defaults to 64.
+     MINIO_API_REQUESTS_DEADLINE: Time a call waits for its turn before SlowDown is returned, defaults to 10s.
+     A limit of 0 disables it. Waiting calls of all access keys are served in turn.
+
+  CONNECTIONS:
+     MINIO_HTTP_READ_HEADER_TIMEOUT: Time a client may take to send the request headers, defaults to 10s.
+     MINIO_HTTP_IDLE_TIMEOUT: Time an idle keep-alive connection is kept open, defaults to 30s.
+     MINIO_HTTP_WRITE_TIMEOUT: Time a client may take to accept any response data, defaults to 30s.
+     MINIO_HTTP_MAX_HEADER_BYTES: Maximum size of the request headers, defaults to 64KiB.
+     MINIO_HTTP_MAX_CONNS_PER_IP: Maximum number of open connections per client IP, defaults to 512, 0 disables the limit.
+     MINIO_HTTP_MIN_BODY_RATE: Minimum rate per second of request body data averaged over 30s, defaults to 1KiB,
+                               0 disables the limit.
+     Clients dropped for being too slow are logged.
+
+  HTTP2:
+     MINIO_HTTP2_MAX_CONCURRENT_STREAMS: Maximum number of concurrent requests per HTTP/2 connection, defaults to 250.
+
+  PROXY:
+     MINIO_PROXY_TRUSTED_CIDRS: Comma separated list of CIDRs of the load balancers allowed to send the client
+                                address through the PROXY protocol version 1 or 2.
+
+  LIFECYCLE:
+     MINIO_LIFECYCLE_SCAN_INTERVAL: Time between two scans of all buckets applying their lifecycle rules, defaults to 1h.
+     MINIO_LIFECYCLE_SCAN_DELAY: Pause after each object scanned to limit the disk load of a scan, defaults to 1ms.
+     MINIO_LIFECYCLE_TIER_PATH: Directory objects are moved to by lifecycle transition rules, transitions are
+                                disabled if not set.
+
+  DATA USAGE:
+     MINIO_DATA_USAGE_CRAWL_INTERVAL: Time between two crawls of all buckets recounting their data usage, overrides "cache.usageExpiry" of the config, defaults to 1h.
+     MINIO_DATA_USAGE_CRAWL_DELAY: Pause after each directory crawled to limit the disk load of a crawl, defaults to 1ms.
+
+  PROFILING:
+     MINIO_PROFILER: Comma separated list of profiles to record from startup until the server exits,
+                     supported profiles are cpu, mem, block, mutex and goroutine.
+
+CERTIFICATES:
+  TLS is enabled when the certs directory of the config directory holds a certificate.
+     certs/public.crt, certs/private.key: Default certificate.
+     certs/DOMAIN/public.crt, certs/DOMAIN/private.key: Certificate served to clients requesting DOMAIN through SNI.
+     certs/CAs/: Additional CA certificates trusted for outbound connections.
+  Certificates are reloaded when their files change, changes to certs/CAs/ require a restart.
+
 EXAMPLES:
   1. Start minio server on "/home/shared" directory.
       $ {{.HelpName}} /home/shared
@@ -60,12 +129,15 @@ EXAMPLES:
   2. Start minio server bound to a specific ADDRESS:PORT.
       $ {{.HelpName}} --address 192.168.1.101:9000 /home/shared
 
-  3. Start erasure coded minio server on a 12 disks server.
+  3. Start minio server on port 9000 and on a Unix domain socket for local clients.
+      $ {{.HelpName}} --address :9000 --address unix:///run/minio.sock /home/shared
+
+  4. Start erasure coded minio server on a 12 disks server.
       $ {{.HelpName}} /mnt/export1/ /mnt/export2/ /mnt/export3/ /mnt/export4/ \
           /mnt/export5/ /mnt/export6/ /mnt/export7/ /mnt/export8/ /mnt/export9/ \
           /mnt/export10/ /mnt/export11/ /mnt/export12/
 
-  4. Start erasure coded distributed minio server on a 4 node setup with 1 drive each. Run following commands on all the 4 nodes.
+  5. Start erasure coded distributed minio server on a 4 node setup with 1 drive each. Run following commands on all the 4 nodes.
       $ export MINIO_ACCESS_KEY=minio
       $ export MINIO_SECRET_KEY=miniostorage
       $ {{.HelpName}} http://192.168.1.11/mnt/export/ http://192.168.1.12/mnt/export/ \
@@ -75,10 +147,6 @@ EXAMPLES:
 
 
 
-func initConfig() {
-
-}
-
 func serverHandleCmdArgs(ctx *cli.Context) {
 	// Set configuration directory.
 	{
@@ -98,12 +166,36 @@ func serverHandleCmdArgs(ctx *cli.Context) {
 		setConfigDir(configDirAbs)
 	}
 
-	// Server address.
-	serverAddr := ctx.String("address")
-	println(CheckLocalServerAddr(serverAddr), "Invalid address ‘%s’ in command line argument.", serverAddr)
+	// Server addresses, the first ADDRESS:PORT decides which of the
+	// endpoints are local.
+	var tcpAddrs, socketAddrs []string
+	for _, addr := range ctx.StringSlice("address") {
+		if isUnixSocketAddr(addr) {
+			if _, err := parseUnixSocketAddr(addr); err != nil {
+				println(err, "Invalid address ‘%s’ in command line argument.", addr)
+				os.Exit(1)
+			}
+			socketAddrs = append(socketAddrs, addr)
+			continue
+		}
+		println(CheckLocalServerAddr(addr), "Invalid address ‘%s’ in command line argument.", addr)
+		tcpAddrs = append(tcpAddrs, addr)
+	}
+	serverAddr := ":" + globalMinioPort
+	if len(tcpAddrs) > 0 {
+		serverAddr = tcpAddrs[0]
+	} else if len(socketAddrs) == 0 {
+		tcpAddrs = []string{serverAddr}
+	}
+
+	socketMode, err := parseUnixSocketMode(ctx.String("socket-mode"))
+	if err != nil {
+		println(err, "Invalid socket permissions in command line argument.")
+		os.Exit(1)
+	}
+	globalUnixSocketMode = socketMode
 
 	var setupType SetupType
-	var err error
 	globalMinioAddr, globalEndpoints, setupType, err = CreateEndpoints(serverAddr, ctx.Args()...)
 	println(err, "Invalid command line arguments server=‘%s’, args=%s", serverAddr, ctx.Args())
 	globalMinioHost, globalMinioPort = mustSplitHostPort(globalMinioAddr)
@@ -115,6 +207,17 @@ func serverHandleCmdArgs(ctx *cli.Context) {
 		println(checkPortAvailability(globalMinioPort), "Port %d already in use", globalMinioPort)
 	}
 
+	// Nodes of a distributed setup reach each other over TCP.
+	if len(tcpAddrs) == 0 && setupType == DistXLSetupType {
+		println(errInvalidArgument, "Distributed setups need an ADDRESS:PORT besides Unix domain sockets.")
+		os.Exit(1)
+	}
+	globalMinioAddrs = socketAddrs
+	if len(tcpAddrs) > 0 {
+		globalMinioAddrs = append(append([]string{globalMinioAddr}, tcpAddrs[1:]...), socketAddrs...)
+	}
+
+	globalSetupType = setupType
 	globalIsXL = (setupType == XLSetupType)
 	globalIsDistXL = (setupType == DistXLSetupType)
 	if globalIsDistXL {
@@ -125,8 +228,193 @@ func serverHandleCmdArgs(ctx *cli.Context) {
 }
 
 func serverHandleEnvVars() {
+	// Handle credentials set through the environment, otherwise the
+	// credentials of the config file are used.
+	cred, ok, err := getCredentialFromEnv()
+	if err != nil {
+		println(err, "Invalid access/secret key set in environment.")
+		os.Exit(1)
+	}
+	if ok {
+		globalIsEnvCreds = true
+		globalEnvCreds = cred
+	}
+
+	if browser := os.Getenv("MINIO_BROWSER"); browser != "" {
+		browserFlag, err := ParseBrowserFlag(browser)
+		if err != nil {
+			println(err, "Invalid MINIO_BROWSER set in environment.")
+			os.Exit(1)
+		}
+		globalIsEnvBrowser = true
+		globalIsBrowserEnabled = bool(browserFlag)
+	}
+
+	if region := os.Getenv("MINIO_REGION"); region != "" {
+		globalIsEnvRegion = true
+		globalServerRegion = region
+	}
+
+	// Audit sinks set through the environment replace the ones of
+	// the config file.
+	auditCfg, ok, err := auditConfigFromEnv()
+	if err != nil {
+		println(err, "Invalid audit logging set in environment.")
+		os.Exit(1)
+	}
+	if ok {
+		globalIsEnvAudit = true
+		globalEnvAuditConfig = auditCfg
+	}
+
+	if timeout := os.Getenv("MINIO_SHUTDOWN_TIMEOUT"); timeout != "" {
+		globalServerShutdownTimeout, err = time.ParseDuration(timeout)
+		if err != nil || globalServerShutdownTimeout < 0 {
+			println(err, "Invalid MINIO_SHUTDOWN_TIMEOUT set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	for _, limit := range []struct {
+		env   string
+		value *int
+	}{
+		{"MINIO_API_REQUESTS_READ", &globalAPIReadRequests},
+		{"MINIO_API_REQUESTS_WRITE", &globalAPIWriteRequests},
+		{"MINIO_API_REQUESTS_LIST", &globalAPIListRequests},
+	} {
+		if value := os.Getenv(limit.env); value != "" {
+			*limit.value, err = strconv.Atoi(value)
+			if err != nil || *limit.value < 0 {
+				println(err, "Invalid "+limit.env+" set in environment.")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if deadline := os.Getenv("MINIO_API_REQUESTS_DEADLINE"); deadline != "" {
+		globalAPIRequestsDeadline, err = time.ParseDuration(deadline)
+		if err != nil || globalAPIRequestsDeadline < 0 {
+			println(err, "Invalid MINIO_API_REQUESTS_DEADLINE set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	if streams := os.Getenv("MINIO_HTTP2_MAX_CONCURRENT_STREAMS"); streams != "" {
+		globalHTTP2MaxConcurrentStreams, err = strconv.Atoi(streams)
+		if err != nil || globalHTTP2MaxConcurrentStreams <= 0 {
+			println(err, "Invalid MINIO_HTTP2_MAX_CONCURRENT_STREAMS set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	// Timeouts of client connections, zero disables a timeout.
+	for _, timeout := range []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"MINIO_HTTP_READ_HEADER_TIMEOUT", &globalHTTPReadHeaderTimeout},
+		{"MINIO_HTTP_IDLE_TIMEOUT", &globalHTTPIdleTimeout},
+		{"MINIO_HTTP_WRITE_TIMEOUT", &globalHTTPWriteTimeout},
+	} {
+		if value := os.Getenv(timeout.env); value != "" {
+			*timeout.value, err = time.ParseDuration(value)
+			if err != nil || *timeout.value < 0 {
+				println(err, "Invalid "+timeout.env+" set in environment.")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if maxHeaderBytes := os.Getenv("MINIO_HTTP_MAX_HEADER_BYTES"); maxHeaderBytes != "" {
+		size, err := humanize.ParseBytes(maxHeaderBytes)
+		if err != nil || size == 0 || size > humanize.GiByte {
+			println(err, "Invalid MINIO_HTTP_MAX_HEADER_BYTES set in environment.")
+			os.Exit(1)
+		}
+		globalHTTPMaxHeaderBytes = int(size)
+	}
+
+	if maxConns := os.Getenv("MINIO_HTTP_MAX_CONNS_PER_IP"); maxConns != "" {
+		globalMaxConnsPerIP, err = strconv.Atoi(maxConns)
+		if err != nil || globalMaxConnsPerIP < 0 {
+			println(err, "Invalid MINIO_HTTP_MAX_CONNS_PER_IP set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	if minRate := os.Getenv("MINIO_HTTP_MIN_BODY_RATE"); minRate != "" {
+		rate, err := humanize.ParseBytes(minRate)
+		if err != nil {
+			println(err, "Invalid MINIO_HTTP_MIN_BODY_RATE set in environment.")
+			os.Exit(1)
+		}
+		globalHTTPMinBodyRate = int64(rate)
+	}
+
+	if interval := os.Getenv("MINIO_LIFECYCLE_SCAN_INTERVAL"); interval != "" {
+		globalLifecycleScanInterval, err = time.ParseDuration(interval)
+		if err != nil || globalLifecycleScanInterval <= 0 {
+			println(err, "Invalid MINIO_LIFECYCLE_SCAN_INTERVAL set in environment.")
+			os.Exit(1)
+		}
+	}
 
+	if delay := os.Getenv("MINIO_LIFECYCLE_SCAN_DELAY"); delay != "" {
+		globalLifecycleScanDelay, err = time.ParseDuration(delay)
+		if err != nil || globalLifecycleScanDelay < 0 {
+			println(err, "Invalid MINIO_LIFECYCLE_SCAN_DELAY set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	if tierPath := os.Getenv("MINIO_LIFECYCLE_TIER_PATH"); tierPath != "" {
+		globalLifecycleTier, err = newPosix(tierPath)
+		if err != nil {
+			println(err, "Invalid MINIO_LIFECYCLE_TIER_PATH set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	if interval := os.Getenv("MINIO_DATA_USAGE_CRAWL_INTERVAL"); interval != "" {
+		globalDataUsageCrawlInterval, err = time.ParseDuration(interval)
+		if err != nil || globalDataUsageCrawlInterval <= 0 {
+			println(err, "Invalid MINIO_DATA_USAGE_CRAWL_INTERVAL set in environment.")
+			os.Exit(1)
+		}
+		globalIsEnvDataUsageCrawlInterval = true
+	}
+
+	if delay := os.Getenv("MINIO_DATA_USAGE_CRAWL_DELAY"); delay != "" {
+		globalDataUsageCrawlDelay, err = time.ParseDuration(delay)
+		if err != nil || globalDataUsageCrawlDelay < 0 {
+			println(err, "Invalid MINIO_DATA_USAGE_CRAWL_DELAY set in environment.")
+			os.Exit(1)
+		}
+	}
 
+	if proxies := os.Getenv("MINIO_PROXY_TRUSTED_CIDRS"); proxies != "" {
+		globalTrustedProxies, err = parseTrustedProxies(proxies)
+		if err != nil {
+			println(err, "Invalid MINIO_PROXY_TRUSTED_CIDRS set in environment.")
+			os.Exit(1)
+		}
+	}
+
+	// Start profiling right away if requested, the profiles are
+	// written when the server exits.
+	if profiler := os.Getenv("MINIO_PROFILER"); profiler != "" {
+		types, err := parseProfilerTypes(profiler)
+		if err != nil {
+			println(err, "Invalid MINIO_PROFILER set in environment.")
+			os.Exit(1)
+		}
+		if err = globalProfiler.Start(types); err != nil {
+			println(err, "Unable to start profiling.")
+			os.Exit(1)
+		}
+		fmt.Printf("Profiling %s into %s\n", strings.Join(types, ","), globalProfiler.Dir())
+	}
 }
 
 // serverMain handler called for 'minio server' command.
@@ -144,16 +432,39 @@ func serverMain(ctx *cli.Context) {
 	serverHandleCmdArgs(ctx)
 	serverHandleEnvVars()
 
+	// Load the CAs trusted for outbound connections, before the
+	// configuration sets up the audit webhooks using them.
+	var err error
+	if globalRootCAs, err = loadRootCAs(getCADir()); err != nil {
+		println(err, "Unable to load the CA certificates.")
+		os.Exit(1)
+	}
 
 	initConfig()
 
+	// Load the bandwidth limits set through the admin API.
+	if err := initBandwidthLimits(); err != nil {
+		println(err, "Unable to load the bandwidth limits.")
+		os.Exit(1)
+	}
+
+	// Check and load TLS certificates.
+	if globalTLSCerts, err = newCertManager(getCertsDir()); err != nil {
+		println(err, "Unable to load the TLS certificates.")
+		os.Exit(1)
+	}
+	globalIsSSL = globalTLSCerts != nil
+	if globalIsSSL {
+		go globalTLSCerts.Watch()
+	}
+
 	// Configure server.
 	handler, err := configureServerHandler(globalEndpoints)
 	if err !=nil{
 		println(err, "Unable to configure one of server's RPC services.")
 	}
 	// Initialize a new HTTP server.
-	apiServer := NewServerMux(globalMinioAddr, handler)
+	apiServer := NewServerMux(globalMinioAddrs, handler)
 
 	// Initialize S3 Peers inter-node communication only in distributed setup.
 	//initGlobalS3Peers(globalEndpoints)
@@ -162,13 +473,22 @@ func serverMain(ctx *cli.Context) {
 	//initGlobalAdminPeers(globalEndpoints)
 
 	// Start server, automatically configures TLS if certs are available.
-	go func() {
-		cert, key := "", ""
-		//if globalIsSSL {
-		//	cert, key = getPublicCertFile(), getPrivateKeyFile()
-		//}
-		apiServer.ListenAndServe(cert, key)
-	}()
+	startServer := func() {
+		go func() {
+			if err := apiServer.ListenAndServe(globalTLSCerts); err != nil {
+				println(err, "Unable to listen on %s.", strings.Join(globalMinioAddrs, ", "))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// A restarted process serves only once initialized, its parent
+	// keeps serving on the same sockets meanwhile. Distributed setups
+	// need to serve to initialize the object layer.
+	serveEarly := globalIsDistXL || !isRestartedProcess()
+	if serveEarly {
+		startServer()
+	}
 
 	newObject, err := newObjectLayer(globalEndpoints)
 	if err != nil{
@@ -183,9 +503,16 @@ func serverMain(ctx *cli.Context) {
 	//// Prints the formatted startup message once object layer is initialized.
 	//apiEndpoints := getAPIEndpoints(apiServer.Addr)
 	//printStartupMessage(apiEndpoints)
-	//
-	//// Set uptime time after object layer has initialized.
-	//globalBootTime = UTCNow()
+
+	// Set uptime time after object layer has initialized.
+	globalBootTime = UTCNow()
+
+	if !serveEarly {
+		startServer()
+	}
+	if err == nil {
+		notifyParentReady()
+	}
 
 	// Waits on the server.
 	<-globalServiceDoneCh
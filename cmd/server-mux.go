@@ -18,12 +18,15 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -45,6 +48,10 @@ const (
 // HTTP2 PRI method.
 var httpMethodPRI = "PRI"
 
+// Number of bytes h2c clients send before their first frame is
+// complete, the connection preface followed by a frame header.
+const h2cFirstFrameLen = len("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n") + 9
+
 var defaultHTTP2Methods = []string{
 	httpMethodPRI,
 }
@@ -68,6 +75,18 @@ type ConnMux struct {
 	net.Conn
 	// To peek net.Conn incoming data
 	peeker *bufio.Reader
+	// Client address sent by a trusted proxy, nil if none.
+	proxyAddr net.Addr
+	// Set if the client sent data which was not answered yet.
+	pendingRead atomic.Bool
+	// Set for h2c connections, which manage their own timeouts.
+	ignoreReadDeadlines bool
+	// Bytes to read on h2c connections before the accept time read
+	// deadline is lifted.
+	h2cPendingBytes int
+	// Releases the connection from the per IP count.
+	releaseOnce sync.Once
+	releaseFn   func()
 }
 
 // NewConnMux - creates a new ConnMux instance
@@ -90,6 +109,19 @@ const (
 // to one of the default http methods. Returns error if there are any
 // errors in peeking over the connection.
 func (c *ConnMux) PeekProtocol() (string, error) {
+	// Load balancers in front of the server send the address of
+	// the client they forward first, only trusted ones may do so.
+	if isTrustedProxy(c.Conn.RemoteAddr()) {
+		proxyAddr, err := c.readProxyHeader()
+		if err != nil {
+			return "", err
+		}
+		c.proxyAddr = proxyAddr
+	} else if buf, err := c.peeker.Peek(len(proxyV1Prefix)); err == nil &&
+		(bytes.Equal(buf, proxyV1Prefix) || bytes.Equal(buf, proxyV2Signature[:len(proxyV1Prefix)])) {
+		return "", errProxyHeaderUntrusted
+	}
+
 	// Peek for HTTP verbs.
 	buf, err := c.peeker.Peek(maxHTTPVerbLen)
 	if err != nil {
@@ -117,45 +149,84 @@ func (c *ConnMux) PeekProtocol() (string, error) {
 }
 
 // Read reads from the tcp session for data sent by
-// the client, additionally sets deadline for 15 secs
-// after each successful read. Deadline cancels and
-// returns error if the client does not send any
-// data in 15 secs. Also keeps track of the total
+// the client, read deadlines are set by the http server.
+// Clients timing out in the middle of a request are
+// logged as slow clients. Also keeps track of the total
 // bytes received from the client.
 func (c *ConnMux) Read(b []byte) (n int, err error) {
 	// Update total incoming number of bytes.
 	defer func() {
-		//globalConnStats.incInputBytes(n)
+		globalConnStats.incInputBytes(n)
 	}()
 
 	n, err = c.peeker.Read(b)
-	if err != nil {
-		return n, err
+	if n > 0 {
+		c.pendingRead.Store(true)
 	}
+	// Clients stalling before their first h2c frame time out as
+	// any other connection, HTTP/2 times out idle ones afterwards.
+	if c.h2cPendingBytes > 0 && n > 0 {
+		if c.h2cPendingBytes -= n; c.h2cPendingBytes <= 0 {
+			c.Conn.SetReadDeadline(time.Time{})
+		}
+	}
+	// Timeouts of idle connections are expected.
+	if err != nil && isTimeoutError(err) && c.pendingRead.Swap(false) {
+		logSlowClient(c.RemoteAddr().String(), "while reading the request")
+	}
+	return n, err
+}
 
-	// Read deadline was already set previously, set again
-	// after a successful read operation for future read
-	// operations.
-	c.Conn.SetReadDeadline(UTCNow().Add(defaultTCPReadTimeout))
-
-	// Success.
-	return n, nil
+// SetReadDeadline sets the read deadline, ignored on h2c
+// connections.
+func (c *ConnMux) SetReadDeadline(t time.Time) error {
+	if c.ignoreReadDeadlines {
+		return nil
+	}
+	return c.Conn.SetReadDeadline(t)
 }
 
-// Write to the client over a tcp session, additionally
-// keeps track of the total bytes written by the server.
+// Write to the client over a tcp session, a write
+// fails if the client does not accept any data for
+// globalHTTPWriteTimeout. Additionally keeps track of
+// the total bytes written by the server.
 func (c *ConnMux) Write(b []byte) (n int, err error) {
 	// Update total outgoing number of bytes.
 	defer func() {
-		//globalConnStats.incOutputBytes(n)
+		globalConnStats.incOutputBytes(n)
 	}()
 
+	c.pendingRead.Store(false)
+	if globalHTTPWriteTimeout > 0 {
+		c.Conn.SetWriteDeadline(UTCNow().Add(globalHTTPWriteTimeout))
+	}
+
 	// Call the conn write wrapper.
-	return c.Conn.Write(b)
+	n, err = c.Conn.Write(b)
+	if err != nil && isTimeoutError(err) {
+		logSlowClient(c.RemoteAddr().String(), "while writing the response")
+	}
+	return n, err
+}
+
+// RemoteAddr returns the client address sent by a trusted proxy,
+// the address of the peer otherwise. Peers on Unix domain sockets
+// are unnamed, the path of the socket is returned instead.
+func (c *ConnMux) RemoteAddr() net.Addr {
+	if c.proxyAddr != nil {
+		return c.proxyAddr
+	}
+	if addr, ok := c.Conn.RemoteAddr().(*net.UnixAddr); ok && (addr == nil || addr.Name == "" || addr.Name == "@") {
+		return c.Conn.LocalAddr()
+	}
+	return c.Conn.RemoteAddr()
 }
 
 // Close closes the underlying tcp connection.
 func (c *ConnMux) Close() (err error) {
+	if c.releaseFn != nil {
+		c.releaseOnce.Do(c.releaseFn)
+	}
 	// Make sure that we always close a connection,
 	return c.Conn.Close()
 }
@@ -225,17 +296,9 @@ func newListenerMux(listener net.Listener, config *tls.Config) *ListenerMux {
 	}
 	// Start listening, wrap connections with tls when needed
 	go func() {
-		// Extract tcp listener.
-		tcpListener, ok := l.Listener.(*net.TCPListener)
-		if !ok {
-			l.acceptResCh <- ListenerMuxAcceptRes{err: errInvalidArgument}
-			return
-		}
-
 		// Loop for accepting new connections
 		for {
-			// Use accept TCP method to receive the connection.
-			conn, err := tcpListener.AcceptTCP()
+			conn, err := l.Listener.Accept()
 			if err != nil {
 				l.acceptResCh <- ListenerMuxAcceptRes{err: err}
 				continue
@@ -244,9 +307,12 @@ func newListenerMux(listener net.Listener, config *tls.Config) *ListenerMux {
 			// Enable Read timeout
 			conn.SetReadDeadline(UTCNow().Add(defaultTCPReadTimeout))
 
-			// Enable keep alive for each connection.
-			conn.SetKeepAlive(true)
-			conn.SetKeepAlivePeriod(defaultKeepAliveTimeout)
+			// Enable keep alive for each tcp connection.
+			tcpConn, isTCP := conn.(*net.TCPConn)
+			if isTCP {
+				tcpConn.SetKeepAlive(true)
+				tcpConn.SetKeepAlivePeriod(defaultKeepAliveTimeout)
+			}
 
 			// Allocate new conn muxer.
 			connMux := NewConnMux(conn)
@@ -258,12 +324,28 @@ func newListenerMux(listener net.Listener, config *tls.Config) *ListenerMux {
 				if cerr != nil {
 					// io.EOF is usually returned by non-http clients,
 					// just close the connection to avoid any leak.
-					if cerr != io.EOF {
+					if cerr == errProxyHeaderUntrusted {
+						println(cerr, "Rejected PROXY protocol header from untrusted peer", connMux.Conn.RemoteAddr().String())
+					} else if cerr != io.EOF {
 						println(cerr, "Unable to peek into incoming protocol")
 					}
 					connMux.Close()
 					return
 				}
+
+				// Cap the connections of every client, load
+				// balancers not sending the client address and
+				// local clients on Unix domain sockets are exempt.
+				if isTCP && (connMux.proxyAddr != nil || !isTrustedProxy(connMux.Conn.RemoteAddr())) {
+					ip, _, _ := net.SplitHostPort(connMux.RemoteAddr().String())
+					if cerr = globalIPConnCounter.acquire(ip, globalMaxConnsPerIP); cerr != nil {
+						println(cerr, "Rejected connection from", ip)
+						connMux.Close()
+						return
+					}
+					connMux.releaseFn = func() { globalIPConnCounter.release(ip) }
+				}
+
 				switch protocol {
 				case protocolTLS:
 					tlsConn := tls.Server(connMux, l.config)
@@ -275,9 +357,21 @@ func newListenerMux(listener net.Listener, config *tls.Config) *ListenerMux {
 						tlsConn.Close()
 						return
 					}
+					// The http server sets its own deadlines.
+					connMux.Conn.SetReadDeadline(time.Time{})
 					l.acceptResCh <- ListenerMuxAcceptRes{
 						conn: tlsConn,
 					}
+				case protocolHTTP2:
+					// Read deadlines of the http server apply to
+					// HTTP/1 requests only, HTTP/2 connections
+					// time out once idle. The accept time deadline
+					// is kept until the first frame is read.
+					connMux.ignoreReadDeadlines = true
+					connMux.h2cPendingBytes = h2cFirstFrameLen
+					l.acceptResCh <- ListenerMuxAcceptRes{
+						conn: connMux,
+					}
 				default:
 					l.acceptResCh <- ListenerMuxAcceptRes{
 						conn: connMux,
@@ -343,7 +437,9 @@ func (l *ListenerMux) Accept() (net.Conn, error) {
 
 // ServerMux - the main mux server
 type ServerMux struct {
-	Addr      string
+	// Addresses to listen on, either ADDRESS:PORT or the path of
+	// a Unix domain socket prefixed with unix://.
+	Addrs     []string
 	handler   http.Handler
 	listeners []*ListenerMux
 
@@ -357,34 +453,33 @@ type ServerMux struct {
 }
 
 // NewServerMux constructor to create a ServerMux
-func NewServerMux(addr string, handler http.Handler) *ServerMux {
+func NewServerMux(addrs []string, handler http.Handler) *ServerMux {
 	m := &ServerMux{
-		Addr:    addr,
+		Addrs:   addrs,
 		handler: handler,
-		// Wait for 5 seconds for new incoming connnections, otherwise
-		// forcibly close them during graceful stop or restart.
-		gracefulTimeout: 5 * time.Second,
+		// Wait for in-flight requests to finish, otherwise forcibly
+		// close them during graceful stop or restart.
+		gracefulTimeout: globalServerShutdownTimeout,
 	}
 
 	// Returns configured HTTP server.
 	return m
 }
 
-// Initialize listeners on all ports.
-func initListeners(serverAddr string, tls *tls.Config) ([]*ListenerMux, error) {
+// Listens on all the IP addresses of the host of serverAddr.
+func listenTCP(serverAddr string) ([]net.Listener, error) {
 	host, port, err := net.SplitHostPort(serverAddr)
 	if err != nil {
 		return nil, err
 	}
-	var listeners []*ListenerMux
+
 	if host == "" {
 		var listener net.Listener
 		listener, err = net.Listen("tcp", serverAddr)
 		if err != nil {
 			return nil, err
 		}
-		listeners = append(listeners, newListenerMux(listener, tls))
-		return listeners, nil
+		return []net.Listener{listener}, nil
 	}
 	var addrs []string
 	if net.ParseIP(host) != nil {
@@ -398,22 +493,84 @@ func initListeners(serverAddr string, tls *tls.Config) ([]*ListenerMux, error) {
 			return nil, errUnexpected
 		}
 	}
+	var listeners []net.Listener
 	for _, addr := range addrs {
 		var listener net.Listener
 		listener, err = net.Listen("tcp", net.JoinHostPort(addr, port))
 		if err != nil {
+			closeListeners(listeners)
 			return nil, err
 		}
-		listeners = append(listeners, newListenerMux(listener, tls))
+		listeners = append(listeners, listener)
 	}
 	return listeners, nil
 }
 
+// Closes all the given listeners.
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
+
+// Wraps all the given listeners with a ListenerMux.
+func newListenerMuxes(listeners []net.Listener, tls *tls.Config) []*ListenerMux {
+	var listenerMuxes []*ListenerMux
+	for _, listener := range listeners {
+		listenerMuxes = append(listenerMuxes, newListenerMux(listener, tls))
+	}
+	return listenerMuxes
+}
+
+// Initialize listeners on all addresses.
+func initListeners(serverAddrs []string, tls *tls.Config) ([]*ListenerMux, error) {
+	// Reuse the listening sockets passed by the parent process on restart.
+	inherited, err := getInheritedListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(inherited) > 0 {
+		for _, listener := range inherited {
+			// This process owns the socket files from now on.
+			if unixListener, ok := listener.(*net.UnixListener); ok {
+				unixListener.SetUnlinkOnClose(true)
+			}
+		}
+		return newListenerMuxes(inherited, tls), nil
+	}
+
+	var listeners []net.Listener
+	for _, serverAddr := range serverAddrs {
+		if isUnixSocketAddr(serverAddr) {
+			var path string
+			var listener net.Listener
+			path, err = parseUnixSocketAddr(serverAddr)
+			if err == nil {
+				listener, err = listenUnixSocket(path, globalUnixSocketMode)
+			}
+			if err != nil {
+				closeListeners(listeners)
+				return nil, err
+			}
+			listeners = append(listeners, listener)
+			continue
+		}
+		var tcpListeners []net.Listener
+		tcpListeners, err = listenTCP(serverAddr)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, tcpListeners...)
+	}
+	return newListenerMuxes(listeners, tls), nil
+}
+
 // ListenAndServe - serve HTTP requests with protocol multiplexing support
-// TLS is actived when certFile and keyFile parameters are not empty.
-func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
+// TLS is actived when certs is not nil.
+func (m *ServerMux) ListenAndServe(certs *certManager) (err error) {
 
-	tlsEnabled := certFile != "" && keyFile != ""
+	tlsEnabled := certs != nil
 
 	config := &tls.Config{
 		// Causes servers to use Go's default ciphersuite preferences,
@@ -424,20 +581,19 @@ func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
 	} // Always instantiate.
 
 	if tlsEnabled {
-		// Configure TLS in the server
+		// Configure TLS in the server, the server's preference
+		// wins so h2 comes first.
 		if config.NextProtos == nil {
-			config.NextProtos = []string{"http/1.1", "h2"}
-		}
-		config.Certificates = make([]tls.Certificate, 1)
-		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			return err
+			config.NextProtos = []string{"h2", "http/1.1"}
 		}
+		// Certificates are looked up on every handshake so
+		// reloaded certificates are served right away.
+		config.GetCertificate = certs.GetCertificate
 	}
 
-	//go m.handleServiceSignals()
+	go m.handleServiceSignals()
 
-	listeners, err := initListeners(m.Addr, config)
+	listeners, err := initListeners(m.Addrs, config)
 	if err != nil {
 		return err
 	}
@@ -448,8 +604,9 @@ func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
 
 	// All http requests start to be processed by httpHandler
 	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if tlsEnabled && r.TLS == nil {
-			// TLS is enabled but Request is not TLS configured
+		if tlsEnabled && r.TLS == nil && !isUnixSocketRequest(r) {
+			// TLS is enabled but Request is not TLS configured,
+			// local clients on Unix domain sockets may skip it.
 			u := url.URL{
 				Scheme:   httpsScheme,
 				Opaque:   r.URL.Opaque,
@@ -472,6 +629,11 @@ func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
 				return
 			}
 
+			// Drop clients sending their request body too slowly.
+			if globalHTTPMinBodyRate > 0 && r.ContentLength != 0 {
+				r.Body = newMinRateBody(w, r, globalHTTPMinBodyRate)
+			}
+
 			// Execute registered handlers, update currentReqs to keep
 			// tracks of current requests currently processed by the server
 			atomic.AddInt32(&m.currentReqs, 1)
@@ -480,12 +642,37 @@ func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
 		}
 	})
 
+	// HTTP/2 is negotiated through ALPN on TLS connections, plain
+	// connections speak it when clients start with the HTTP/2
+	// connection preface (h2c with prior knowledge). Requires Go 1.24,
+	// see minGoVersion.
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(!tlsEnabled)
+	server := &http.Server{
+		Handler:   httpHandler,
+		Protocols: &protocols,
+		// Idle connections and slow headers are closed, the
+		// timeout of request bodies is set per request.
+		ReadHeaderTimeout: globalHTTPReadHeaderTimeout,
+		IdleTimeout:       globalHTTPIdleTimeout,
+		MaxHeaderBytes:    globalHTTPMaxHeaderBytes,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: globalHTTP2MaxConcurrentStreams,
+			// Connections not sending any frame are pinged, and
+			// closed if the ping is not answered in time.
+			SendPingTimeout: globalHTTPIdleTimeout,
+			PingTimeout:     globalHTTPReadHeaderTimeout,
+		},
+	}
+
 	var wg = &sync.WaitGroup{}
 	for _, listener := range listeners {
 		wg.Add(1)
 		go func(listener *ListenerMux) {
 			defer wg.Done()
-			serr := http.Serve(listener, httpHandler)
+			serr := server.Serve(listener)
 			// Do not print the error if the listener is closed.
 			if !listener.IsClosed() {
 				println(serr, "Unable to serve incoming requests.")
@@ -497,6 +684,51 @@ func (m *ServerMux) ListenAndServe(certFile, keyFile string) (err error) {
 	return nil
 }
 
+// listenerFiles - returns duplicates of the listening sockets to be
+// passed on to a new process, the caller must close them.
+func (m *ServerMux) listenerFiles() ([]*os.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var files []*os.File
+	for _, listener := range m.listeners {
+		fileListener, ok := listener.Listener.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			closeFiles(files)
+			return nil, errInvalidArgument
+		}
+		f, err := fileListener.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// keepSocketFiles - keeps the files of the Unix domain sockets once
+// closed, they are served by a new process.
+func (m *ServerMux) keepSocketFiles() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, listener := range m.listeners {
+		if unixListener, ok := listener.Listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+}
+
+// Closes all the given files.
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
 // Close initiates the graceful shutdown
 func (m *ServerMux) Close() error {
 	m.mu.Lock()
@@ -507,6 +739,7 @@ func (m *ServerMux) Close() error {
 	}
 	// Closed completely.
 	m.closing = true
+	globalIsServerDraining.Store(true)
 
 	// Close the listeners.
 	for _, listener := range m.listeners {
@@ -521,14 +754,16 @@ func (m *ServerMux) Close() error {
 	// in regular interval or force the shutdown
 	ticker := time.NewTicker(serverShutdownPoll)
 	defer ticker.Stop()
+	timer := time.NewTimer(m.gracefulTimeout)
+	defer timer.Stop()
 	for {
-		select {
-		case <-time.After(m.gracefulTimeout):
+		if atomic.LoadInt32(&m.currentReqs) <= 0 {
 			return nil
+		}
+		select {
+		case <-timer.C:
+			return fmt.Errorf("%d requests still in progress after %s", atomic.LoadInt32(&m.currentReqs), m.gracefulTimeout)
 		case <-ticker.C:
-			if atomic.LoadInt32(&m.currentReqs) <= 0 {
-				return nil
-			}
 		}
 	}
 }
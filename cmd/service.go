@@ -17,18 +17,27 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
-	//"syscall"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // Type of service signals currently supported.
 type serviceSignal int
 
 const (
-	serviceStatus  = iota // Gets status about the service.
-	serviceRestart        // Restarts the service.
-	serviceStop           // Stops the server.
+	serviceStatus       = iota // Gets status about the service.
+	serviceRestart             // Restarts the service.
+	serviceStop                // Stops the server.
+	serviceReloadConfig        // Reloads the configuration file.
 	// Add new service requests here.
 )
 
@@ -44,12 +53,82 @@ func init() {
 	globalServiceSignalCh = make(chan serviceSignal)
 }
 
+// Environment variables through which a restarted process receives
+// the listening sockets and the pipe to report readiness on.
+const (
+	envListenFDs = "_MINIO_LISTEN_FDS"
+	envReadyFD   = "_MINIO_READY_FD"
+)
+
+// Time to wait for a restarted process to report it is ready.
+const restartReadyTimeout = 5 * time.Minute
+
+// First file descriptor passed through exec.Cmd.ExtraFiles.
+const firstExtraFD = 3
+
+var errRestartNotReady = errors.New("New process exited before it was ready")
+
+// isRestartedProcess - returns true if this process was started by
+// restartProcess of a previous server process.
+func isRestartedProcess() bool {
+	return os.Getenv(envListenFDs) != ""
+}
+
+// getInheritedListeners - returns the listening sockets passed by the
+// parent process, nil if none were passed.
+func getInheritedListeners() ([]net.Listener, error) {
+	value := os.Getenv(envListenFDs)
+	if value == "" {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("Invalid %s=%s", envListenFDs, value)
+	}
+
+	var listeners []net.Listener
+	for fd := firstExtraFD; fd < firstExtraFD+count; fd++ {
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("listener-%d", fd))
+		listener, err := net.FileListener(f)
+		// The listener holds its own duplicate of the socket.
+		f.Close()
+		if err != nil {
+			for _, listener = range listeners {
+				listener.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// notifyParentReady - tells the parent process this process is ready
+// to serve requests, does nothing if there is no parent waiting.
+func notifyParentReady() {
+	value := os.Getenv(envReadyFD)
+	if value == "" {
+		return
+	}
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		println(err, "Invalid %s=%s", envReadyFD, value)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	if _, err = f.Write([]byte{1}); err != nil {
+		println(err, "Unable to notify the parent process.")
+	}
+	f.Close()
+}
+
 // restartProcess starts a new process passing it the active fd's. It
 // doesn't fork, but starts a new process using the same environment and
 // arguments as when it was originally started. This allows for a newly
-// deployed binary to be started. It returns the pid of the newly started
-// process when successful.
-func restartProcess() error {
+// deployed binary to be started. It returns once the new process
+// reports it is ready, the new process is killed if it is not ready
+// within restartReadyTimeout.
+func restartProcess(listenerFiles []*os.File) error {
 	// Use the original binary location. This works with symlinks such that if
 	// the file it points to has been changed we will use the updated symlink.
 	argv0, err := exec.LookPath(os.Args[0])
@@ -57,35 +136,129 @@ func restartProcess() error {
 		return err
 	}
 
-	// Pass on the environment and replace the old count key with the new one.
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	// Pass on the environment and replace the old fd keys with the new ones.
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envListenFDs+"=") && !strings.HasPrefix(kv, envReadyFD+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		fmt.Sprintf("%s=%d", envListenFDs, len(listenerFiles)),
+		fmt.Sprintf("%s=%d", envReadyFD, firstExtraFD+len(listenerFiles)))
+
 	cmd := exec.Command(argv0, os.Args[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Start()
+	cmd.Env = env
+	cmd.ExtraFiles = append(listenerFiles, readyWriter)
+	err = cmd.Start()
+	// The new process holds its own copies of the fds.
+	readyWriter.Close()
+	closeFiles(listenerFiles)
+	if err != nil {
+		return err
+	}
+
+	// Reading fails once the new process exits without reporting ready.
+	readyCh := make(chan error, 1)
+	go func() {
+		_, rerr := readyReader.Read(make([]byte, 1))
+		if rerr == io.EOF {
+			rerr = errRestartNotReady
+		}
+		readyCh <- rerr
+	}()
+
+	select {
+	case err = <-readyCh:
+	case <-time.After(restartReadyTimeout):
+		err = fmt.Errorf("New process not ready after %s", restartReadyTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+	// The new process keeps running after this process exits.
+	return cmd.Process.Release()
+}
+
+// shutdownObjectLayer - shuts down the object layer if initialized.
+func shutdownObjectLayer() error {
+	objAPI := newObjectLayerFn()
+	if objAPI == nil {
+		// Server not initialized yet, exit happily.
+		return nil
+	}
+	return objAPI.Shutdown()
+}
+
+// signalTrap - returns a channel which receives true each time one
+// of the given signals is caught.
+func signalTrap(sig ...os.Signal) <-chan bool {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, sig...)
+
+	trapCh := make(chan bool, 1)
+	go func() {
+		for range sigCh {
+			select {
+			case trapCh <- true:
+			default:
+			}
+		}
+	}()
+	return trapCh
+}
+
+// flushServiceLogs - writes out the buffered access log records and
+// closes the audit logger and the event notifier, called once the
+// in-flight requests are drained and before the object layer is shut
+// down.
+func flushServiceLogs() {
+	globalBucketAccessLogger.Flush()
+	setAuditLogger(nil)
+	setEventNotifier(nil)
 }
 
 // Handles all serviceSignal and execute service functions.
 func (m *ServerMux) handleServiceSignals() error {
 	// Custom exit function
 	runExitFn := func(err error) {
-		// If global profiler is set stop before we exit.
-		if globalProfiler != nil {
-			globalProfiler.Stop()
-		}
+		// Stop profiling if running, before we exit.
+		globalProfiler.Stop()
 
-		// Call user supplied user exit function
-		println(err, "Unable to gracefully complete service operation.")
+		if err != nil {
+			println(err, "Unable to gracefully complete service operation.")
+		}
 
 		// We are usually done here, close global service done channel.
 		globalServiceDoneCh <- struct{}{}
 	}
 
-	// Wait for SIGTERM in a go-routine.
-	//trapCh := signalTrap(os.Interrupt, syscall.SIGTERM)
-	//go func(trapCh <-chan bool) {
-	//	<-trapCh
-	//	globalServiceSignalCh <- serviceStop
-	//}(trapCh)
+	// Wait for SIGTERM in a go-routine, a second signal while
+	// stopping gracefully exits immediately.
+	trapCh := signalTrap(os.Interrupt, syscall.SIGTERM)
+	go func(trapCh <-chan bool) {
+		<-trapCh
+		globalServiceSignalCh <- serviceStop
+		<-trapCh
+		println("Forcing exit.")
+		os.Exit(1)
+	}(trapCh)
+
+	// Reload the configuration file on SIGHUP.
+	go func(hupCh <-chan bool) {
+		for range hupCh {
+			globalServiceSignalCh <- serviceReloadConfig
+		}
+	}(signalTrap(syscall.SIGHUP))
 
 	// Start listening on service signal. Monitor signals.
 	for {
@@ -93,26 +266,45 @@ func (m *ServerMux) handleServiceSignals() error {
 		switch signal {
 		case serviceStatus:
 			/// We don't do anything for this.
+		case serviceReloadConfig:
+			if err := reloadConfig(); err != nil {
+				println(err, "Unable to reload configuration file, keeping the current configuration.")
+			} else {
+				println("Configuration file reloaded.")
+			}
 		case serviceRestart:
-			if err := m.Close(); err != nil {
-				println(err, "Unable to close server gracefully")
+			// Hand the listening sockets over to the new process, this
+			// process drains only once the new one serves requests so no
+			// connection is refused in between.
+			listenerFiles, err := m.listenerFiles()
+			if err != nil {
+				// Free the port for the new process instead.
+				println(err, "Unable to pass the listening sockets to the new process.")
+				if err = m.Close(); err != nil {
+					println(err, "Unable to close server gracefully")
+				}
 			}
-			if err := restartProcess(); err != nil {
+			if err = restartProcess(listenerFiles); err != nil {
 				println(err, "Unable to restart the server.")
+				if listenerFiles != nil {
+					// Keep serving with the current process.
+					continue
+				}
+			} else if listenerFiles != nil {
+				m.keepSocketFiles()
+				if err = m.Close(); err != nil {
+					println(err, "Unable to close server gracefully")
+				}
 			}
-			runExitFn(nil)
+			flushServiceLogs()
+			runExitFn(shutdownObjectLayer())
 		case serviceStop:
 			println("Gracefully stopping... (press Ctrl+C again to force)")
 			if err := m.Close(); err != nil {
 				println(err, "Unable to close server gracefully")
 			}
-			//objAPI := newObjectLayerFn()
-			//if objAPI == nil {
-			//	// Server not initialized yet, exit happily.
-			//	runExitFn(nil)
-			//} else {
-			//	runExitFn(objAPI.Shutdown())
-			//}
+			flushServiceLogs()
+			runExitFn(shutdownObjectLayer())
 		}
 	}
 }
@@ -26,7 +26,8 @@ package cmd
 
 import (
 	"bytes"
-	//"encoding/hex"
+	"crypto/subtle"
+	"encoding/hex"
 	"net/http"
 	//"net/url"
 	"sort"
@@ -113,3 +114,101 @@ func getScope(t time.Time, region string) string {
 	}, "/")
 	return scope
 }
+
+// getStringToSign a string based on selected query values.
+func getStringToSign(canonicalRequest string, t time.Time, scope string) string {
+	stringToSign := signV4Algorithm + "\n" + t.Format(iso8601Format) + "\n"
+	stringToSign = stringToSign + scope + "\n"
+	stringToSign = stringToSign + getSHA256Hash([]byte(canonicalRequest))
+	return stringToSign
+}
+
+// getSigningKey hmac seed to calculate final signature.
+func getSigningKey(secretKey string, t time.Time, region string) []byte {
+	date := sumHMAC([]byte("AWS4"+secretKey), []byte(t.Format(yyyymmdd)))
+	regionBytes := sumHMAC(date, []byte(region))
+	service := sumHMAC(regionBytes, []byte("s3"))
+	signingKey := sumHMAC(service, []byte("aws4_request"))
+	return signingKey
+}
+
+// getSignature final signature in hexadecimal form.
+func getSignature(signingKey []byte, stringToSign string) string {
+	return hex.EncodeToString(sumHMAC(signingKey, []byte(stringToSign)))
+}
+
+// compareSignatureV4 returns true if and only if both signatures
+// are equal. The signatures are expected to be HEX encoded strings
+// according to the AWS S3 signature V4 spec.
+func compareSignatureV4(sig1, sig2 string) bool {
+	// The CTC using []byte(str) works because the hex encoding
+	// is unique for a sequence of bytes.
+	return subtle.ConstantTimeCompare([]byte(sig1), []byte(sig2)) == 1
+}
+
+// doesSignatureMatch - Verify authorization header with calculated header in accordance with
+//     - http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+// returns ErrNone if signature matches.
+func doesSignatureMatch(hashedPayload string, r *http.Request, cred credential) APIErrorCode {
+	// Save authorization header.
+	v4Auth := r.Header.Get("Authorization")
+
+	// Parse signature version '4' header.
+	signV4Values, err := parseSignV4(v4Auth)
+	if err != ErrNone {
+		return err
+	}
+
+	// Extract all the signed headers along with its values.
+	extractedSignedHeaders, errCode := extractSignedHeaders(signV4Values.SignedHeaders, r)
+	if errCode != ErrNone {
+		return errCode
+	}
+
+	// Verify if the access key id matches.
+	if signV4Values.Credential.accessKey != cred.AccessKey {
+		return ErrInvalidAccessKeyID
+	}
+
+	// Extract date, if not present throw error.
+	var date string
+	if date = r.Header.Get(http.CanonicalHeaderKey("x-amz-date")); date == "" {
+		if date = r.Header.Get("Date"); date == "" {
+			return ErrMissingDateHeader
+		}
+	}
+	// Parse date header.
+	t, e := time.Parse(iso8601Format, date)
+	if e != nil {
+		return ErrMalformedDate
+	}
+
+	// Reject requests signed too far away from the server time.
+	if skew := UTCNow().Sub(t); skew > globalMaxSkewTime || skew < -globalMaxSkewTime {
+		return ErrRequestTimeTooSkewed
+	}
+
+	// Query string.
+	queryStr := r.URL.Query().Encode()
+
+	// Get canonical request.
+	canonicalRequest := getCanonicalRequest(extractedSignedHeaders, hashedPayload, queryStr, r.URL.Path, r.Method)
+
+	// Get string to sign from canonical request.
+	region := signV4Values.Credential.scope.region
+	stringToSign := getStringToSign(canonicalRequest, t, getScope(t, region))
+
+	// Get hmac signing key.
+	signingKey := getSigningKey(cred.SecretKey, signV4Values.Credential.scope.date, region)
+
+	// Calculate signature.
+	newSignature := getSignature(signingKey, stringToSign)
+
+	// Verify if signature match.
+	if !compareSignatureV4(newSignature, signV4Values.Signature) {
+		return ErrSignatureDoesNotMatch
+	}
+
+	// Return error none.
+	return ErrNone
+}
@@ -0,0 +1,342 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// Number of trace entries buffered per subscriber, entries are
+// dropped for subscribers which are not keeping up.
+const traceSubscriberQueueSize = 1000
+
+// traceCall is the timing of a single object layer call
+// made while serving a request.
+type traceCall struct {
+	Name     string        `json:"name"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// requestTrace is a traced HTTP request/response pair along
+// with the object layer calls made while serving it.
+type requestTrace struct {
+	Time        time.Time     `json:"time"`
+	RequestID   string        `json:"requestID"`
+	API         string        `json:"api"`
+	Bucket      string        `json:"bucket,omitempty"`
+	Object      string        `json:"object,omitempty"`
+	Method      string        `json:"method"`
+	Path        string        `json:"path"`
+	Query       string        `json:"query,omitempty"`
+	RemoteAddr  string        `json:"remoteAddr"`
+	ReqHeader   http.Header   `json:"reqHeader"`
+	StatusCode  int           `json:"statusCode"`
+	ErrorCode   string        `json:"errorCode,omitempty"`
+	RespHeader  http.Header   `json:"respHeader"`
+	InputBytes  int64         `json:"inputBytes"`
+	OutputBytes int64         `json:"outputBytes"`
+	Duration    time.Duration `json:"duration"`
+	Calls       []traceCall   `json:"calls,omitempty"`
+}
+
+// traceFilter selects the traced requests sent to a subscriber.
+type traceFilter struct {
+	// Only requests on this bucket, empty for all buckets.
+	Bucket string
+	// Only requests of this API, empty for all APIs.
+	API string
+	// Only requests answered with an error.
+	ErrorsOnly bool
+	// Only requests taking at least this long.
+	Threshold time.Duration
+}
+
+// Parses the trace filter from the query parameters
+// `bucket`, `api`, `errors` and `threshold`.
+func parseTraceFilter(values url.Values) (filter traceFilter, err error) {
+	filter.Bucket = values.Get("bucket")
+	filter.API = values.Get("api")
+	if errorsOnly := values.Get("errors"); errorsOnly != "" {
+		if filter.ErrorsOnly, err = strconv.ParseBool(errorsOnly); err != nil {
+			return filter, err
+		}
+	}
+	if threshold := values.Get("threshold"); threshold != "" {
+		if filter.Threshold, err = time.ParseDuration(threshold); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// Returns the query parameters of the trace filter.
+func (f traceFilter) values() url.Values {
+	values := make(url.Values)
+	if f.Bucket != "" {
+		values.Set("bucket", f.Bucket)
+	}
+	if f.API != "" {
+		values.Set("api", f.API)
+	}
+	if f.ErrorsOnly {
+		values.Set("errors", "true")
+	}
+	if f.Threshold > 0 {
+		values.Set("threshold", f.Threshold.String())
+	}
+	return values
+}
+
+// match - returns true if the traced request passes the filter.
+func (f traceFilter) match(info requestTrace) bool {
+	if f.Bucket != "" && f.Bucket != info.Bucket {
+		return false
+	}
+	if f.API != "" && f.API != info.API {
+		return false
+	}
+	if f.ErrorsOnly && info.StatusCode < http.StatusBadRequest {
+		return false
+	}
+	return info.Duration >= f.Threshold
+}
+
+// traceSubscriber receives the traced requests passing its filter.
+type traceSubscriber struct {
+	ch     chan requestTrace
+	filter traceFilter
+}
+
+// tracePubSub publishes traced requests to all subscribers.
+type tracePubSub struct {
+	mu          sync.RWMutex
+	subscribers map[*traceSubscriber]struct{}
+	// Number of subscribers, allows a lockless check
+	// of whether requests need to be traced at all.
+	numSubscribers atomic.Int32
+}
+
+func newTracePubSub() *tracePubSub {
+	return &tracePubSub{
+		subscribers: make(map[*traceSubscriber]struct{}),
+	}
+}
+
+// Global trace publisher.
+var globalTrace = newTracePubSub()
+
+// Subscribe - returns a new subscriber receiving the traced
+// requests passing the filter.
+func (ps *tracePubSub) Subscribe(filter traceFilter) *traceSubscriber {
+	sub := &traceSubscriber{
+		ch:     make(chan requestTrace, traceSubscriberQueueSize),
+		filter: filter,
+	}
+	ps.mu.Lock()
+	ps.subscribers[sub] = struct{}{}
+	ps.numSubscribers.Inc()
+	ps.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe - stops sending traced requests to the subscriber.
+func (ps *tracePubSub) Unsubscribe(sub *traceSubscriber) {
+	ps.mu.Lock()
+	if _, ok := ps.subscribers[sub]; ok {
+		delete(ps.subscribers, sub)
+		ps.numSubscribers.Dec()
+	}
+	ps.mu.Unlock()
+}
+
+// HasSubscribers - returns true if anyone is tracing.
+func (ps *tracePubSub) HasSubscribers() bool {
+	return ps.numSubscribers.Load() > 0
+}
+
+// Publish - sends the traced request to all matching subscribers,
+// never blocks on subscribers which are not keeping up.
+func (ps *tracePubSub) Publish(info requestTrace) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	for sub := range ps.subscribers {
+		if !sub.filter.match(info) {
+			continue
+		}
+		select {
+		case sub.ch <- info:
+		default:
+		}
+	}
+}
+
+// traceCalls collects the object layer calls made while serving
+// a request, calls may be recorded from multiple go-routines.
+type traceCalls struct {
+	mu    sync.Mutex
+	calls []traceCall
+}
+
+// record - records an object layer call which started at startTime.
+func (c *traceCalls) record(name string, startTime time.Time, err error) {
+	call := traceCall{
+		Name:     name,
+		Time:     startTime,
+		Duration: UTCNow().Sub(startTime),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+}
+
+// get - returns the calls recorded so far.
+func (c *traceCalls) get() []traceCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]traceCall(nil), c.calls...)
+}
+
+// Context key of the object layer calls of a traced request.
+type traceCallsKey struct{}
+
+// Returns the object layer calls of a traced request, nil if
+// the request is not traced.
+func getTraceCalls(r *http.Request) *traceCalls {
+	calls, _ := r.Context().Value(traceCallsKey{}).(*traceCalls)
+	return calls
+}
+
+// Request headers which are never sent to trace subscribers.
+var traceRedactedHeaders = []string{
+	"Authorization",
+	"X-Amz-Security-Token",
+}
+
+// Query parameters of presigned requests which are never sent to
+// trace subscribers.
+var traceRedactedQueryParams = []string{
+	"X-Amz-Credential",
+	"X-Amz-Signature",
+	"X-Amz-Security-Token",
+	"AWSAccessKeyId",
+	"Signature",
+}
+
+// redactTraceQuery - returns rawQuery with the values of the
+// credential and signature parameters replaced, the order of the
+// parameters is kept.
+func redactTraceQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key := param
+		if j := strings.Index(param, "="); j >= 0 {
+			key = param[:j]
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		for _, redacted := range traceRedactedQueryParams {
+			if strings.EqualFold(name, redacted) {
+				params[i] = key + "=*REDACTED*"
+				break
+			}
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// traceHandler definition: publishes all the HTTP requests along
+// with their object layer calls while anyone is tracing.
+type traceHandler struct {
+	handler http.Handler
+}
+
+// setTraceHandler to trace the incoming requests.
+func setTraceHandler(h http.Handler) http.Handler {
+	return traceHandler{handler: h}
+}
+
+func (h traceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Tracing is expensive, skip it while nobody listens and
+	// for the trace requests themselves.
+	if !globalTrace.HasSubscribers() {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	api := getAPIName(r)
+	if api == adminTraceAPIName {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	calls := &traceCalls{}
+	r = r.WithContext(context.WithValue(r.Context(), traceCallsKey{}, calls))
+
+	reqHeader := cloneHeader(r.Header)
+	for _, header := range traceRedactedHeaders {
+		if _, ok := reqHeader[header]; ok {
+			reqHeader.Set(header, "*REDACTED*")
+		}
+	}
+	reqHeader.Set("Host", r.Host)
+
+	body := &httpRequestBodyCounter{ReadCloser: r.Body}
+	r.Body = body
+	ww := &httpResponseRecorder{ResponseWriter: w}
+
+	startTime := UTCNow()
+	h.handler.ServeHTTP(ww, r)
+	duration := UTCNow().Sub(startTime)
+
+	bucket, object := urlPath2BucketObjectName(r.URL)
+	globalTrace.Publish(requestTrace{
+		Time:        startTime,
+		RequestID:   w.Header().Get(responseRequestIDKey),
+		API:         api,
+		Bucket:      bucket,
+		Object:      object,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Query:       redactTraceQuery(r.URL.RawQuery),
+		RemoteAddr:  r.RemoteAddr,
+		ReqHeader:   reqHeader,
+		StatusCode:  ww.statusCode(),
+		ErrorCode:   ww.errorCode(),
+		RespHeader:  cloneHeader(w.Header()),
+		InputBytes:  body.bytesRead,
+		OutputBytes: ww.bytesWritten,
+		Duration:    duration,
+		Calls:       calls.get(),
+	})
+}
@@ -45,6 +45,9 @@ var errDataTooSmall = errors.New("Object size smaller than expected")
 // errServerNotInitialized - server not initialized.
 var errServerNotInitialized = errors.New("Server not initialized, please try again")
 
+// errServerDraining - server is draining its connections.
+var errServerDraining = errors.New("Server is draining its connections")
+
 // errServerVersionMismatch - server versions do not match.
 var errServerVersionMismatch = errors.New("Server versions do not match")
 
@@ -0,0 +1,88 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Prefix of server addresses listening on a Unix domain socket, e.g.
+// unix:///run/minio.sock.
+const unixSocketScheme = "unix://"
+
+// Permissions of the Unix domain sockets, unless set otherwise.
+const defaultUnixSocketMode os.FileMode = 0660
+
+var errUnixSocketInUse = errors.New("Unix domain socket is in use by another process")
+
+// isUnixSocketAddr - returns true if addr is the address of a Unix
+// domain socket.
+func isUnixSocketAddr(addr string) bool {
+	return strings.HasPrefix(addr, unixSocketScheme)
+}
+
+// parseUnixSocketAddr - returns the path of the socket of addr, it
+// must be absolute.
+func parseUnixSocketAddr(addr string) (string, error) {
+	path := strings.TrimPrefix(addr, unixSocketScheme)
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("Unix domain socket path %s is not absolute", addr)
+	}
+	return filepath.Clean(path), nil
+}
+
+// parseUnixSocketMode - parses permissions given in octal, e.g. 0660.
+func parseUnixSocketMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("Invalid socket permissions %s", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// listenUnixSocket - listens on the socket at path with the given
+// permissions. A socket left behind by a server which did not exit
+// cleanly is replaced, one still accepting connections is not.
+func listenUnixSocket(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, derr := net.Dial("unix", path)
+		if derr == nil {
+			conn.Close()
+			return nil, errUnixSocketInUse
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	// The socket must not be reachable by anyone else before it
+	// has the permissions it is supposed to have.
+	return listenUnixSocketMode(path, mode)
+}
+
+// isUnixSocketRequest - returns true if r was received on a Unix
+// domain socket.
+func isUnixSocketRequest(r *http.Request) bool {
+	_, ok := r.Context().Value(http.LocalAddrContextKey).(*net.UnixAddr)
+	return ok
+}
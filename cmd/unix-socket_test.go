@@ -0,0 +1,68 @@
+/*
+ * Minio Cloud Storage, (C) 2017 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Socket permissions are not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), "minio.sock")
+
+	for _, mode := range []os.FileMode{0600, 0660} {
+		listener, err := listenUnixSocket(path, mode)
+		if err != nil {
+			t.Fatalf("Mode %o: unable to listen: %v", mode, err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil || fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != mode {
+			t.Fatalf("Mode %o: expected a socket with mode %o, got %v, %v", mode, mode, fi.Mode(), err)
+		}
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatalf("Mode %o: unable to connect: %v", mode, err)
+		}
+		conn.Close()
+
+		if _, err = listenUnixSocket(path, mode); err != errUnixSocketInUse {
+			t.Fatalf("Mode %o: expected errUnixSocketInUse, got %v", mode, err)
+		}
+		listener.Close()
+		if _, err = os.Lstat(path); !os.IsNotExist(err) {
+			t.Fatalf("Mode %o: expected the socket to be removed once closed, got %v", mode, err)
+		}
+	}
+
+	// A socket left behind is replaced.
+	listener, err := listenUnixSocket(path, 0600)
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if listener, err = listenUnixSocket(path, 0600); err != nil {
+		t.Fatalf("Expected a stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+}
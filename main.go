@@ -32,8 +32,9 @@ import (
 )
 
 const (
-	// Minio requires at least Go v1.7
-	minGoVersion        = "1.7"
+	// Minio requires at least Go v1.24, for HTTP/2 without TLS
+	// (http.Protocols) and http.HTTP2Config.
+	minGoVersion        = "1.24"
 	goVersionConstraint = ">= " + minGoVersion
 )
 